@@ -1,10 +1,10 @@
-import os
 import logging
 import requests
 from typing import List, Optional, Dict, Any
+from core.gateway import GATEWAY_URL, gateway_headers
+from tools.gateway import LEGACY_NAMES
  
 logger = logging.getLogger(__name__)
-GATEWAY_URL = os.getenv("GATEWAY_URL", "http://localhost:8001")
  
 class SerqetAgent:
     def __init__(self, slug: str):
@@ -17,6 +17,7 @@ class SerqetAgent:
         try:
             resp = requests.get(
                 f"{GATEWAY_URL}/api/v1/agents",
+                headers=gateway_headers(),
                 timeout=2
             )
             resp.raise_for_status()
@@ -34,5 +35,6 @@ class SerqetAgent:
     @property
     def allowed_tools(self) -> List[str]:
         if self.config and self.config.get("allowed_tools"):
-            return [t.strip() for t in self.config["allowed_tools"].split(",") if t.strip()]
+            names = [t.strip() for t in self.config["allowed_tools"].split(",") if t.strip()]
+            return [LEGACY_NAMES.get(n, n) for n in names]
         return []
@@ -24,7 +24,9 @@ _RULES: list[tuple[list[str], str]] = [
     (["task", "todo", "plan", "remind", "checklist"], "tasks"),
 ]
  
-def get_agent_for_intent(query: str) -> SerqetAgent:
+def get_agent_for_intent(query: str, slug: str | None = None) -> SerqetAgent:
+    if slug:
+        return make_agent(slug)  # scheduled jobs name their agent
     q = query.lower()
     for keywords, slug in _RULES:
         if any(kw in q for kw in keywords):
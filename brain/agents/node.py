@@ -11,10 +11,9 @@ from providers.factory import get_llm
 from utils.parser import parse_content, extract_action_and_clean
 from utils.vision import encode_image
 from core.memory import memory_engine
-from tools import ALL_TOOLS
+from tools import all_tools
 
 logger = logging.getLogger(__name__)
-TOOL_MAP = {t.name: t for t in ALL_TOOLS}
 
 
 def _build_directive(agent) -> str:
@@ -62,9 +61,9 @@ def _build_prompt_stack(agent, context: str, state: AgentState) -> list:
     return stack
 
 
-def _run_tool(tool_name: str, tool_args: dict, agent_slug: str) -> dict:
+def _run_tool(tool, tool_args: dict, agent_slug: str) -> dict:
     """Execute a single tool and return a normalised result dict."""
-    tool = TOOL_MAP[tool_name]
+    tool_name = tool.name
     output = tool.invoke(tool_args)
 
     if tool_name == "web_research":
@@ -125,7 +124,7 @@ def agent_node(state: AgentState) -> dict:
     query = str(state["messages"][-1].content) or "System Refresh"
     session_id = state.get("session_id", "default")
 
-    agent = get_agent_for_intent(query)
+    agent = get_agent_for_intent(query, state.get("agent"))
     logger.info("[BRAIN] specialist=%s session=%s", agent.slug, session_id)
 
     # Memory recall — never crash the whole request on memory failure
@@ -135,7 +134,8 @@ def agent_node(state: AgentState) -> dict:
         logger.warning("[MEMORY] Recall failed: %s", e)
         context = ""
 
-    allowed_tools = [t for t in ALL_TOOLS if t.name in agent.allowed_tools]
+    allowed_tools = [t for t in all_tools() if t.name in agent.allowed_tools]
+    tool_map = {t.name: t for t in allowed_tools}
     llm = get_llm("gemini")
     llm_with_tools = llm.bind_tools(allowed_tools) if allowed_tools else llm
 
@@ -145,17 +145,19 @@ def agent_node(state: AgentState) -> dict:
 
         if getattr(response, "tool_calls", None):
             logger.info("[BRAIN] %d tool call(s) detected", len(response.tool_calls))
-            final_action, final_data = None, None
+            # Every allowed call becomes a plan step, in the order the model
+            # made them; steps from before a followup loop are carried over.
+            steps = list(state.get("steps") or [])
 
             for call in response.tool_calls:
                 name, args = call["name"], call["args"]
 
-                if name not in agent.allowed_tools:
+                if name not in tool_map:
                     logger.warning("[SECURITY] Blocked: %s", name)
                     continue
 
                 logger.info("[BRAIN] Executing tool: %s", name)
-                result = _run_tool(name, args, agent.slug)
+                result = _run_tool(tool_map[name], args, agent.slug)
 
                 if result.get("needs_followup"):
                     # Signal the graph to loop back via state flag — no recursion
@@ -175,16 +177,18 @@ def agent_node(state: AgentState) -> dict:
                         "messages": new_msgs,
                         "action": None,
                         "tool_data": None,
+                        "steps": steps,
                         "_loop": True,
                     }
 
-                final_action = result["action"]
-                final_data = result["data"]
+                steps.append({"action": result["action"], "data": result["data"]})
 
+            last = steps[-1] if steps else {"action": None, "data": None}
             return {
                 "messages": [response],   # delta only — LangGraph appends to existing list
-                "action": final_action,
-                "tool_data": final_data,
+                "action": last["action"],
+                "tool_data": last["data"],
+                "steps": steps,
                 "_loop": False,
             }
 
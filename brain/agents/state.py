@@ -8,6 +8,8 @@ class AgentState(TypedDict, total=False):
     session_id: str
     user_id:    str
     file_path:  Optional[str]
+    agent:      Optional[str] # specialist forced by the caller
     action:     Optional[str]
     tool_data:  Optional[dict[str, Any]]
+    steps:      list[dict[str, Any]]  # every tool call, in order: {action, data}
     _loop:      bool          # internal routing flag
\ No newline at end of file
@@ -0,0 +1,13 @@
+import os
+
+GATEWAY_URL = os.getenv("GATEWAY_URL", "http://localhost:8001")
+
+
+def gateway_headers() -> dict:
+    """
+    Credentials for calls back into the gateway. Every /api/v1 route needs
+    a token, so the Brain sends GATEWAY_API_KEY (an API key issued with
+    POST /api/v1/auth/keys) as X-API-Key.
+    """
+    key = os.getenv("GATEWAY_API_KEY")
+    return {"X-API-Key": key} if key else {}
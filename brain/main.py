@@ -1,11 +1,13 @@
 import uvicorn
+import json
 import logging
 import os
 import uuid
-from fastapi import FastAPI
+from fastapi import FastAPI, Request
+from fastapi.responses import StreamingResponse
 from schemas.request import IntentRequest
 from agents.node import build_graph
-from langchain_core.messages import HumanMessage, AIMessage
+from langchain_core.messages import HumanMessage, AIMessage, AIMessageChunk
 from core.memory import memory_engine
 from utils.voice import generate_speech_async
 
@@ -58,59 +60,71 @@ def _extract_display_text(last_msg, action: str, tool_data: dict | None) -> str:
     return content.strip() if content else "Executing OS module…"
 
 
+def _initial_state(req: IntentRequest) -> dict:
+    messages = []
+    for m in req.history:
+        if m.role == "user":
+            messages.append(HumanMessage(content=m.text))
+        else:
+            messages.append(AIMessage(content=m.text))
+    messages.append(HumanMessage(content=req.query))
+
+    return {
+        "messages": messages,
+        "session_id": req.session_id,
+        "user_id": getattr(req, "user_id", "user"),
+        "file_path": req.file_path,
+        "agent": req.agent,
+        "action": None,
+        "tool_data": None,
+        "steps": [],
+        "_loop": False,
+    }
+
+
+async def _build_response(req: IntentRequest, result: dict) -> dict:
+    last_msg   = result["messages"][-1]
+    action_val = result.get("action") or ""
+    action_str = str(action_val)
+    tool_data  = result.get("tool_data")
+    steps      = result.get("steps") or []
+
+    display_text = _extract_display_text(last_msg, action_str, tool_data)
+    if len(steps) > 1:
+        labels = [s["action"].replace("execute_", "").replace("_", " ").title() for s in steps]
+        display_text = f"Executing {len(steps)} steps: {', '.join(labels)}"
+
+    # Only synthesise speech for conversational (non-tool) responses
+    audio_url = None
+    if display_text and not action_str.startswith("execute_"):
+        try:
+            voice_filename = f"speech_{uuid.uuid4().hex}.mp3"
+            voice_save_path = os.path.join(UPLOAD_DIR, voice_filename)
+            success = await generate_speech_async(display_text, voice_save_path)
+            if success:
+                audio_url = f"/uploads/{voice_filename}"
+                logger.info("[VOICE] Free Neural Synthesis: %s", audio_url)
+        except Exception as ve:
+            logger.error("[VOICE ERROR] Synthesis failed: %s", ve)
+
+    return {
+        "status":     "success",
+        "message":    display_text,
+        "audio_url":  audio_url,
+        "action":     action_val,
+        "data":       tool_data,
+        "steps":      steps,
+        "session_id": req.session_id,
+    }
+
+
 @app.post("/brain/v1/process_intent")
 async def process_intent(req: IntentRequest):
     try:
-        messages = []
-        for m in req.history:
-            if m.role == "user":
-                messages.append(HumanMessage(content=m.text))
-            else:
-                messages.append(AIMessage(content=m.text))
-        messages.append(HumanMessage(content=req.query))
-
-        initial_state = {
-            "messages": messages,
-            "session_id": req.session_id,
-            "user_id": getattr(req, "user_id", "user"),
-            "file_path": req.file_path,
-            "action": None,
-            "tool_data": None,
-            "_loop": False,
-        }
-
         logger.info("[BRAIN] Processing Session: %s", req.session_id)
 
-        result = serqet_brain.invoke(initial_state)
-
-        last_msg   = result["messages"][-1]
-        action_val = result.get("action") or ""
-        action_str = str(action_val)
-        tool_data  = result.get("tool_data")
-
-        display_text = _extract_display_text(last_msg, action_str, tool_data)
-
-        # Only synthesise speech for conversational (non-tool) responses
-        audio_url = None
-        if display_text and not action_str.startswith("execute_"):
-            try:
-                voice_filename = f"speech_{uuid.uuid4().hex}.mp3"
-                voice_save_path = os.path.join(UPLOAD_DIR, voice_filename)
-                success = await generate_speech_async(display_text, voice_save_path)
-                if success:
-                    audio_url = f"/uploads/{voice_filename}"
-                    logger.info("[VOICE] Free Neural Synthesis: %s", audio_url)
-            except Exception as ve:
-                logger.error("[VOICE ERROR] Synthesis failed: %s", ve)
-
-        return {
-            "status":     "success",
-            "message":    display_text,
-            "audio_url":  audio_url,
-            "action":     action_val,
-            "data":       tool_data,
-            "session_id": req.session_id,
-        }
+        result = serqet_brain.invoke(_initial_state(req))
+        return await _build_response(req, result)
 
     except Exception as e:
         logger.error("!!! [BRAIN PANIC] !!!: %s", e, exc_info=True)
@@ -122,6 +136,66 @@ async def process_intent(req: IntentRequest):
         }
 
 
+def _sse(event: str, data) -> str:
+    return f"event: {event}\ndata: {json.dumps(data, default=str)}\n\n"
+
+
+def _chunk_text(chunk) -> str:
+    content = getattr(chunk, "content", "")
+    if isinstance(content, str):
+        return content
+    return "".join(
+        part.get("text", "") for part in content
+        if isinstance(part, dict) and part.get("type") == "text"
+    )
+
+
+@app.post("/brain/v1/process_intent/stream")
+async def process_intent_stream(req: IntentRequest, request: Request):
+    """
+    Server-sent events version of process_intent, in the contract the
+    gateway's StreamIntent reads: "token" events while the model writes,
+    "tool" once a tool is chosen, then "final" with the full response.
+    The graph is abandoned as soon as the gateway disconnects.
+    """
+    async def events():
+        logger.info("[BRAIN] Streaming Session: %s", req.session_id)
+        graph = serqet_brain.astream(_initial_state(req), stream_mode=["messages", "values"])
+        result = None
+        try:
+            async for mode, chunk in graph:
+                if await request.is_disconnected():
+                    logger.info("[BRAIN] Client left session %s; stream cancelled", req.session_id)
+                    return
+                if mode == "messages":
+                    # Only model output; tool results are not part of the reply.
+                    text = _chunk_text(chunk[0]) if isinstance(chunk[0], AIMessageChunk) else ""
+                    if text:
+                        yield _sse("token", {"text": text})
+                    continue
+                if result is None or chunk.get("action") != result.get("action"):
+                    if chunk.get("action"):
+                        yield _sse("tool", {"action": chunk["action"], "data": chunk.get("tool_data")})
+                result = chunk
+
+            if result is None:
+                yield _sse("error", {"message": "graph produced no state"})
+                return
+            yield _sse("final", await _build_response(req, result))
+
+        except Exception as e:
+            logger.error("!!! [BRAIN PANIC] !!!: %s", e, exc_info=True)
+            yield _sse("error", {"message": f"Neural Link Error: {str(e)}"})
+        finally:
+            await graph.aclose()
+
+    return StreamingResponse(
+        events(),
+        media_type="text/event-stream",
+        headers={"Cache-Control": "no-cache", "X-Accel-Buffering": "no"},
+    )
+
+
 @app.get("/health")
 async def health():
     return {"status": "online", "engine": "gemini-2.0-flash"}
@@ -10,4 +10,5 @@ class IntentRequest(BaseModel):
     session_id: str = "default"
     query: str
     file_path: Optional[str] = None
+    agent: Optional[str] = None  # specialist slug; None lets the router pick
     history: List[Message] = []
\ No newline at end of file
@@ -1,38 +1,22 @@
-from .social import create_social_draft
-from .finance import (
-    record_expense, record_savings, get_market_analysis,
-    sync_portfolio, get_portfolio_summary, analyze_net_worth,
-    analyze_technical_indicators, generate_trading_signal,
-)
-from .tasks import create_task
-from .jobs import track_job_application
-from .health import record_meal, record_workout
+from .finance import get_market_analysis, get_portfolio_summary, analyze_net_worth, analyze_technical_indicators
 from .research import web_research
-from .arbiter import analyze_niche_profitability, scout_business_niche, launch_venture
-from .code import document_code_logic
-from .security import log_security_issue
-from .oracle import archive_knowledge_node, submit_for_review
- 
-ALL_TOOLS = [
-    create_social_draft,
-    record_expense,
-    record_savings,
+from .arbiter import analyze_niche_profitability, scout_business_niche
+from .gateway import gateway_tools, LEGACY_NAMES
+
+# Tools that do their work in the Brain. Everything the gateway executes is
+# defined there and loaded by gateway_tools().
+LOCAL_TOOLS = [
     get_market_analysis,
-    sync_portfolio,
     get_portfolio_summary,
     analyze_net_worth,
     analyze_technical_indicators,
-    generate_trading_signal,
-    launch_venture,
-    create_task,
-    track_job_application,
-    record_meal,
-    record_workout,
     web_research,
     analyze_niche_profitability,
     scout_business_niche,
-    document_code_logic,
-    log_security_issue,
-    archive_knowledge_node,
-    submit_for_review,
 ]
+
+
+def all_tools() -> list:
+    """Local tools plus the gateway's, a local tool winning on a name clash."""
+    local = {t.name for t in LOCAL_TOOLS}
+    return LOCAL_TOOLS + [t for t in gateway_tools() if t.name not in local]
@@ -32,24 +32,3 @@ def scout_business_niche(
         "decision_needed": True,
         "search_query": f"profitable low competition {industry} niches 2026 affiliate subscription"
     }
-
-@tool
-def launch_venture(
-    name: Annotated[str, "Name of the business venture"],
-    category: Annotated[str, "e.g., 'Affiliate', 'SaaS', 'Content', 'Newsletter'"],
-    strategy: Annotated[str, "The detailed step-by-step execution plan"],
-    projected_roi: Annotated[str, "Estimated monthly earnings, e.g., '$500/mo'"],
-    platform: Annotated[str, "Primary platform, e.g., 'X', 'Substack', 'Amazon'"]
-) -> Dict[str, Any]:
-    """
-    Finalizes and saves a new business venture into the Serqet OS Finance Hub.
-    Call this only after a strategy has been generated and confirmed.
-    """
-    return {
-        "action": "execute_launch_venture",
-        "name": name,
-        "category": category,
-        "strategy": strategy,
-        "projected_roi": projected_roi,
-        "platform": platform
-    }
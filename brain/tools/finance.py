@@ -2,9 +2,7 @@ import requests
 import pandas as pd
 from typing import List, Dict, Any, Annotated, Optional
 from langchain_core.tools import tool
-import os
-
-GATEWAY_URL = os.getenv("GATEWAY_URL", "http://localhost:8001")
+from core.gateway import GATEWAY_URL, gateway_headers
  
 
 def _calc_indicators(df: pd.DataFrame) -> dict:
@@ -28,29 +26,6 @@ def _calc_indicators(df: pd.DataFrame) -> dict:
 
 PAIR_MAP = {"BTC": "XXBTZUSD", "ETH": "XETHZUSD", "SOL": "SOLUSD"}
  
-@tool
-def record_expense(
-    amount: Annotated[float, "The numerical value of the expense"],
-    category: Annotated[str, "Spending category (e.g. food, rent, tech)"],
-    description: Annotated[str, "Brief description of the purchase"],
-) -> Dict[str, Any]:
-    """Records a financial expense. Call when the user mentions spending money."""
-    return {"action": "db_record_expense", "amount": amount, "category": category, "description": description}
- 
-@tool
-def record_savings(
-    amount: Annotated[float, "The numerical value saved or earned"],
-    source: Annotated[str, "Source of funds (e.g. salary, gift, interest)"],
-    description: Annotated[str, "Context regarding the income or saving"],
-) -> Dict[str, Any]:
-    """Records financial income or savings."""
-    return {"action": "db_record_savings", "amount": amount, "source": source, "description": description}
- 
-@tool
-def sync_portfolio() -> Dict[str, Any]:
-    """Syncs the latest holdings from Kraken. Use when user asks to refresh balances."""
-    return {"action": "execute_sync_holdings"}
- 
 @tool
 def get_portfolio_summary() -> Dict[str, Any]:
     """Retrieves current crypto holdings from the local database."""
@@ -68,7 +43,11 @@ def get_market_analysis(
     """Fetches market data and calculates RSI/SMA indicators for a given asset."""
     pair = PAIR_MAP.get(asset.upper(), "XXBTZUSD")
     try:
-        resp = requests.get(f"{GATEWAY_URL}/api/v1/finance/ohlc?pair={pair}", timeout=10)
+        resp = requests.get(
+            f"{GATEWAY_URL}/api/v1/finance/ohlc?pair={pair}",
+            headers=gateway_headers(),
+            timeout=10,
+        )
         resp.raise_for_status()
         candles = resp.json()
         if not candles or len(candles) < 20:
@@ -88,17 +67,3 @@ def analyze_technical_indicators(
         return _calc_indicators(pd.DataFrame(ohlc_data))
     except Exception as e:
         return {"status": "error", "message": str(e)}
- 
-@tool
-def generate_trading_signal(
-    asset: str,
-    action: Annotated[str, "BUY, SELL, or HOLD"],
-    reasoning: str,
-    confidence: float,
-) -> Dict[str, Any]:
-    """Saves the final AI trade signal to the database."""
-    return {
-        "action": "execute_generate_trading_signal",
-        "asset": asset, "signal_action": action,
-        "reasoning": reasoning, "confidence": confidence,
-    }
@@ -0,0 +1,53 @@
+"""
+Executor tools published by the gateway at /api/v1/tools. Their names,
+descriptions and argument schemas live only in the gateway's registry; the
+Brain turns each into a tool that hands its arguments back, and the gateway
+runs it when it executes the plan.
+"""
+import logging
+import requests
+from typing import Any, Dict, List, Optional
+from langchain_core.tools import BaseTool, StructuredTool
+from core.gateway import GATEWAY_URL, gateway_headers
+
+logger = logging.getLogger(__name__)
+
+_PREFIX = "execute_"
+
+# Names agent configs used before the gateway published its own.
+LEGACY_NAMES = {
+    "archive_knowledge_node": "db_save_knowledge",
+    "log_security_issue":     "db_log_security",
+    "document_code_logic":    "db_save_code",
+}
+
+_tools: Optional[List[BaseTool]] = None
+
+
+def _make_tool(schema: Dict[str, Any]) -> BaseTool:
+    def passthrough(**kwargs) -> Dict[str, Any]:
+        return {k: v for k, v in kwargs.items() if v is not None}
+
+    return StructuredTool.from_function(
+        func=passthrough,
+        # execute_record_expense -> record_expense; the node adds the prefix back.
+        name=schema["name"].removeprefix(_PREFIX),
+        description=schema.get("description") or schema["name"],
+        args_schema=schema.get("parameters") or {"type": "object", "properties": {}},
+    )
+
+
+def gateway_tools() -> List[BaseTool]:
+    """Fetch the executor tools once; a failed fetch is retried on the next call."""
+    global _tools
+    if _tools is not None:
+        return _tools
+    try:
+        resp = requests.get(f"{GATEWAY_URL}/api/v1/tools", headers=gateway_headers(), timeout=5)
+        resp.raise_for_status()
+        _tools = [_make_tool(s) for s in resp.json()]
+        logger.info("[TOOLS] Loaded %d tool schemas from the gateway", len(_tools))
+    except (requests.RequestException, ValueError, KeyError) as e:
+        logger.warning("[TOOLS] Could not load tool schemas from the gateway: %s", e)
+        return []
+    return _tools
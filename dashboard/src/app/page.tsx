@@ -5,6 +5,7 @@ import { useRouter, useSearchParams } from "next/navigation";
 import { Sidebar } from "@/components/layout/Sidebar";
 import { ChatInterface } from "@/components/chat/ChatInterface";
 import { useSerqet } from "@/hooks/useSerqet";
+import { LoginGate } from "@/components/auth/LoginGate";
 
 // Modules
 import { OverviewModule } from "@/components/modules/OverviewModule";
@@ -119,7 +120,9 @@ export default function Home() {
         Initializing OS...
       </div>
     }>
-      <DashboardContent />
+      <LoginGate>
+        <DashboardContent />
+      </LoginGate>
     </Suspense>
   );
 }
\ No newline at end of file
@@ -0,0 +1,59 @@
+"use client";
+
+import { useEffect, useState } from "react";
+import { Input } from "@/components/ui/input";
+import { getToken, login, UNAUTHORIZED_EVENT } from "@/lib/api";
+
+// LoginGate renders its children once a session token is stored, and the
+// login form until then or after the gateway rejects the token.
+export function LoginGate({ children }: { children: React.ReactNode }) {
+  const [ready, setReady] = useState(false);
+  const [authed, setAuthed] = useState(false);
+  const [email, setEmail] = useState("");
+  const [password, setPassword] = useState("");
+  const [error, setError] = useState<string | null>(null);
+  const [busy, setBusy] = useState(false);
+
+  useEffect(() => {
+    setAuthed(!!getToken());
+    setReady(true);
+    const onUnauthorized = () => setAuthed(false);
+    window.addEventListener(UNAUTHORIZED_EVENT, onUnauthorized);
+    return () => window.removeEventListener(UNAUTHORIZED_EVENT, onUnauthorized);
+  }, []);
+
+  const submit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setBusy(true);
+    setError(null);
+    try {
+      await login(email, password);
+      setAuthed(true);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Login failed");
+    } finally {
+      setBusy(false);
+    }
+  };
+
+  if (!ready) return null;
+  if (authed) return <>{children}</>;
+
+  return (
+    <div className="bg-black h-screen w-screen flex items-center justify-center">
+      <form onSubmit={submit} className="w-80 space-y-4 p-8 bg-zinc-900/80 border border-zinc-800 rounded-2xl">
+        <h1 className="text-primary font-black uppercase tracking-[0.4em] text-sm">Serqet OS</h1>
+        <Input type="email" placeholder="Email" value={email} onChange={(e) => setEmail(e.target.value)} required />
+        <Input type="password" placeholder="Password" value={password} onChange={(e) => setPassword(e.target.value)} required />
+        {error && <p className="text-red-500 text-xs">{error}</p>}
+        <button
+          type="submit"
+          disabled={busy}
+          className="w-full py-2 bg-primary text-black rounded-full text-[10px] font-black tracking-widest disabled:opacity-50"
+        >
+          {busy ? "SIGNING IN..." : "SIGN IN"}
+        </button>
+      </form>
+    </div>
+  );
+}
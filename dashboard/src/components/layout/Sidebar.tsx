@@ -11,7 +11,7 @@ import {
   Globe, Shield, SearchCode, Hash, Clock,
   Activity
 } from "lucide-react";
-import { GATEWAY_URL } from '@/lib/constants';
+import { apiFetch } from '@/lib/api';
 import {
   Dialog,
   DialogContent,
@@ -56,8 +56,8 @@ export function Sidebar({
   const fetchData = useCallback(async () => {
     try {
       const [sessRes, agentRes] = await Promise.all([
-        fetch(`${GATEWAY_URL}/api/v1/sessions`),
-        fetch(`${GATEWAY_URL}/api/v1/agents`)
+        apiFetch(`/api/v1/sessions`),
+        apiFetch(`/api/v1/agents`)
       ]);
       if (sessRes.ok) setSessions(await sessRes.json());
       if (agentRes.ok) setAgents(await agentRes.json());
@@ -67,7 +67,7 @@ export function Sidebar({
   const checkPulse = useCallback(async () => {
     const start = Date.now();
     try {
-      const res = await fetch(`${GATEWAY_URL}/api/v1/health/stats`);
+      const res = await apiFetch(`/api/v1/health/stats`);
       if (res.ok) {
         setLatency(Date.now() - start);
         setSysHealth(prev => ({ ...prev, gateway: 'online' }));
@@ -95,7 +95,7 @@ export function Sidebar({
   }, [sessions, searchQuery]);
 
   const handleNewSession = async () => {
-    const res = await fetch(`${GATEWAY_URL}/api/v1/sessions`, { method: 'POST' });
+    const res = await apiFetch(`/api/v1/sessions`, { method: 'POST' });
     const newSession = await res.json();
     fetchData();
     onSessionSelect(newSession.session_id);
@@ -103,7 +103,7 @@ export function Sidebar({
 
   const saveRename = async (id: string) => {
     if (!editValue.trim()) return setEditingId(null);
-    await fetch(`${GATEWAY_URL}/api/v1/sessions/${id}`, {
+    await apiFetch(`/api/v1/sessions/${id}`, {
       method: 'PATCH',
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify({ title: editValue })
@@ -115,7 +115,7 @@ export function Sidebar({
   const handleDeleteSession = async (e: React.MouseEvent, id: string) => {
     e.stopPropagation();
     if (!confirm("Terminate session and wipe context?")) return;
-    await fetch(`${GATEWAY_URL}/api/v1/sessions/${id}`, { method: 'DELETE' });
+    await apiFetch(`/api/v1/sessions/${id}`, { method: 'DELETE' });
     fetchData();
   };
 
@@ -134,7 +134,7 @@ export function Sidebar({
   };
 
   const saveNeuralDNA = async () => {
-    await fetch(`${GATEWAY_URL}/api/v1/agents/${selectedAgent.slug}`, {
+    await apiFetch(`/api/v1/agents/${selectedAgent.slug}`, {
       method: 'PATCH',
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify({ system_prompt: tempPrompt })
@@ -4,13 +4,13 @@ import { useEffect, useState } from 'react';
 import { Card } from "@/components/ui/card";
 import { Zap, Play, Edit3, CheckCircle, Clock } from "lucide-react";
 import ReactMarkdown from 'react-markdown';
-import { GATEWAY_URL } from '@/lib/constants';
+import { apiFetch } from '@/lib/api';
 
 export function ActionsModule({ onQuickAction }: any) {
   const [actions, setActions] = useState<any[]>([]);
 
   useEffect(() => {
-    fetch(`${GATEWAY_URL}/api/v1/actions/pending`)
+    apiFetch(`/api/v1/actions/pending`)
       .then(res => res.json())
       .then(setActions);
   }, []);
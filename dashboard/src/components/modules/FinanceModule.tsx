@@ -7,14 +7,14 @@ import {
   Terminal, ShieldCheck, Wallet, ArrowRight, BarChart3,
   Rocket, Target, ArrowUpRight, Globe, AlertCircle, RefreshCw
 } from "lucide-react";
-import { GATEWAY_URL } from '@/lib/constants';
+import { apiFetch } from '@/lib/api';
 
 export function FinanceModule({ onQuickAction }: { onQuickAction?: (q: string) => void }) {
   const [subTab, setSubTab] = useState<"algo" | "crypto" | "ventures" | "fiat">("algo");
   const [summary, setSummary] = useState({ total_expenses: 0, total_income: 0, recent_records: [] });
 
   useEffect(() => {
-    fetch(`${GATEWAY_URL}/api/v1/finance/summary`)
+    apiFetch(`/api/v1/finance/summary`)
       .then(res => res.json())
       .then(setSummary)
       .catch(err => console.error("Finance summary fetch error", err));
@@ -65,7 +65,7 @@ function AlgoTradingView({ onQuickAction
 
   const fetchSignals = async () => {
     try {
-      const res = await fetch(`${GATEWAY_URL}/api/v1/finance/signals`);
+      const res = await apiFetch(`/api/v1/finance/signals`);
       const data = await res.json();
       // Ensure we are setting an array and mapping keys correctly
       if (Array.isArray(data)) {
@@ -153,7 +153,7 @@ function VentureHub({ onQuickAction }: { onQuickAction?: (q: string) => void })
   const [loading, setLoading] = useState(true);
 
   const fetchVentures = () => {
-    fetch(`${GATEWAY_URL}/api/v1/finance/ventures`)
+    apiFetch(`/api/v1/finance/ventures`)
       .then(res => res.json())
       .then(data => {
         if (Array.isArray(data)) setVentures(data);
@@ -277,9 +277,9 @@ function CryptoTerminal() {
   const [holdings, setHoldings] = useState<any[]>([]);
 
   useEffect(() => {
-      fetch(`${GATEWAY_URL}/api/v1/finance/holdings`)
+      apiFetch(`/api/v1/finance/holdings`)
           .then(res => res.json())
-          .then(data => { if (Array.isArray(data)) setHoldings(data); });
+          .then(data => { if (Array.isArray(data?.holdings)) setHoldings(data.holdings); });
   }, []);
 
   return (
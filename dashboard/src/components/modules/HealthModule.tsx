@@ -3,14 +3,14 @@
 import { useEffect, useState } from 'react';
 import { Card, CardContent, CardHeader, CardTitle } from "@/components/ui/card";
 import { Utensils, Dumbbell, Activity } from "lucide-react";
-import { GATEWAY_URL } from '@/lib/constants';
+import { apiFetch } from '@/lib/api';
 
 export function HealthModule() {
   const [subTab, setSubTab] = useState<"diet" | "fitness">("diet");
   const [data, setData] = useState<{diet: any[], fitness: any[]}>({ diet: [], fitness: [] });
 
   useEffect(() => {
-    fetch(`${GATEWAY_URL}/api/v1/health/stats`)
+    apiFetch(`/api/v1/health/stats`)
       .then(res => res.json())
       .then(setData);
   }, []);
@@ -1,13 +1,13 @@
 import { useEffect, useState } from 'react';
 import { Card } from "@/components/ui/card";
 import { LayoutDashboard } from "lucide-react";
-import { GATEWAY_URL } from '@/lib/constants';
+import { apiFetch } from '@/lib/api';
 
 export function JobModule() {
   const [jobs, setJobs] = useState<any[]>([]);
 
   useEffect(() => {
-    fetch(`${GATEWAY_URL}/api/v1/jobs`)
+    apiFetch(`/api/v1/jobs`)
       .then(res => res.json())
       .then(setJobs);
   }, []);
@@ -12,13 +12,13 @@ import {
   Linkedin,
   Play
 } from "lucide-react";
-import { GATEWAY_URL } from '@/lib/constants';
+import { apiFetch } from '@/lib/api';
 
 export function OverviewModule({ onQuickAction, onNavigate }: any) {
   const [data, setData] = useState<any>(null);
 
   useEffect(() => {
-    const fetchSnapshot = () => fetch(`${GATEWAY_URL}/api/v1/overview`).then(res => res.json()).then(setData);
+    const fetchSnapshot = () => apiFetch(`/api/v1/overview`).then(res => res.json()).then(setData);
     fetchSnapshot();
     const interval = setInterval(fetchSnapshot, 15000);
     return () => clearInterval(interval);
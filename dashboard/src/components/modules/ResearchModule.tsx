@@ -5,13 +5,13 @@ import { Card, CardContent } from "@/components/ui/card";
 import { Globe, Search, FileText, Terminal, Layers } from "lucide-react";
 import ReactMarkdown from 'react-markdown';
 import remarkGfm from 'remark-gfm';
-import { GATEWAY_URL } from '@/lib/constants';
+import { apiFetch } from '@/lib/api';
 
 export function ResearchModule() {
   const [reports, setReports] = useState<any[]>([]);
 
   useEffect(() => {
-    fetch(`${GATEWAY_URL}/api/v1/research`)
+    apiFetch(`/api/v1/research`)
       .then(res => res.json())
       .then(setReports);
   }, []);
@@ -9,7 +9,7 @@ import {
   Eye, RefreshCw, Wrench, Fingerprint,
   Monitor, Zap, Activity, ShieldAlert
 } from "lucide-react";
-import { GATEWAY_URL } from '@/lib/constants';
+import { apiFetch } from '@/lib/api';
 
 type SettingsTab = 'agents' | 'engine' | 'memory' | 'interface';
 
@@ -40,7 +40,7 @@ export function SettingsModule() {
 
   const fetchAgents = async () => {
     try {
-      const res = await fetch(`${GATEWAY_URL}/api/v1/agents`);
+      const res = await apiFetch(`/api/v1/agents`);
       const data = await res.json();
       setAgents(data);
       // Initialize if needed
@@ -62,7 +62,7 @@ export function SettingsModule() {
     setIsSaving(true);
     try {
       // Sends BOTH system_prompt and allowed_tools to the updated Go API
-      await fetch(`${GATEWAY_URL}/api/v1/agents/${selectedAgent.slug}`, {
+      await apiFetch(`/api/v1/agents/${selectedAgent.slug}`, {
         method: 'PATCH',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({ 
@@ -3,13 +3,13 @@
 import { useEffect, useState } from 'react';
 import { Card } from "@/components/ui/card";
 import { MessageSquare, Share2, Send, Clock, Twitter, Linkedin } from "lucide-react";
-import { GATEWAY_URL } from '@/lib/constants';
+import { apiFetch } from '@/lib/api';
 
 export function SocialModule() {
   const [posts, setPosts] = useState<any[]>([]);
 
   useEffect(() => {
-    fetch(`${GATEWAY_URL}/api/v1/social/posts`).then(res => res.json()).then(setPosts);
+    apiFetch(`/api/v1/social/posts`).then(res => res.json()).then(setPosts);
   }, []);
 
   return (
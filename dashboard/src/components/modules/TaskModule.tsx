@@ -2,13 +2,13 @@
 
 import { useEffect, useState } from 'react';
 import { ListTodo, CheckCircle2, Circle, AlertCircle } from "lucide-react";
-import { GATEWAY_URL } from '@/lib/constants';
+import { apiFetch } from '@/lib/api';
 
 export function TaskModule() {
   const [tasks, setTasks] = useState<any[]>([]);
 
   useEffect(() => {
-    fetch(`${GATEWAY_URL}/api/v1/tasks`).then(res => res.json()).then(setTasks);
+    apiFetch(`/api/v1/tasks`).then(res => res.json()).then(setTasks);
   }, []);
 
   return (
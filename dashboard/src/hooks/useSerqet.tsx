@@ -2,6 +2,7 @@
  
 import { useState, useEffect, useCallback } from "react";
 import { GATEWAY_URL, DEFAULT_USER } from "@/lib/constants";
+import { apiFetch } from "@/lib/api";
 import { ChatMessage, IntentResponse, UploadResponse } from "@/types";
  
 export function useSerqet(
@@ -24,7 +25,7 @@ export function useSerqet(
     if (!activeSessionId) return;
     const load = async () => {
       try {
-        const res = await fetch(`${GATEWAY_URL}/api/v1/history/${activeSessionId}`);
+        const res = await apiFetch(`/api/v1/history/${activeSessionId}`);
         if (!res.ok) return;
         const data = await res.json();
         setChatHistory(
@@ -54,7 +55,7 @@ export function useSerqet(
       if (file) {
         const form = new FormData();
         form.append("file", file);
-        const up = await fetch(`${GATEWAY_URL}/api/v1/upload`, { method: "POST", body: form });
+        const up = await apiFetch(`/api/v1/upload`, { method: "POST", body: form });
         if (!up.ok) throw new Error("Upload failed");
         const upData: UploadResponse = await up.json();
         brainPath  = upData.path;
@@ -69,7 +70,7 @@ export function useSerqet(
       }]);
  
       // 3. Send intent — FIX: user_id read from env, not hardcoded "wired"
-      const res = await fetch(`${GATEWAY_URL}/api/v1/intent`, {
+      const res = await apiFetch(`/api/v1/intent`, {
         method:  "POST",
         headers: { "Content-Type": "application/json" },
         body: JSON.stringify({
@@ -0,0 +1,41 @@
+import { GATEWAY_URL } from "@/lib/constants";
+
+// Every /api/v1 route except register, login and tools needs a token. The
+// session token from /auth/login is kept in localStorage; a 401 clears it
+// and tells the LoginGate to ask again.
+const TOKEN_KEY = "serqet_token";
+export const UNAUTHORIZED_EVENT = "serqet:unauthorized";
+
+export function getToken(): string | null {
+  if (typeof window === "undefined") return null;
+  return localStorage.getItem(TOKEN_KEY);
+}
+
+export function setToken(token: string | null) {
+  if (token) localStorage.setItem(TOKEN_KEY, token);
+  else localStorage.removeItem(TOKEN_KEY);
+}
+
+export async function apiFetch(path: string, init: RequestInit = {}): Promise<Response> {
+  const headers = new Headers(init.headers);
+  const token = getToken();
+  if (token) headers.set("Authorization", `Bearer ${token}`);
+
+  const res = await fetch(`${GATEWAY_URL}${path}`, { ...init, headers });
+  if (res.status === 401) {
+    setToken(null);
+    window.dispatchEvent(new Event(UNAUTHORIZED_EVENT));
+  }
+  return res;
+}
+
+export async function login(email: string, password: string): Promise<void> {
+  const res = await fetch(`${GATEWAY_URL}/api/v1/auth/login`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ email, password }),
+  });
+  const body = await res.json();
+  if (!res.ok) throw new Error(body.error || "Login failed");
+  setToken(body.token);
+}
@@ -1,41 +1,85 @@
 package api
 
 import (
-	"gateway/db"
+	"errors"
 	"gateway/models"
 	"gateway/services"
+
 	"github.com/gofiber/fiber/v3"
 )
 
 func GetPendingActions(c fiber.Ctx) error {
 	var actions []models.PendingAction
-	db.Instance.Where("status = ?", "Pending").Order("created_at desc").Find(&actions)
+	userDB(c).Where("status IN ?", []string{models.ActionPending, models.ActionEdited}).
+		Order("created_at desc").Find(&actions)
 	return c.JSON(actions)
 }
 
 func GetAllActions(c fiber.Ctx) error {
 	var actions []models.PendingAction
-	db.Instance.Order("created_at desc").Find(&actions)
+	userDB(c).Order("created_at desc").Find(&actions)
 	return c.JSON(actions)
 }
 
-func DeployAction(c fiber.Ctx) error {
-	id := c.Params("id")
-	
+func GetAction(c fiber.Ctx) error {
 	var action models.PendingAction
-	if err := db.Instance.First(&action, id).Error; err != nil {
+	if err := userDB(c).First(&action, "id = ?", c.Params("id")).Error; err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Action not found"})
 	}
+	return c.JSON(action)
+}
 
-	// Eventually call a real API 
-	// (e.g., SendGrid for Email, Twitter API for Social)
-	// For now, update the status.
-	db.Instance.Model(&action).Update("status", "Executed")
+type actionReview struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+func actionResult(c fiber.Ctx, action *models.PendingAction, err error) error {
+	switch {
+	case errors.Is(err, services.ErrActionNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Action not found"})
+	case errors.Is(err, services.ErrInvalidTransition):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return err
+	}
+	return c.JSON(action)
+}
 
-	services.EmitEvent("BRAIN", "Action Deployed: "+action.Title, "SUCCESS")
+// RequireTradeAdmin guards approve and retry: deploying a trade order
+// places it on the exchange, so only admins may release one.
+func RequireTradeAdmin(c fiber.Ctx) error {
+	var action models.PendingAction
+	if err := userDB(c).Select("type").First(&action, "id = ?", c.Params("id")).Error; err == nil &&
+		services.AdminOnlyAction(action.Type) {
+		return RequireAdmin(c)
+	}
+	return c.Next()
+}
 
-	return c.JSON(fiber.Map{
-		"status": "Executed",
-		"message": "Deployment successful.",
-	})
-}
\ No newline at end of file
+func ApproveAction(c fiber.Ctx) error {
+	action, err := services.ApproveAction(c.Context(), c.Params("id"), currentUser(c).Email)
+	return actionResult(c, action, err)
+}
+
+func RejectAction(c fiber.Ctx) error {
+	action, err := services.RejectAction(c.Context(), c.Params("id"), currentUser(c).Email)
+	return actionResult(c, action, err)
+}
+
+func EditAction(c fiber.Ctx) error {
+	var body actionReview
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if body.Title == "" && body.Content == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "title or content is required"})
+	}
+	action, err := services.EditAction(c.Context(), c.Params("id"), currentUser(c).Email, body.Title, body.Content)
+	return actionResult(c, action, err)
+}
+
+func RetryAction(c fiber.Ctx) error {
+	action, err := services.RetryAction(c.Context(), c.Params("id"))
+	return actionResult(c, action, err)
+}
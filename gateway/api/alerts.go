@@ -0,0 +1,107 @@
+package api
+
+import (
+	"gateway/models"
+	"gateway/services"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/shopspring/decimal"
+)
+
+type alertBody struct {
+	Name         *string          `json:"name"`
+	Kind         *string          `json:"kind"`
+	Asset        *string          `json:"asset"`
+	Threshold    *decimal.Decimal `json:"threshold"`
+	WindowMins   *int             `json:"window_mins"`
+	CooldownMins *int             `json:"cooldown_mins"`
+	Enabled      *bool            `json:"enabled"`
+}
+
+func (b alertBody) apply(a *models.PriceAlert) {
+	if b.Name != nil {
+		a.Name = *b.Name
+	}
+	if b.Kind != nil {
+		a.Kind = *b.Kind
+	}
+	if b.Asset != nil {
+		a.Asset = *b.Asset
+	}
+	if b.Threshold != nil {
+		a.Threshold = *b.Threshold
+	}
+	if b.WindowMins != nil {
+		a.WindowMins = *b.WindowMins
+	}
+	if b.CooldownMins != nil {
+		a.CooldownMins = *b.CooldownMins
+	}
+	if b.Enabled != nil {
+		a.Enabled = *b.Enabled
+	}
+}
+
+func GetAlerts(c fiber.Ctx) error {
+	var alerts []models.PriceAlert
+	userDB(c).Order("id asc").Find(&alerts)
+	return c.JSON(alerts)
+}
+
+func CreateAlert(c fiber.Ctx) error {
+	var body alertBody
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	alert := models.PriceAlert{Enabled: true, CooldownMins: services.DefaultAlertCooldown}
+	body.apply(&alert)
+	if err := services.ValidateAlert(&alert); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := userDB(c).Create(&alert).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not create alert"})
+	}
+	return c.Status(201).JSON(alert)
+}
+
+func UpdateAlert(c fiber.Ctx) error {
+	var alert models.PriceAlert
+	if err := userDB(c).First(&alert, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Alert not found"})
+	}
+
+	var body alertBody
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	body.apply(&alert)
+	if err := services.ValidateAlert(&alert); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// An edited alert is re-armed and may fire on the next check.
+	alert.Triggered = false
+	if err := userDB(c).Save(&alert).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not update alert"})
+	}
+	return c.JSON(alert)
+}
+
+func DeleteAlert(c fiber.Ctx) error {
+	result := userDB(c).Where("id = ?", c.Params("id")).Delete(&models.PriceAlert{})
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Alert not found"})
+	}
+	return c.JSON(fiber.Map{"status": "deleted"})
+}
+
+// CheckAlerts evaluates the owner's alerts now instead of waiting for the
+// price_alerts job, and returns those that fired.
+func CheckAlerts(c fiber.Ctx) error {
+	fired, err := services.CheckAlerts(c.Context())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"fired": fired})
+}
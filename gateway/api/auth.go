@@ -0,0 +1,129 @@
+package api
+
+import (
+	"errors"
+	"gateway/db"
+	"gateway/models"
+	"gateway/services"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+const userLocal = "user"
+
+func bearerToken(c fiber.Ctx) string {
+	if h := c.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+	}
+	if k := c.Get("X-API-Key"); k != "" {
+		return k
+	}
+	// EventSource cannot set headers.
+	return c.Query("token")
+}
+
+// RequireAuth accepts a session token or API key and scopes the request's
+// context to that user, so db.For(c.Context()) only sees their rows.
+func RequireAuth(c fiber.Ctx) error {
+	user, err := services.Authenticate(bearerToken(c))
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "authentication required"})
+	}
+	c.Locals(userLocal, user)
+	c.SetContext(db.WithOwner(c.Context(), user.ID))
+	return c.Next()
+}
+
+func RequireAdmin(c fiber.Ctx) error {
+	if currentUser(c).Role != models.RoleAdmin {
+		return c.Status(403).JSON(fiber.Map{"error": "admin only"})
+	}
+	return c.Next()
+}
+
+func currentUser(c fiber.Ctx) *models.User {
+	return fiber.Locals[*models.User](c, userLocal)
+}
+
+// userDB is the handle every handler behind RequireAuth should query with.
+func userDB(c fiber.Ctx) *gorm.DB {
+	return db.For(c.Context())
+}
+
+func Register(c fiber.Ctx) error {
+	var body struct {
+		Email    string `json:"email"`
+		Name     string `json:"name"`
+		Password string `json:"password"`
+	}
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	user, err := services.Register(body.Email, body.Name, body.Password)
+	switch {
+	case errors.Is(err, services.ErrSignupClosed):
+		return c.Status(403).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrEmailTaken):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(201).JSON(user)
+}
+
+func Login(c fiber.Ctx) error {
+	var body struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	token, session, err := services.Login(body.Email, body.Password)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"token": token, "expires_at": session.ExpiresAt})
+}
+
+func Logout(c fiber.Ctx) error {
+	services.Logout(bearerToken(c))
+	return c.JSON(fiber.Map{"status": "logged out"})
+}
+
+func GetMe(c fiber.Ctx) error {
+	return c.JSON(currentUser(c))
+}
+
+func GetAPIKeys(c fiber.Ctx) error {
+	var keys []models.APIKey
+	db.Instance.Where("user_id = ?", currentUser(c).ID).Order("created_at desc").Find(&keys)
+	return c.JSON(keys)
+}
+
+func CreateAPIKey(c fiber.Ctx) error {
+	var body struct {
+		Name string `json:"name"`
+	}
+	c.Bind().JSON(&body)
+
+	token, key, err := services.CreateAPIKey(currentUser(c).ID, body.Name)
+	if err != nil {
+		return err
+	}
+	// The plaintext key is only ever returned here.
+	return c.Status(201).JSON(fiber.Map{"key": token, "api_key": key})
+}
+
+func DeleteAPIKey(c fiber.Ctx) error {
+	result := db.Instance.Where("id = ? AND user_id = ?", c.Params("id"), currentUser(c).ID).
+		Delete(&models.APIKey{})
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "API key not found"})
+	}
+	return c.JSON(fiber.Map{"status": "deleted"})
+}
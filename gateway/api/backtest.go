@@ -0,0 +1,45 @@
+package api
+
+import (
+	"errors"
+	"gateway/services"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// RunBacktest replays stored signals or a rule over historical candles.
+func RunBacktest(c fiber.Ctx) error {
+	var req services.BacktestRequest
+	if err := c.Bind().JSON(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	result, err := services.RunBacktest(c.Context(), req)
+	switch {
+	case errors.Is(err, services.ErrNoCandles):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(result)
+}
+
+// IngestCandles backfills stored OHLC for a pair and interval.
+func IngestCandles(c fiber.Ctx) error {
+	var body struct {
+		Pair     string `json:"pair"`
+		Interval int    `json:"interval"`
+	}
+	if err := c.Bind().JSON(&body); err != nil || body.Pair == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "pair is required"})
+	}
+	if body.Interval == 0 {
+		body.Interval = 60
+	}
+
+	n, err := services.IngestCandles(body.Pair, body.Interval)
+	if err != nil {
+		return c.Status(502).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"status": "success", "stored": n})
+}
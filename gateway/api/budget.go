@@ -0,0 +1,109 @@
+package api
+
+import (
+	"gateway/models"
+	"gateway/services"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/shopspring/decimal"
+)
+
+type budgetBody struct {
+	Category     *string          `json:"category"`
+	MonthlyLimit *decimal.Decimal `json:"monthly_limit"`
+	Rollover     *string          `json:"rollover"`
+	MaxRollover  *decimal.Decimal `json:"max_rollover"`
+	StartsOn     *string          `json:"starts_on"` // "2006-01"
+}
+
+func (b budgetBody) apply(budget *models.Budget) error {
+	if b.Category != nil {
+		budget.Category = *b.Category
+	}
+	if b.MonthlyLimit != nil {
+		budget.MonthlyLimit = *b.MonthlyLimit
+	}
+	if b.Rollover != nil {
+		budget.Rollover = *b.Rollover
+	}
+	if b.MaxRollover != nil {
+		budget.MaxRollover = *b.MaxRollover
+	}
+	if b.StartsOn != nil {
+		month, err := services.ParseMonth(*b.StartsOn)
+		if err != nil {
+			return err
+		}
+		budget.StartsOn = month
+	}
+	return nil
+}
+
+func GetBudgets(c fiber.Ctx) error {
+	var budgets []models.Budget
+	userDB(c).Order("category asc").Find(&budgets)
+	return c.JSON(budgets)
+}
+
+func CreateBudget(c fiber.Ctx) error {
+	var body budgetBody
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	var budget models.Budget
+	if err := body.apply(&budget); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := services.ValidateBudget(&budget); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := userDB(c).Create(&budget).Error; err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": "A budget for this category already exists"})
+	}
+	return c.Status(201).JSON(budget)
+}
+
+func UpdateBudget(c fiber.Ctx) error {
+	var budget models.Budget
+	if err := userDB(c).First(&budget, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Budget not found"})
+	}
+
+	var body budgetBody
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := body.apply(&budget); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := services.ValidateBudget(&budget); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := userDB(c).Save(&budget).Error; err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": "A budget for this category already exists"})
+	}
+	return c.JSON(budget)
+}
+
+func DeleteBudget(c fiber.Ctx) error {
+	result := userDB(c).Where("id = ?", c.Params("id")).Delete(&models.Budget{})
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Budget not found"})
+	}
+	return c.JSON(fiber.Map{"status": "deleted"})
+}
+
+// GetBudgetStatus reports each budget's spent, remaining and projected
+// month-end spend for ?month=2006-01 (default: this month).
+func GetBudgetStatus(c fiber.Ctx) error {
+	month, err := services.ParseMonth(c.Query("month"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	report, err := services.BuildBudgetReport(c.Context(), month)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not build budget status"})
+	}
+	return c.JSON(report)
+}
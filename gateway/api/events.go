@@ -0,0 +1,140 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"gateway/db"
+	"gateway/models"
+	"gateway/services"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+const (
+	eventKeepAlive   = 15 * time.Second
+	eventReplayLimit = 500
+)
+
+func splitList(v string) []string {
+	var out []string
+	for _, p := range strings.Split(v, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// GetEvents returns persisted events, newest first, with the same filters
+// as the stream.
+func GetEvents(c fiber.Ctx) error {
+	filter := services.EventFilter{
+		Sources: splitList(c.Query("source")),
+		Levels:  splitList(c.Query("level")),
+	}
+
+	// System events have no owner and are visible to everyone.
+	q := db.Instance.Where("owner_id = ? OR owner_id IS NULL", currentUser(c).ID).
+		Order("id desc").Limit(fiber.Query[int](c, "limit", 50))
+	if len(filter.Sources) > 0 {
+		q = q.Where("UPPER(source) IN ?", upperAll(filter.Sources))
+	}
+	if len(filter.Levels) > 0 {
+		q = q.Where("UPPER(level) IN ?", upperAll(filter.Levels))
+	}
+
+	var events []models.SystemEvent
+	q.Find(&events)
+	return c.JSON(events)
+}
+
+func upperAll(in []string) []string {
+	out := make([]string, len(in))
+	for i, v := range in {
+		out[i] = strings.ToUpper(v)
+	}
+	return out
+}
+
+// StreamEvents pushes SystemEvents over SSE. Clients may filter with
+// ?source=BRAIN,EXECUTOR&level=ERROR and resume with the Last-Event-ID
+// header (or ?last_event_id= for EventSource polyfills that cannot set it).
+func StreamEvents(c fiber.Ctx) error {
+	filter := services.EventFilter{
+		Owner:   currentUser(c).ID,
+		Sources: splitList(c.Query("source")),
+		Levels:  splitList(c.Query("level")),
+	}
+
+	lastID := c.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = c.Query("last_event_id")
+	}
+	var since uint64
+	if lastID != "" {
+		since, _ = strconv.ParseUint(lastID, 10, 64)
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	// Subscribe before replaying so nothing emitted in between is lost;
+	// live deliveries already sent by the replay are dropped below.
+	sub := services.Events.Subscribe(filter)
+
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		defer sub.Close()
+
+		send := func(ev models.SystemEvent) error {
+			fmt.Fprintf(w, "id: %d\nevent: system\ndata: %s\n\n", ev.ID, mustJSON(ev))
+			return w.Flush()
+		}
+
+		// IDs are not delivered in order (concurrent transactions commit
+		// out of order), so only the replayed IDs are deduplicated.
+		replayed := map[uint]bool{}
+		if since > 0 {
+			var backlog []models.SystemEvent
+			db.Instance.Where("id > ? AND (owner_id = ? OR owner_id IS NULL)", since, filter.Owner).Order("id asc").Limit(eventReplayLimit).Find(&backlog)
+			for _, ev := range backlog {
+				if !filter.Match(ev) {
+					continue
+				}
+				replayed[ev.ID] = true
+				if send(ev) != nil {
+					return
+				}
+			}
+		}
+
+		keepAlive := time.NewTicker(eventKeepAlive)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case ev, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				if replayed[ev.ID] {
+					delete(replayed, ev.ID)
+					continue
+				}
+				if send(ev) != nil {
+					return
+				}
+			case <-keepAlive.C:
+				// Writing is the only way to notice a dropped client.
+				w.WriteString(": keep-alive\n\n")
+				if w.Flush() != nil {
+					return
+				}
+			}
+		}
+	})
+}
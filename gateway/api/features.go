@@ -1,7 +1,6 @@
 package api
 
 import (
-	"gateway/db"
 	"gateway/models"
 
 	"github.com/gofiber/fiber/v3"
@@ -9,7 +8,7 @@ import (
 
 func GetSocialPosts(c fiber.Ctx) error {
 	var posts []models.SocialPost
-	result := db.Instance.Order("created_at desc").Find(&posts)
+	result := userDB(c).Order("created_at desc").Find(&posts)
 	if result.Error != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Could not fetch social posts"})
 	}
@@ -18,13 +17,13 @@ func GetSocialPosts(c fiber.Ctx) error {
 
 func GetTasks(c fiber.Ctx) error {
 	var tasks []models.TaskRecord
-	db.Instance.Order("created_at desc").Find(&tasks)
+	userDB(c).Order("created_at desc").Find(&tasks)
 	return c.JSON(tasks)
 }
 
 func GetJobs(c fiber.Ctx) error {
 	var jobs []models.JobApplication
-	db.Instance.Order("created_at desc").Find(&jobs)
+	userDB(c).Order("created_at desc").Find(&jobs)
 	return c.JSON(jobs)
 }
 
@@ -32,8 +31,8 @@ func GetHealthStats(c fiber.Ctx) error {
 	var meals []models.DietRecord
 	var workouts []models.WorkoutRecord
 	
-	db.Instance.Order("created_at desc").Limit(10).Find(&meals)
-	db.Instance.Order("created_at desc").Limit(10).Find(&workouts)
+	userDB(c).Order("created_at desc").Limit(10).Find(&meals)
+	userDB(c).Order("created_at desc").Limit(10).Find(&workouts)
 
 	return c.JSON(fiber.Map{
 		"diet": meals,
@@ -43,6 +42,6 @@ func GetHealthStats(c fiber.Ctx) error {
 
 func GetResearch(c fiber.Ctx) error {
 	var reports []models.ResearchReports
-	db.Instance.Order("created_at desc").Limit(10).Find(&reports)
+	userDB(c).Order("created_at desc").Limit(10).Find(&reports)
 	return c.JSON(reports)
 }
\ No newline at end of file
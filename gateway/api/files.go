@@ -2,6 +2,7 @@ package api
 
 import (
 	"fmt"
+	"mime/multipart"
 	"os"
 	"path/filepath"
 
@@ -9,25 +10,40 @@ import (
 	"github.com/google/uuid"
 )
 
-func UploadHandler(c fiber.Ctx) error {
-    file, err := c.FormFile("file")
-    if err != nil { return c.Status(400).JSON(fiber.Map{"error": "No file"}) }
-
-    uploadDir := "./uploads"
-    os.MkdirAll(uploadDir, 0755)
+const uploadDir = "./uploads"
 
-    filename := uuid.New().String() + filepath.Ext(file.Filename)
-    savePath := filepath.Join(uploadDir, filename)
+// saveUpload stores file under uploadDir with a random name and returns it.
+func saveUpload(c fiber.Ctx, file *multipart.FileHeader) (string, error) {
+	os.MkdirAll(uploadDir, 0755)
 
-    if err := c.SaveFile(file, savePath); err != nil {
-        return c.Status(500).JSON(fiber.Map{"error": "Save failed"})
-    }
+	filename := uuid.New().String() + filepath.Ext(file.Filename)
+	if err := c.SaveFile(file, filepath.Join(uploadDir, filename)); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
 
-    absPath, _ := filepath.Abs(savePath)
+// uploadPath resolves a filename returned by UploadHandler.
+func uploadPath(filename string) string {
+	return filepath.Join(uploadDir, filepath.Base(filename))
+}
 
-    return c.JSON(fiber.Map{
-        "url":      fmt.Sprintf("/uploads/%s", filename),
-        "path":     absPath,
-        "filename": filename,
-    })
-}
\ No newline at end of file
+func UploadHandler(c fiber.Ctx) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "No file"})
+	}
+
+	filename, err := saveUpload(c, file)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Save failed"})
+	}
+
+	absPath, _ := filepath.Abs(uploadPath(filename))
+
+	return c.JSON(fiber.Map{
+		"url":      fmt.Sprintf("/uploads/%s", filename),
+		"path":     absPath,
+		"filename": filename,
+	})
+}
@@ -1,11 +1,11 @@
 package api
 
 import (
-	"gateway/db"
+	"errors"
 	"gateway/models"
 	"gateway/services"
-	"gateway/utils"
 	"log"
+	"strings"
 
 	"github.com/gofiber/fiber/v3"
 	// "gorm.io/gorm"
@@ -13,70 +13,52 @@ import (
 
 func GetVentures(c fiber.Ctx) error {
 	var ventures []models.VentureCampaign
-	if err := db.Instance.Order("created_at desc").Find(&ventures).Error; err != nil {
+	if err := userDB(c).Order("created_at desc").Find(&ventures).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Could not fetch ventures"})
 	}
 	return c.JSON(ventures)
 }
 
+// GetFinanceSummary totals income, expenses and venture revenue in
+// ?currency= (default services.DefaultCurrency).
 func GetFinanceSummary(c fiber.Ctx) error {
-	var recent []models.FinanceRecord
-	db.Instance.Order("created_at desc").Limit(10).Find(&recent)
-
-	var totalExpenses float64
-	db.Instance.Model(&models.FinanceRecord{}).
-		Where("type = ?", "expense").
-		Select("COALESCE(sum(amount), 0)").
-		Scan(&totalExpenses)
-
-	var manualIncome float64
-	db.Instance.Model(&models.FinanceRecord{}).
-		Where("type = ?", "income").
-		Select("COALESCE(sum(amount), 0)").
-		Scan(&manualIncome)
-
-	var ventureRevenue float64
-	db.Instance.Model(&models.VentureCampaign{}).
-		Select("COALESCE(sum(revenue_earned), 0)").
-		Scan(&ventureRevenue)
-
-	return c.JSON(fiber.Map{
-		"total_expenses": totalExpenses,
-		"total_income":   manualIncome + ventureRevenue,
-		"recent_records": recent,
-	})
+	currency, err := reportingCurrency(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	summary, err := services.BuildFinanceSummary(c.Context(), currency)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not build summary"})
+	}
+	return c.JSON(summary)
 }
 
 func SyncHoldings(c fiber.Ctx) error {
-	balances, err := services.FetchKrakenBalances()
-	if err != nil {
+	imported, synced, err := services.SyncKraken(c.Context())
+	switch {
+	case errors.Is(err, services.ErrNotExchangeOwner):
+		return c.Status(403).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	for asset, amountStr := range balances {
-		amount := utils.ParseNumeric(amountStr)
-		log.Printf("%f", amount)
-		if amount > 0 {
-			db.Instance.Where(models.CryptoHoldings{Asset: asset}).
-				Assign(models.CryptoHoldings{Balance: amount}).
-				FirstOrCreate(&models.CryptoHoldings{})
-		}
-	}
-
-	return c.JSON(fiber.Map{"status": "success", "synced": len(balances)})
+	return c.JSON(fiber.Map{"status": "success", "synced": synced, "imported": imported})
 }
 
-
+// GetCryptoHoldings returns each holding with its price and unrealized
+// P&L, plus portfolio totals.
 func GetCryptoHoldings(c fiber.Ctx) error {
-	var holdings []models.CryptoHoldings
-	db.Instance.Find(&holdings)
-	return c.JSON(holdings)
+	value, err := services.ValueHoldings(c.Context())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not value holdings"})
+	}
+	return c.JSON(value)
 }
 
 func GetSignals(c fiber.Ctx) error {
 	var signals []models.TradingSignal
 	
-	result := db.Instance.Order("status desc, created_at desc").Limit(10).Find(&signals)
+	result := userDB(c).Order("status desc, created_at desc").Limit(10).Find(&signals)
 	if result.Error != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Could not fetch trading signals"})
 	}
@@ -91,36 +73,113 @@ func UpdateSignalStatus(c fiber.Ctx) error {
 	}
 	c.Bind().JSON(&body)
 
-	db.Instance.Model(&models.TradingSignal{}).Where("id = ?", id).Update("status", body.Status)
+	userDB(c).Model(&models.TradingSignal{}).Where("id = ?", id).Update("status", body.Status)
 	return c.JSON(fiber.Map{"status": "updated"})
 }
 
-func GetNetWorthAnalysis(c fiber.Ctx) error {
-	var holdings []models.CryptoHoldings
-	var expenses []models.FinanceRecord
-	
-	// Use global Instance for consistency
-	db.Instance.Find(&holdings)
-	db.Instance.Find(&expenses)
+// GetOHLCData serves candles from the local store. interval takes 1m-1w
+// (default 1h); since is a unix time, and only newer candles are returned.
+func GetOHLCData(c fiber.Ctx) error {
+	pair := c.Query("pair", "XXBTZUSD")
+	interval, err := services.ParseInterval(c.Query("interval", "1h"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
 
-	totalExpenses := 0.0
-	for _, e := range expenses {
-		totalExpenses += e.Amount
+	candles, err := services.CachedCandles(pair, interval, fiber.Query[int64](c, "since", 0))
+	if err != nil {
+		log.Printf("[OHLC] %s: %v", pair, err)
+		return c.Status(500).JSON(fiber.Map{"error": "Error fetching market data"})
 	}
 
-	return c.JSON(fiber.Map{
-		"crypto_holdings": holdings,
-		"total_spent":     totalExpenses,
-		"status":          "Neural analysis complete.",
-	})
+	return c.JSON(candles)
+}
+
+func signalResult(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrSignalNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "Signal not found"})
+	case errors.Is(err, services.ErrSignalNotTradable):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrTradeLimit):
+		return c.Status(422).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(502).JSON(fiber.Map{"error": err.Error()})
 }
 
-func GetOHLCData(c fiber.Ctx) error {
-	pair := c.Query("pair", "XXBTZUSD")
-	candles, err := services.FetchMarketCandles(pair)
+// OrderSignal sends a BUY/SELL signal's order to the Action Center for
+// approval, or with "validate": true has Kraken check it without trading.
+func OrderSignal(c fiber.Ctx) error {
+	var body services.SignalOrder
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	signal, description, err := services.ProposeSignalOrder(c.Context(), c.Params("id"), body)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Error fetching market data"})
+		return signalResult(c, err)
+	}
+	if body.Validate {
+		return c.JSON(fiber.Map{"status": "validated", "order": description, "signal": signal})
 	}
+	return c.Status(202).JSON(fiber.Map{"status": "awaiting approval", "order": description, "signal": signal})
+}
 
-	return c.JSON(candles)
-}
\ No newline at end of file
+// RefreshSignalOrder re-reads fills and fees for a signal's placed order.
+func RefreshSignalOrder(c fiber.Ctx) error {
+	signal, err := services.RefreshSignalOrder(c.Context(), c.Params("id"))
+	if err != nil {
+		return signalResult(c, err)
+	}
+	return c.JSON(signal)
+}
+
+func GetTradeLimits(c fiber.Ctx) error {
+	return c.JSON(services.CurrentTradeLimits())
+}
+
+// maxHistoryLimit caps ?limit on the ledger and trade listings.
+const maxHistoryLimit = 1000
+
+// historyLimit reads ?limit, defaulting to 100 and clamped to
+// [1, maxHistoryLimit].
+func historyLimit(c fiber.Ctx) int {
+	return max(1, min(fiber.Query[int](c, "limit", 100), maxHistoryLimit))
+}
+
+// GetLedger lists imported exchange ledger entries, newest first.
+func GetLedger(c fiber.Ctx) error {
+	query := userDB(c).Order("time desc").Limit(historyLimit(c))
+	if t := c.Query("type"); t != "" {
+		query = query.Where("type = ?", t)
+	}
+	if a := c.Query("asset"); a != "" {
+		query = query.Where("asset = ?", strings.ToUpper(a))
+	}
+	var entries []models.LedgerEntry
+	query.Find(&entries)
+	return c.JSON(entries)
+}
+
+func GetExchangeTrades(c fiber.Ctx) error {
+	query := userDB(c).Order("time desc").Limit(historyLimit(c))
+	if p := c.Query("pair"); p != "" {
+		query = query.Where("pair = ?", p)
+	}
+	var trades []models.ExchangeTrade
+	query.Find(&trades)
+	return c.JSON(trades)
+}
+
+// ImportKrakenHistory pulls new ledger entries and trades without
+// touching holdings.
+func ImportKrakenHistory(c fiber.Ctx) error {
+	summary, err := services.ImportKrakenHistory(c.Context())
+	switch {
+	case errors.Is(err, services.ErrNotExchangeOwner):
+		return c.Status(403).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(502).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(summary)
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"errors"
+	"gateway/services"
+	"gateway/utils"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/shopspring/decimal"
+)
+
+// queryDecimal reads an optional decimal query parameter.
+func queryDecimal(c fiber.Ctx, key string) (*decimal.Decimal, error) {
+	s := c.Query(key)
+	if s == "" {
+		return nil, nil
+	}
+	d, err := utils.ParseDecimal(s)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// GetCashFlowForecast projects the cash balance ?days=30|90|180 ahead in
+// ?currency=. ?floor= overrides CASHFLOW_FLOOR and ?balance= the starting
+// balance taken from the cash accounts.
+func GetCashFlowForecast(c fiber.Ctx) error {
+	opts := services.ForecastOptions{Days: fiber.Query[int](c, "days", 30)}
+	if !services.ValidForecastHorizon(opts.Days) {
+		return c.Status(400).JSON(fiber.Map{"error": "days must be 30, 90 or 180"})
+	}
+	var err error
+	if opts.Currency, err = reportingCurrency(c); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if opts.Floor, err = queryDecimal(c, "floor"); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "floor: " + err.Error()})
+	}
+	if opts.StartingBalance, err = queryDecimal(c, "balance"); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "balance: " + err.Error()})
+	}
+
+	forecast, err := services.ForecastCashFlow(c.Context(), opts)
+	switch {
+	case errors.Is(err, services.ErrNoFXRate):
+		return c.Status(422).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(500).JSON(fiber.Map{"error": "Could not build forecast"})
+	}
+	return c.JSON(forecast)
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"errors"
+	"gateway/db"
+	"gateway/models"
+	"gateway/services"
+	"gateway/utils"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/shopspring/decimal"
+)
+
+// GetFXRates lists cached rates, newest first, optionally for one currency.
+func GetFXRates(c fiber.Ctx) error {
+	query := db.Instance.Order("as_of desc, base asc, quote asc").Limit(fiber.Query[int](c, "limit", 200))
+	if cur := strings.ToUpper(c.Query("currency")); cur != "" {
+		query = query.Where("base = ? OR quote = ?", cur, cur)
+	}
+	var rates []models.FXRate
+	query.Find(&rates)
+	return c.JSON(rates)
+}
+
+type fxRateBody struct {
+	Base  string          `json:"base"`
+	Quote string          `json:"quote"`
+	Rate  decimal.Decimal `json:"rate"`
+	AsOf  string          `json:"as_of"` // "2006-01-02"; empty means today
+}
+
+// SetFXRate stores a manually entered rate, replacing any for the same
+// pair and day.
+func SetFXRate(c fiber.Ctx) error {
+	var body fxRateBody
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	rate := models.FXRate{Base: body.Base, Quote: body.Quote, Rate: body.Rate, Source: "manual"}
+	if body.AsOf != "" {
+		day, err := parseDay(body.AsOf)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "as_of must look like 2006-01-02"})
+		}
+		rate.AsOf = day
+	}
+	if err := services.ValidateFXRate(&rate); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if _, err := services.SaveFXRates([]models.FXRate{rate}); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not save rate"})
+	}
+	return c.Status(201).JSON(rate)
+}
+
+// ImportFXRates loads a .json or .csv rate file, sent as multipart "file"
+// or named by the "filename" an earlier /upload returned.
+func ImportFXRates(c fiber.Ctx) error {
+	var name string
+	if file, err := c.FormFile("file"); err == nil {
+		if name, err = saveUpload(c, file); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Save failed"})
+		}
+	} else if name = c.FormValue("filename"); name == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "No file"})
+	}
+
+	loaded, err := services.LoadFXRatesFile(uploadPath(name))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return c.Status(404).JSON(fiber.Map{"error": "Uploaded file not found"})
+	case err != nil:
+		return c.Status(422).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"status": "loaded", "rates": loaded})
+}
+
+// ConvertCurrency converts amount from one currency to another at the rate
+// for date (default today).
+func ConvertCurrency(c fiber.Ctx) error {
+	from, err := services.NormalizeCurrency(c.Query("from"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	to, err := services.NormalizeCurrency(c.Query("to", services.DefaultCurrency()))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if from == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "from is required"})
+	}
+	at := time.Now()
+	if d := c.Query("date"); d != "" {
+		if at, err = parseDay(d); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "date must look like 2006-01-02"})
+		}
+	}
+
+	fx, err := services.LoadFXTable()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not load FX rates"})
+	}
+	rate, err := fx.Rate(from, to, at)
+	if err != nil {
+		return c.Status(422).JSON(fiber.Map{"error": err.Error()})
+	}
+	amount := decimal.NewFromInt(1)
+	if a := c.Query("amount"); a != "" {
+		if amount, err = utils.ParseDecimal(a); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+	converted := utils.RoundMoney(amount.Mul(rate), to)
+	return c.JSON(fiber.Map{"from": from, "to": to, "rate": rate, "amount": amount, "converted": converted})
+}
+
+// reportingCurrency reads ?currency=, defaulting to services.DefaultCurrency.
+func reportingCurrency(c fiber.Ctx) (string, error) {
+	cur, err := services.NormalizeCurrency(c.Query("currency"))
+	if cur == "" && err == nil {
+		cur = services.DefaultCurrency()
+	}
+	return cur, err
+}
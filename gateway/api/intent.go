@@ -1,51 +1,141 @@
 package api
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
 	"gateway/db"
 	"gateway/models"
 	"gateway/services"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
 )
 
-func HandleIntent(c fiber.Ctx) error {
-	var body struct {
-		UserID    string `json:"user_id"`
-		SessionID string `json:"session_id"`
-		Query     string `json:"query"`
-		FilePath  string `json:"file_path,omitempty"`
-		WebURL    string `json:"web_url"`
-	}
+// streamTimeout bounds a streaming intent end to end.
+const streamTimeout = 5 * time.Minute
+
+type intentRequest struct {
+	UserID    string `json:"-"`
+	SessionID string `json:"session_id"`
+	Query     string `json:"query"`
+	FilePath  string `json:"file_path,omitempty"`
+	WebURL    string `json:"web_url"`
+}
+
+func bindIntent(c fiber.Ctx) (*intentRequest, error) {
+	var body intentRequest
 	if err := c.Bind().JSON(&body); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+		return nil, fiber.NewError(400, "invalid request body")
 	}
 	if body.Query == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "query is required"})
+		return nil, fiber.NewError(400, "query is required")
 	}
 	if body.SessionID == "" {
 		body.SessionID = "default"
 	}
-	if body.UserID == "" {
-		body.UserID = "user"
-	}
+	body.UserID = currentUser(c).ID.String()
+	return &body, nil
+}
 
+func recentHistory(ctx context.Context, sessionID string) []models.ChatHistory {
 	var history []models.ChatHistory
-	db.Instance.Where("session_id = ?", body.SessionID).
+	db.For(ctx).Where("session_id = ?", sessionID).
 		Order("created_at desc").Limit(5).Find(&history)
+	return history
+}
+
+func HandleIntent(c fiber.Ctx) error {
+	body, err := bindIntent(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
 
-	services.EmitEvent("BRAIN", "Processing: "+body.SessionID, "INFO")
+	ctx := c.Context()
+	history := recentHistory(ctx, body.SessionID)
+
+	services.EmitEvent(ctx, "BRAIN", "Processing: "+body.SessionID, "INFO")
 
 	brainRes, err := services.RequestIntent(
 		body.UserID, body.SessionID, body.Query, body.FilePath, history,
 	)
 	if err != nil {
-		services.EmitEvent("BRAIN", "Neural Link failure", "ERROR")
+		services.EmitEvent(ctx, "BRAIN", "Neural Link failure", "ERROR")
 		return c.Status(502).JSON(fiber.Map{"error": "Brain offline"})
 	}
 
+	finalizeIntent(ctx, body, brainRes)
+	return c.JSON(brainRes)
+}
+
+// HandleIntentStream is the SSE variant of HandleIntent. Brain events are
+// relayed as they arrive; once the Brain finishes, the plan is executed, each
+// step is sent as a "step" event and a closing "done" event carries the same
+// payload HandleIntent would have returned.
+func HandleIntentStream(c fiber.Ctx) error {
+	body, err := bindIntent(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// The request context is captured now; the writer below runs after this
+	// handler has returned.
+	owner := c.Context()
+	history := recentHistory(owner, body.SessionID)
+
+	services.EmitEvent(owner, "BRAIN", "Streaming: "+body.SessionID, "INFO")
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithTimeout(owner, streamTimeout)
+		defer cancel()
+
+		// A failed flush means the dashboard disconnected; returning the
+		// error aborts the upstream Brain request.
+		relay := func(ev services.BrainEvent) error {
+			err := writeSSE(w, ev.Event, ev.Data)
+			if err != nil {
+				cancel()
+			}
+			return err
+		}
+
+		brainRes, err := services.StreamIntent(ctx,
+			body.UserID, body.SessionID, body.Query, body.FilePath, history, relay)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("[STREAM] %v", err)
+				services.EmitEvent(owner, "BRAIN", "Neural Link failure", "ERROR")
+				writeSSE(w, "error", mustJSON(fiber.Map{"error": "Brain offline"}))
+			} else {
+				log.Printf("[STREAM] Session %s aborted: %v", body.SessionID, ctx.Err())
+			}
+			return
+		}
+
+		finalizeIntent(owner, body, brainRes)
+
+		for _, r := range brainRes.Results {
+			if writeSSE(w, "step", mustJSON(r)) != nil {
+				return
+			}
+		}
+		writeSSE(w, "done", mustJSON(brainRes))
+	})
+}
+
+// finalizeIntent persists the exchange and executes the Brain's plan,
+// rewriting brainRes with the outcome.
+func finalizeIntent(ctx context.Context, body *intentRequest, brainRes *services.BrainResponse) {
 	// Persist user message
-	db.Instance.Create(&models.ChatHistory{
+	db.For(ctx).Create(&models.ChatHistory{
 		UserID:    body.UserID,
 		SessionID: body.SessionID,
 		Role:      "user",
@@ -53,22 +143,42 @@ func HandleIntent(c fiber.Ctx) error {
 		FilePath:  body.WebURL,
 	})
 
-	if brainRes.Action != "" {
-		log.Printf("[EXECUTOR] Action=%s", brainRes.Action)
-		services.EmitEvent("EXECUTOR", "Tool: "+brainRes.Action, "INFO")
-		msg, nav := services.ExecuteToolCall(brainRes.Action, brainRes.Data)
+	if plan := brainRes.Plan(); len(plan) > 0 {
+		log.Printf("[EXECUTOR] Plan with %d step(s)", len(plan))
+		services.EmitEvent(ctx, "EXECUTOR", fmt.Sprintf("Plan: %d step(s)", len(plan)), "INFO")
+
+		results, err := services.ExecutePlan(ctx, plan)
+		brainRes.Results = results
+
+		for _, r := range results {
+			text := r.Message
+			if r.Error != "" {
+				text = r.Error
+			}
+			db.For(ctx).Create(&models.ChatHistory{
+				UserID:    body.UserID,
+				SessionID: body.SessionID,
+				Role:      "tool",
+				Text:      fmt.Sprintf("[%d] %s: %s %s", r.Step, r.Action, r.Status, text),
+			})
+		}
 
-		if msg != "" {
-			brainRes.Message = msg
+		if err != nil {
+			log.Printf("[EXECUTOR] %v", err)
+			services.EmitEvent(ctx, "EXECUTOR", "Plan rolled back", "ERROR")
+			brainRes.Message = "No changes were saved: " + err.Error()
+			brainRes.Action = ""
+		} else if msgs, nav := planSummary(results); len(msgs) > 0 {
+			brainRes.Message = strings.Join(msgs, " ")
 			brainRes.Action = nav
-			services.EmitEvent("EXECUTOR", "Success: "+nav, "SUCCESS")
+			services.EmitEvent(ctx, "EXECUTOR", "Success: "+nav, "SUCCESS")
 		} else {
-			log.Printf("[EXECUTOR] Empty result for action: %s", brainRes.Action)
+			log.Printf("[EXECUTOR] Empty result for plan")
 		}
 	}
 
 	// Persist agent response
-	db.Instance.Create(&models.ChatHistory{
+	db.For(ctx).Create(&models.ChatHistory{
 		UserID:    body.UserID,
 		SessionID: body.SessionID,
 		Role:      "serqet",
@@ -76,6 +186,36 @@ func HandleIntent(c fiber.Ctx) error {
 		FilePath:  body.WebURL,
 		AudioURL:  brainRes.AudioURL,
 	})
+}
 
-	return c.JSON(brainRes)
+// planSummary collects the messages of executed steps and the navigation
+// target of the last one.
+func planSummary(results []services.StepResult) ([]string, string) {
+	var msgs []string
+	nav := ""
+	for _, r := range results {
+		if r.Status != services.StepExecuted || r.Message == "" {
+			continue
+		}
+		msgs = append(msgs, r.Message)
+		nav = r.Nav
+	}
+	return msgs, nav
+}
+
+func writeSSE(w *bufio.Writer, event string, data []byte) error {
+	fmt.Fprintf(w, "event: %s\n", event)
+	for _, line := range strings.Split(string(data), "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	w.WriteString("\n")
+	return w.Flush()
+}
+
+func mustJSON(v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return []byte("{}")
+	}
+	return b
 }
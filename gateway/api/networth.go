@@ -0,0 +1,146 @@
+package api
+
+import (
+	"errors"
+	"gateway/models"
+	"gateway/services"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/shopspring/decimal"
+)
+
+type netWorthAccountBody struct {
+	Name     *string          `json:"name"`
+	Kind     *string          `json:"kind"`
+	Balance  *decimal.Decimal `json:"balance"`
+	Currency *string          `json:"currency"`
+	Notes    *string          `json:"notes"`
+}
+
+func (b netWorthAccountBody) apply(a *models.NetWorthAccount) {
+	if b.Name != nil {
+		a.Name = *b.Name
+	}
+	if b.Kind != nil {
+		a.Kind = *b.Kind
+	}
+	if b.Balance != nil {
+		a.Balance = *b.Balance
+	}
+	if b.Currency != nil {
+		a.Currency = *b.Currency
+	}
+	if b.Notes != nil {
+		a.Notes = *b.Notes
+	}
+}
+
+func GetNetWorthAccounts(c fiber.Ctx) error {
+	var accounts []models.NetWorthAccount
+	userDB(c).Order("kind asc, name asc").Find(&accounts)
+	return c.JSON(accounts)
+}
+
+func CreateNetWorthAccount(c fiber.Ctx) error {
+	var body netWorthAccountBody
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	var account models.NetWorthAccount
+	body.apply(&account)
+	if err := services.ValidateNetWorthAccount(&account); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := userDB(c).Create(&account).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not save account"})
+	}
+	return c.Status(201).JSON(account)
+}
+
+func UpdateNetWorthAccount(c fiber.Ctx) error {
+	var account models.NetWorthAccount
+	if err := userDB(c).First(&account, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Account not found"})
+	}
+
+	var body netWorthAccountBody
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	body.apply(&account)
+	if err := services.ValidateNetWorthAccount(&account); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := userDB(c).Save(&account).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not save account"})
+	}
+	return c.JSON(account)
+}
+
+func DeleteNetWorthAccount(c fiber.Ctx) error {
+	result := userDB(c).Where("id = ?", c.Params("id")).Delete(&models.NetWorthAccount{})
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Account not found"})
+	}
+	return c.JSON(fiber.Map{"status": "deleted"})
+}
+
+// GetNetWorthAnalysis values holdings and accounts now, in ?currency=,
+// without recording a snapshot.
+func GetNetWorthAnalysis(c fiber.Ctx) error {
+	currency, err := reportingCurrency(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	current, err := services.MeasureNetWorth(c.Context(), currency)
+	switch {
+	case errors.Is(err, services.ErrNoFXRate):
+		return c.Status(422).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(500).JSON(fiber.Map{"error": "Could not value net worth"})
+	}
+	return c.JSON(current)
+}
+
+// GetNetWorthHistory serves the snapshots as a series for charting:
+// ?period=day|week|month (default day), ?since=2006-01-02 and ?currency=.
+func GetNetWorthHistory(c fiber.Ctx) error {
+	currency, err := reportingCurrency(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	period := c.Query("period", "day")
+	if !services.ValidNetWorthPeriod(period) {
+		return c.Status(400).JSON(fiber.Map{"error": "period must be day, week or month"})
+	}
+	var since time.Time
+	if s := c.Query("since"); s != "" {
+		if since, err = parseDay(s); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "since must look like 2006-01-02"})
+		}
+	}
+
+	points, err := services.NetWorthHistory(c.Context(), period, since, currency)
+	switch {
+	case errors.Is(err, services.ErrNoFXRate):
+		return c.Status(422).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(500).JSON(fiber.Map{"error": "Could not build net worth history"})
+	}
+	return c.JSON(fiber.Map{"currency": currency, "points": points})
+}
+
+// SnapshotNetWorth records today's snapshot now instead of waiting for the
+// net_worth_snapshots job.
+func SnapshotNetWorth(c fiber.Ctx) error {
+	snapshot, err := services.SnapshotNetWorth(c.Context())
+	switch {
+	case errors.Is(err, services.ErrNoFXRate):
+		return c.Status(422).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(500).JSON(fiber.Map{"error": "Could not record snapshot"})
+	}
+	return c.JSON(snapshot)
+}
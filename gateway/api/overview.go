@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"gateway/db"
 	"gateway/models"
+	"gateway/services"
+	"log"
 	"net/http"
 	"runtime"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shopspring/decimal"
 )
 
 var StartTime = time.Now()
@@ -24,7 +27,7 @@ type OverviewSnapshot struct {
 	Actions      []models.PendingAction   `json:"actions"` // NEW: For Action Center
 	Events       []models.SystemEvent     `json:"events"`  // NEW: For Brain Logs
 	Health       map[string]interface{}   `json:"health"`
-	Revenue      float64                  `json:"total_revenue"`
+	Revenue      decimal.Decimal          `json:"total_revenue"`
 	SystemStats  map[string]interface{}   `json:"system_stats"`
 }
 
@@ -37,18 +40,21 @@ func GetOverviewSnapshot(c fiber.Ctx) error {
 	var actions []models.PendingAction
 	var events []models.SystemEvent
 
-	db.Instance.Order("created_at desc").Limit(3).Find(&intel)
-	db.Instance.Order("balance desc").Limit(4).Find(&holdings)
-	db.Instance.Where("status = ?", "Pending").Order("created_at desc").Limit(3).Find(&tasks)
-	db.Instance.Order("created_at desc").Limit(2).Find(&social)
-	db.Instance.Order("created_at desc").Limit(2).Find(&jobs)
+	userDB(c).Order("created_at desc").Limit(3).Find(&intel)
+	userDB(c).Order("usd_value desc").Limit(4).Find(&holdings)
+	userDB(c).Where("status = ?", "Pending").Order("created_at desc").Limit(3).Find(&tasks)
+	userDB(c).Order("created_at desc").Limit(2).Find(&social)
+	userDB(c).Order("created_at desc").Limit(2).Find(&jobs)
 	
-	db.Instance.Where("status = ?", "Pending").Order("created_at desc").Limit(5).Find(&actions)
+	userDB(c).Where("status IN ?", []string{models.ActionPending, models.ActionEdited}).Order("created_at desc").Limit(5).Find(&actions)
 	
-	db.Instance.Order("created_at desc").Limit(10).Find(&events)
+	db.Instance.Where("owner_id = ? OR owner_id IS NULL", currentUser(c).ID).
+		Order("created_at desc").Limit(10).Find(&events)
 
-	var totalRev float64
-	db.Instance.Model(&models.VentureCampaign{}).Select("COALESCE(sum(revenue_earned), 0)").Scan(&totalRev)
+	totalRev, err := services.VentureRevenue(c.Context(), services.DefaultCurrency())
+	if err != nil {
+		log.Printf("[OVERVIEW] venture revenue: %v", err)
+	}
 
 	cpuPercent, _ := cpu.Percent(time.Second, false)
 	cpuString := "0%"
@@ -82,7 +88,7 @@ func GetOverviewSnapshot(c fiber.Ctx) error {
 	beginningOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 
 	var calories int
-	db.Instance.Model(&models.DietRecord{}).
+	userDB(c).Model(&models.DietRecord{}).
 		Where("created_at > ?", beginningOfDay).
 		Select("COALESCE(sum(calories), 0)").
 		Scan(&calories)
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"gateway/models"
+	"gateway/services"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/shopspring/decimal"
+)
+
+func GetPaperPortfolio(c fiber.Ctx) error {
+	val, err := services.ValuePaperPortfolio(c.Context())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not value paper portfolio"})
+	}
+	return c.JSON(val)
+}
+
+func GetPaperTrades(c fiber.Ctx) error {
+	var trades []models.PaperTrade
+	userDB(c).Order("executed_at desc").Limit(fiber.Query[int](c, "limit", 100)).Find(&trades)
+	return c.JSON(trades)
+}
+
+// GetPaperHistory returns the portfolio's equity snapshots, oldest first.
+func GetPaperHistory(c fiber.Ctx) error {
+	var snapshots []models.PaperSnapshot
+	query := userDB(c).Order("taken_at asc")
+	if since := c.Query("since"); since != "" {
+		query = query.Where("taken_at >= ?", since)
+	}
+	query.Find(&snapshots)
+	return c.JSON(snapshots)
+}
+
+func GetPaperStats(c fiber.Ctx) error {
+	stats, err := services.ScorePaperSignals(c.Context())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not score signals"})
+	}
+	return c.JSON(stats)
+}
+
+// RunPaperTrading fills pending signals now instead of waiting for the
+// paper_trading job.
+func RunPaperTrading(c fiber.Ctx) error {
+	summary, err := services.RunPaperTrading(c.Context())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"status": summary})
+}
+
+func ResetPaperPortfolio(c fiber.Ctx) error {
+	var body struct {
+		StartingCash decimal.Decimal `json:"starting_cash"`
+		TradeSize    decimal.Decimal `json:"trade_size"`
+	}
+	c.Bind().JSON(&body)
+
+	portfolio, err := services.ResetPaperPortfolio(c.Context(), body.StartingCash, body.TradeSize)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not reset paper portfolio"})
+	}
+	return c.JSON(portfolio)
+}
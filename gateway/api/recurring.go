@@ -0,0 +1,165 @@
+package api
+
+import (
+	"gateway/models"
+	"gateway/services"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/shopspring/decimal"
+)
+
+type recurringBody struct {
+	Name         *string          `json:"name"`
+	Type         *string          `json:"type"`
+	Amount       *decimal.Decimal `json:"amount"`
+	Currency     *string          `json:"currency"`
+	Category     *string          `json:"category"`
+	Description  *string          `json:"description"`
+	Frequency    *string          `json:"frequency"`
+	DayOfMonth   *int             `json:"day_of_month"`
+	Weekday      *int             `json:"weekday"`
+	Cron         *string          `json:"cron"`
+	Subscription *bool            `json:"subscription"`
+	Active       *bool            `json:"active"`
+	StartsOn     *string          `json:"starts_on"` // "2006-01-02"
+	EndsOn       *string          `json:"ends_on"`   // "2006-01-02"; empty clears it
+}
+
+func parseDay(s string) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", s, time.Local)
+}
+
+func (b recurringBody) apply(r *models.RecurringTransaction) error {
+	if b.Name != nil {
+		r.Name = *b.Name
+	}
+	if b.Type != nil {
+		r.Type = *b.Type
+	}
+	if b.Amount != nil {
+		r.Amount = *b.Amount
+	}
+	if b.Currency != nil {
+		r.Currency = *b.Currency
+	}
+	if b.Category != nil {
+		r.Category = *b.Category
+	}
+	if b.Description != nil {
+		r.Description = *b.Description
+	}
+	if b.Frequency != nil {
+		r.Frequency = *b.Frequency
+	}
+	if b.DayOfMonth != nil {
+		r.DayOfMonth = *b.DayOfMonth
+	}
+	if b.Weekday != nil {
+		r.Weekday = *b.Weekday
+	}
+	if b.Cron != nil {
+		r.Cron = *b.Cron
+	}
+	if b.Subscription != nil {
+		r.Subscription = *b.Subscription
+	}
+	if b.Active != nil {
+		r.Active = *b.Active
+	}
+	if b.StartsOn != nil {
+		t, err := parseDay(*b.StartsOn)
+		if err != nil {
+			return err
+		}
+		r.StartsOn = t
+	}
+	if b.EndsOn != nil {
+		r.EndsOn = nil
+		if *b.EndsOn != "" {
+			t, err := parseDay(*b.EndsOn)
+			if err != nil {
+				return err
+			}
+			r.EndsOn = &t
+		}
+	}
+	return nil
+}
+
+func GetRecurring(c fiber.Ctx) error {
+	var templates []models.RecurringTransaction
+	userDB(c).Order("next_due_at asc").Find(&templates)
+	return c.JSON(templates)
+}
+
+func CreateRecurring(c fiber.Ctx) error {
+	var body recurringBody
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	r := models.RecurringTransaction{Type: "expense", Active: true}
+	if err := body.apply(&r); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dates must look like 2006-01-02"})
+	}
+	if err := services.ValidateRecurring(&r); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := userDB(c).Create(&r).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not create recurring transaction"})
+	}
+	return c.Status(201).JSON(r)
+}
+
+func UpdateRecurring(c fiber.Ctx) error {
+	var r models.RecurringTransaction
+	if err := userDB(c).First(&r, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Recurring transaction not found"})
+	}
+
+	var body recurringBody
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := body.apply(&r); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dates must look like 2006-01-02"})
+	}
+	if err := services.ValidateRecurring(&r); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := userDB(c).Save(&r).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not update recurring transaction"})
+	}
+	return c.JSON(r)
+}
+
+func DeleteRecurring(c fiber.Ctx) error {
+	result := userDB(c).Where("id = ?", c.Params("id")).Delete(&models.RecurringTransaction{})
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Recurring transaction not found"})
+	}
+	return c.JSON(fiber.Map{"status": "deleted"})
+}
+
+// GetUpcomingBills lists recurring expenses due in the next ?days (default 30).
+func GetUpcomingBills(c fiber.Ctx) error {
+	days := fiber.Query[int](c, "days", 30)
+	if days < 1 || days > 366 {
+		return c.Status(400).JSON(fiber.Map{"error": "days must be 1 to 366"})
+	}
+	bills, err := services.ListUpcomingBills(c.Context(), days)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not list upcoming bills"})
+	}
+	return c.JSON(bills)
+}
+
+// DetectSubscriptions suggests templates for expenses that already recur.
+func DetectSubscriptions(c fiber.Ctx) error {
+	candidates, err := services.DetectSubscriptions(c.Context())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not scan expenses"})
+	}
+	return c.JSON(candidates)
+}
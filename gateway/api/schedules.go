@@ -0,0 +1,138 @@
+package api
+
+import (
+	"gateway/models"
+	"gateway/services"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type scheduleBody struct {
+	Name     *string `json:"name"`
+	Cron     *string `json:"cron"`
+	Timezone *string `json:"timezone"`
+	Agent    *string `json:"agent"`
+	Prompt   *string `json:"prompt"`
+	Task     *string `json:"task"`
+	Enabled  *bool   `json:"enabled"`
+	CatchUp  *bool   `json:"catch_up"`
+}
+
+func (b scheduleBody) apply(job *models.ScheduledJob) {
+	if b.Name != nil {
+		job.Name = *b.Name
+	}
+	if b.Cron != nil {
+		job.Cron = *b.Cron
+	}
+	if b.Timezone != nil {
+		job.Timezone = *b.Timezone
+	}
+	if b.Agent != nil {
+		job.Agent = *b.Agent
+	}
+	if b.Prompt != nil {
+		job.Prompt = *b.Prompt
+	}
+	if b.Task != nil {
+		job.Task = *b.Task
+	}
+	if b.Enabled != nil {
+		job.Enabled = *b.Enabled
+	}
+	if b.CatchUp != nil {
+		job.CatchUp = *b.CatchUp
+	}
+}
+
+func GetSchedules(c fiber.Ctx) error {
+	var jobs []models.ScheduledJob
+	userDB(c).Order("name asc").Find(&jobs)
+	return c.JSON(jobs)
+}
+
+func GetSchedule(c fiber.Ctx) error {
+	var job models.ScheduledJob
+	if err := userDB(c).First(&job, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Schedule not found"})
+	}
+	return c.JSON(job)
+}
+
+func CreateSchedule(c fiber.Ctx) error {
+	var body scheduleBody
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	job := models.ScheduledJob{Enabled: true}
+	body.apply(&job)
+	if job.Name == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "name is required"})
+	}
+	if err := services.ValidateJob(&job); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if next, err := services.NextRun(&job, time.Now()); err == nil {
+		job.NextRunAt = &next
+	}
+	if err := userDB(c).Create(&job).Error; err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": "Could not create schedule"})
+	}
+	return c.Status(201).JSON(job)
+}
+
+func UpdateSchedule(c fiber.Ctx) error {
+	var job models.ScheduledJob
+	if err := userDB(c).First(&job, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Schedule not found"})
+	}
+
+	var body scheduleBody
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	body.apply(&job)
+	if err := services.ValidateJob(&job); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// Re-arm from now so a changed expression takes effect immediately.
+	if next, err := services.NextRun(&job, time.Now()); err == nil {
+		job.NextRunAt = &next
+	}
+	if err := userDB(c).Save(&job).Error; err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": "Could not update schedule"})
+	}
+	return c.JSON(job)
+}
+
+func DeleteSchedule(c fiber.Ctx) error {
+	result := userDB(c).Where("id = ?", c.Params("id")).Delete(&models.ScheduledJob{})
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Schedule not found"})
+	}
+	return c.JSON(fiber.Map{"status": "deleted"})
+}
+
+func GetScheduleRuns(c fiber.Ctx) error {
+	var runs []models.JobRun
+	userDB(c).Where("job_id = ?", c.Params("id")).
+		Order("started_at desc").
+		Limit(fiber.Query[int](c, "limit", 50)).
+		Find(&runs)
+	return c.JSON(runs)
+}
+
+// TriggerSchedule runs a job now, outside its cron cadence.
+func TriggerSchedule(c fiber.Ctx) error {
+	var job models.ScheduledJob
+	if err := userDB(c).First(&job, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Schedule not found"})
+	}
+
+	go services.RunJob(job, time.Now())
+	return c.Status(202).JSON(fiber.Map{"status": "triggered"})
+}
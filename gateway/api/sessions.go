@@ -1,7 +1,6 @@
 package api
 
 import (
-	"gateway/db"
 	"gateway/models"
 	"github.com/google/uuid"
 	"github.com/gofiber/fiber/v3"
@@ -9,7 +8,7 @@ import (
 
 func GetSessions(c fiber.Ctx) error {
 	var sessions []models.ChatSession
-	db.Instance.Order("updated_at desc").Find(&sessions)
+	userDB(c).Order("updated_at desc").Find(&sessions)
 	return c.JSON(sessions)
 }
 
@@ -17,9 +16,9 @@ func CreateSession(c fiber.Ctx) error {
 	session := models.ChatSession{
 		SessionID: uuid.New().String(),
 		Title:     "New Intelligence Session",
-		UserID:    "wired",
+		UserID:    currentUser(c).ID.String(),
 	}
-	db.Instance.Create(&session)
+	userDB(c).Create(&session)
 	return c.JSON(session)
 }
 
@@ -27,16 +26,16 @@ func GetSessionHistory(c fiber.Ctx) error {
 	sessionID := c.Params("session_id")
 	var history []models.ChatHistory
 	// Important: order by created_at ASC so chat reads top-to-bottom
-	db.Instance.Where("session_id = ?", sessionID).Order("created_at asc").Find(&history)
+	userDB(c).Where("session_id = ?", sessionID).Order("created_at asc").Find(&history)
 	return c.JSON(history)
 }
 
 func DeleteSession(c fiber.Ctx) error {
 	sessionID := c.Params("session_id")
 
-	db.Instance.Where("session_id = ?", sessionID).Delete(&models.ChatHistory{})
+	userDB(c).Where("session_id = ?", sessionID).Delete(&models.ChatHistory{})
 	
-	result := db.Instance.Where("session_id = ?", sessionID).Delete(&models.ChatSession{})
+	result := userDB(c).Where("session_id = ?", sessionID).Delete(&models.ChatSession{})
 	
 	if result.RowsAffected == 0 {
 		return c.Status(404).JSON(fiber.Map{"error": "Session not found"})
@@ -55,7 +54,7 @@ func UpdateSessionTitle(c fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
 	}
 
-	result := db.Instance.Model(&models.ChatSession{}).
+	result := userDB(c).Model(&models.ChatSession{}).
 		Where("session_id = ?", sessionID).
 		Update("title", body.Title)
 
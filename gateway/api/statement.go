@@ -0,0 +1,192 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"gateway/models"
+	"gateway/services"
+	"os"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ImportStatement stores a bank export and previews it. Send the file as
+// multipart "file", or the "filename" an earlier /upload returned, plus an
+// optional "mapping" JSON for CSVs (see services.CSVMapping) and the
+// statement's "currency" (OFX files carry their own).
+func ImportStatement(c fiber.Ctx) error {
+	imp := models.StatementImport{Mapping: c.FormValue("mapping"), Currency: c.FormValue("currency")}
+	if imp.Mapping != "" {
+		var m services.CSVMapping
+		if err := json.Unmarshal([]byte(imp.Mapping), &m); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "mapping must be a JSON object"})
+		}
+	}
+
+	if file, err := c.FormFile("file"); err == nil {
+		imp.Original = file.Filename
+		if imp.Format, err = services.StatementFormat(file.Filename); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		if imp.Filename, err = saveUpload(c, file); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Save failed"})
+		}
+	} else if name := c.FormValue("filename"); name != "" {
+		imp.Filename, imp.Original = name, name
+		if imp.Format, err = services.StatementFormat(name); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+	} else {
+		return c.Status(400).JSON(fiber.Map{"error": "No file"})
+	}
+
+	f, err := os.Open(uploadPath(imp.Filename))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Uploaded file not found"})
+	}
+	defer f.Close()
+
+	preview, err := services.PreviewStatement(c.Context(), &imp, f)
+	if err != nil {
+		return c.Status(422).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(201).JSON(preview)
+}
+
+func GetStatementImports(c fiber.Ctx) error {
+	var imports []models.StatementImport
+	userDB(c).Order("created_at desc").Limit(fiber.Query[int](c, "limit", 50)).Find(&imports)
+	return c.JSON(imports)
+}
+
+// loadStatementImport opens the import named in the path. When it returns
+// a nil import the error response has already been written.
+func loadStatementImport(c fiber.Ctx) (*models.StatementImport, *os.File, error) {
+	var imp models.StatementImport
+	if err := userDB(c).First(&imp, "id = ?", c.Params("id")).Error; err != nil {
+		return nil, nil, c.Status(404).JSON(fiber.Map{"error": "Import not found"})
+	}
+	f, err := os.Open(uploadPath(imp.Filename))
+	if err != nil {
+		return nil, nil, c.Status(410).JSON(fiber.Map{"error": "Uploaded file is gone"})
+	}
+	return &imp, f, nil
+}
+
+// PreviewStatementImport re-runs the preview, e.g. after adding rules.
+func PreviewStatementImport(c fiber.Ctx) error {
+	imp, f, err := loadStatementImport(c)
+	if imp == nil {
+		return err
+	}
+	defer f.Close()
+
+	preview, err := services.PreviewStatement(c.Context(), imp, f)
+	if err != nil {
+		return c.Status(422).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(preview)
+}
+
+// CommitStatementImport writes the import's new rows, minus any listed in
+// "exclude", with "categories" overriding by row index.
+func CommitStatementImport(c fiber.Ctx) error {
+	var body services.StatementCommit
+	if len(c.Body()) > 0 {
+		if err := c.Bind().JSON(&body); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+
+	imp, f, err := loadStatementImport(c)
+	if imp == nil {
+		return err
+	}
+	defer f.Close()
+
+	result, err := services.CommitStatement(c.Context(), imp, f, body)
+	switch {
+	case errors.Is(err, services.ErrImportCommitted):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(422).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(result)
+}
+
+type categoryRuleBody struct {
+	Pattern  *string `json:"pattern"`
+	IsRegex  *bool   `json:"is_regex"`
+	Category *string `json:"category"`
+	Type     *string `json:"type"`
+	Priority *int    `json:"priority"`
+}
+
+func (b categoryRuleBody) apply(r *models.CategoryRule) {
+	if b.Pattern != nil {
+		r.Pattern = *b.Pattern
+	}
+	if b.IsRegex != nil {
+		r.IsRegex = *b.IsRegex
+	}
+	if b.Category != nil {
+		r.Category = *b.Category
+	}
+	if b.Type != nil {
+		r.Type = *b.Type
+	}
+	if b.Priority != nil {
+		r.Priority = *b.Priority
+	}
+}
+
+func GetCategoryRules(c fiber.Ctx) error {
+	var rules []models.CategoryRule
+	userDB(c).Order("priority desc, id asc").Find(&rules)
+	return c.JSON(rules)
+}
+
+func CreateCategoryRule(c fiber.Ctx) error {
+	var body categoryRuleBody
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	var rule models.CategoryRule
+	body.apply(&rule)
+	if err := services.ValidateCategoryRule(&rule); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := userDB(c).Create(&rule).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not create rule"})
+	}
+	return c.Status(201).JSON(rule)
+}
+
+func UpdateCategoryRule(c fiber.Ctx) error {
+	var rule models.CategoryRule
+	if err := userDB(c).First(&rule, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Rule not found"})
+	}
+
+	var body categoryRuleBody
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	body.apply(&rule)
+	if err := services.ValidateCategoryRule(&rule); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := userDB(c).Save(&rule).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Could not update rule"})
+	}
+	return c.JSON(rule)
+}
+
+func DeleteCategoryRule(c fiber.Ctx) error {
+	result := userDB(c).Where("id = ?", c.Params("id")).Delete(&models.CategoryRule{})
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Rule not found"})
+	}
+	return c.JSON(fiber.Map{"status": "deleted"})
+}
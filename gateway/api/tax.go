@@ -0,0 +1,32 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"gateway/services"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// GetTaxReport returns realized gains for ?year= (default this year) using
+// ?method=FIFO|LIFO|HIFO. ?format=csv downloads a Form 8949 style CSV.
+func GetTaxReport(c fiber.Ctx) error {
+	year := fiber.Query[int](c, "year", time.Now().Year())
+	report, err := services.BuildTaxReport(c.Context(), year, c.Query("method"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if c.Query("format") != "csv" {
+		return c.JSON(report)
+	}
+
+	var buf bytes.Buffer
+	if err := services.WriteForm8949CSV(&buf, report); err != nil {
+		return err
+	}
+	c.Set("Content-Type", "text/csv")
+	c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="form8949-%d-%s.csv"`, year, report.Method))
+	return c.Send(buf.Bytes())
+}
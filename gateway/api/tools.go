@@ -0,0 +1,13 @@
+package api
+
+import (
+	"gateway/services"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// GetToolSchemas publishes the executor's tool definitions so the Brain can
+// build its function declarations from the same source.
+func GetToolSchemas(c fiber.Ctx) error {
+	return c.JSON(services.ToolSchemas())
+}
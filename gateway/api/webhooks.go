@@ -13,7 +13,7 @@ func ExternalSignalHandler(c fiber.Ctx) error {
 	c.Bind().JSON(&payload)
 
 	// Emit a system event so it shows in the UI immediately
-	services.EmitEvent("EXTERNAL", fmt.Sprintf("Signal received from %s", source), "INFO")
+	services.EmitEvent(c.Context(), "EXTERNAL", fmt.Sprintf("Signal received from %s", source), "INFO")
 
 	// Trigger the Brain automatically to analyze the signal
 	// e.g., "An external signal from n8n says: 'New Email from Boss'. What should I do?"
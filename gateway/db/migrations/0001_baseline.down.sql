@@ -0,0 +1,19 @@
+DROP TABLE IF EXISTS pending_actions;
+DROP TABLE IF EXISTS code_snippets;
+DROP TABLE IF EXISTS knowledge_nodes;
+DROP TABLE IF EXISTS security_audits;
+DROP TABLE IF EXISTS agent_configs;
+DROP TABLE IF EXISTS venture_campaigns;
+DROP TABLE IF EXISTS revenue_campaigns;
+DROP TABLE IF EXISTS system_events;
+DROP TABLE IF EXISTS research_reports;
+DROP TABLE IF EXISTS trading_signals;
+DROP TABLE IF EXISTS workout_records;
+DROP TABLE IF EXISTS diet_records;
+DROP TABLE IF EXISTS task_records;
+DROP TABLE IF EXISTS job_applications;
+DROP TABLE IF EXISTS social_posts;
+DROP TABLE IF EXISTS crypto_holdings;
+DROP TABLE IF EXISTS finance_records;
+DROP TABLE IF EXISTS chat_sessions;
+DROP TABLE IF EXISTS chat_histories;
@@ -0,0 +1,229 @@
+-- Schema as produced by the last AutoMigrate release. IF NOT EXISTS lets
+-- databases that were auto-migrated adopt versioned migrations in place.
+
+CREATE TABLE IF NOT EXISTS chat_histories (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id text,
+    session_id text,
+    role text,
+    text text,
+    file_path text,
+    audio_url text
+);
+CREATE INDEX IF NOT EXISTS idx_chat_histories_deleted_at ON chat_histories (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_chat_histories_user_id ON chat_histories (user_id);
+CREATE INDEX IF NOT EXISTS idx_chat_histories_session_id ON chat_histories (session_id);
+
+CREATE TABLE IF NOT EXISTS chat_sessions (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    session_id text,
+    title text,
+    user_id text
+);
+CREATE INDEX IF NOT EXISTS idx_chat_sessions_deleted_at ON chat_sessions (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chat_sessions_session_id ON chat_sessions (session_id);
+
+CREATE TABLE IF NOT EXISTS finance_records (
+    id uuid PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    amount decimal,
+    category text,
+    description text,
+    type text
+);
+
+CREATE TABLE IF NOT EXISTS crypto_holdings (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    asset text,
+    balance decimal,
+    cost_basis decimal,
+    usd_value decimal
+);
+CREATE INDEX IF NOT EXISTS idx_crypto_holdings_deleted_at ON crypto_holdings (deleted_at);
+
+CREATE TABLE IF NOT EXISTS social_posts (
+    id uuid PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    content text,
+    platform text,
+    status text
+);
+
+CREATE TABLE IF NOT EXISTS job_applications (
+    id uuid PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    company text,
+    role text,
+    status text,
+    link text,
+    salary_range text
+);
+
+CREATE TABLE IF NOT EXISTS task_records (
+    id uuid PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    title text,
+    status text,
+    due_date timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS diet_records (
+    id uuid PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    food_item text,
+    calories bigint,
+    protein bigint,
+    carbs bigint,
+    fats bigint
+);
+
+CREATE TABLE IF NOT EXISTS workout_records (
+    id uuid PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    exercise text,
+    sets bigint,
+    reps bigint,
+    weight bigint,
+    duration_mins bigint
+);
+
+CREATE TABLE IF NOT EXISTS trading_signals (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    asset text,
+    action text,
+    price decimal,
+    reasoning text,
+    confidence decimal,
+    status text
+);
+CREATE INDEX IF NOT EXISTS idx_trading_signals_deleted_at ON trading_signals (deleted_at);
+
+CREATE TABLE IF NOT EXISTS research_reports (
+    id uuid PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    query text,
+    findings text,
+    category text
+);
+
+CREATE TABLE IF NOT EXISTS system_events (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    source text,
+    message text,
+    level text
+);
+CREATE INDEX IF NOT EXISTS idx_system_events_deleted_at ON system_events (deleted_at);
+
+CREATE TABLE IF NOT EXISTS revenue_campaigns (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    name text,
+    status text,
+    platform text,
+    strategy text,
+    budget decimal,
+    total_earned decimal
+);
+CREATE INDEX IF NOT EXISTS idx_revenue_campaigns_deleted_at ON revenue_campaigns (deleted_at);
+
+CREATE TABLE IF NOT EXISTS venture_campaigns (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    name text,
+    status text,
+    category text,
+    strategy_summary text,
+    projected_roi text,
+    platform text,
+    revenue_earned decimal
+);
+CREATE INDEX IF NOT EXISTS idx_venture_campaigns_deleted_at ON venture_campaigns (deleted_at);
+
+CREATE TABLE IF NOT EXISTS agent_configs (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    slug text,
+    name text,
+    system_prompt text,
+    allowed_tools text,
+    temperature decimal
+);
+CREATE INDEX IF NOT EXISTS idx_agent_configs_deleted_at ON agent_configs (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_agent_configs_slug ON agent_configs (slug);
+
+CREATE TABLE IF NOT EXISTS security_audits (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    issue text,
+    severity text,
+    status text
+);
+CREATE INDEX IF NOT EXISTS idx_security_audits_deleted_at ON security_audits (deleted_at);
+
+CREATE TABLE IF NOT EXISTS knowledge_nodes (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    topic text,
+    content text,
+    tags text
+);
+CREATE INDEX IF NOT EXISTS idx_knowledge_nodes_deleted_at ON knowledge_nodes (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_knowledge_nodes_topic ON knowledge_nodes (topic);
+
+CREATE TABLE IF NOT EXISTS code_snippets (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    file_name text,
+    language text,
+    code text,
+    description text
+);
+CREATE INDEX IF NOT EXISTS idx_code_snippets_deleted_at ON code_snippets (deleted_at);
+
+CREATE TABLE IF NOT EXISTS pending_actions (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    type text,
+    title text,
+    content text,
+    status text,
+    priority text
+);
+CREATE INDEX IF NOT EXISTS idx_pending_actions_deleted_at ON pending_actions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_pending_actions_status ON pending_actions (status);
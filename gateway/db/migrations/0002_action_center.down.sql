@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_pending_actions_ref_id;
+DROP INDEX IF EXISTS idx_pending_actions_status;
+
+ALTER TABLE pending_actions DROP COLUMN IF EXISTS last_error;
+ALTER TABLE pending_actions DROP COLUMN IF EXISTS attempts;
+ALTER TABLE pending_actions DROP COLUMN IF EXISTS executed_at;
+ALTER TABLE pending_actions DROP COLUMN IF EXISTS decided_at;
+ALTER TABLE pending_actions DROP COLUMN IF EXISTS decided_by;
+ALTER TABLE pending_actions DROP COLUMN IF EXISTS edited_at;
+ALTER TABLE pending_actions DROP COLUMN IF EXISTS edited_by;
+ALTER TABLE pending_actions DROP COLUMN IF EXISTS ref_id;
@@ -0,0 +1,11 @@
+ALTER TABLE pending_actions ADD COLUMN IF NOT EXISTS ref_id text;
+ALTER TABLE pending_actions ADD COLUMN IF NOT EXISTS edited_by text;
+ALTER TABLE pending_actions ADD COLUMN IF NOT EXISTS edited_at timestamptz;
+ALTER TABLE pending_actions ADD COLUMN IF NOT EXISTS decided_by text;
+ALTER TABLE pending_actions ADD COLUMN IF NOT EXISTS decided_at timestamptz;
+ALTER TABLE pending_actions ADD COLUMN IF NOT EXISTS executed_at timestamptz;
+ALTER TABLE pending_actions ADD COLUMN IF NOT EXISTS attempts bigint NOT NULL DEFAULT 0;
+ALTER TABLE pending_actions ADD COLUMN IF NOT EXISTS last_error text;
+
+CREATE INDEX IF NOT EXISTS idx_pending_actions_status ON pending_actions (status);
+CREATE INDEX IF NOT EXISTS idx_pending_actions_ref_id ON pending_actions (ref_id);
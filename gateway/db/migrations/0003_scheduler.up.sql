@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    name text,
+    cron text,
+    timezone text,
+    agent text,
+    prompt text,
+    task text,
+    enabled boolean,
+    catch_up boolean,
+    last_run_at timestamptz,
+    next_run_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_scheduled_jobs_deleted_at ON scheduled_jobs (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scheduled_jobs_name ON scheduled_jobs (name);
+CREATE INDEX IF NOT EXISTS idx_scheduled_jobs_next_run_at ON scheduled_jobs (next_run_at);
+
+CREATE TABLE IF NOT EXISTS job_runs (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    job_id bigint,
+    scheduled_for timestamptz,
+    started_at timestamptz,
+    finished_at timestamptz,
+    status text,
+    output text,
+    error text
+);
+CREATE INDEX IF NOT EXISTS idx_job_runs_deleted_at ON job_runs (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_job_runs_job_id ON job_runs (job_id);
@@ -0,0 +1,24 @@
+ALTER TABLE job_runs DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE scheduled_jobs DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE pending_actions DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE code_snippets DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE knowledge_nodes DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE security_audits DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE venture_campaigns DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE revenue_campaigns DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE system_events DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE research_reports DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE trading_signals DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE workout_records DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE diet_records DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE task_records DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE job_applications DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE social_posts DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE crypto_holdings DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE finance_records DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE chat_sessions DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE chat_histories DROP COLUMN IF EXISTS owner_id;
+
+DROP TABLE IF EXISTS user_sessions;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS users;
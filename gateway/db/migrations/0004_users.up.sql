@@ -0,0 +1,76 @@
+CREATE TABLE IF NOT EXISTS users (
+    id uuid PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    email text,
+    name text,
+    password_hash text,
+    role text
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id uuid PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    user_id uuid,
+    name text,
+    prefix text,
+    hash text,
+    last_used_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_hash ON api_keys (hash);
+
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id uuid PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    user_id uuid,
+    hash text,
+    expires_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user_id ON user_sessions (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_sessions_hash ON user_sessions (hash);
+
+-- Rows created before authentication stay NULL until the first user claims them.
+ALTER TABLE chat_histories ADD COLUMN IF NOT EXISTS owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_chat_histories_owner_id ON chat_histories (owner_id);
+ALTER TABLE chat_sessions ADD COLUMN IF NOT EXISTS owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_chat_sessions_owner_id ON chat_sessions (owner_id);
+ALTER TABLE finance_records ADD COLUMN IF NOT EXISTS owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_finance_records_owner_id ON finance_records (owner_id);
+ALTER TABLE crypto_holdings ADD COLUMN IF NOT EXISTS owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_crypto_holdings_owner_id ON crypto_holdings (owner_id);
+ALTER TABLE social_posts ADD COLUMN IF NOT EXISTS owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_social_posts_owner_id ON social_posts (owner_id);
+ALTER TABLE job_applications ADD COLUMN IF NOT EXISTS owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_job_applications_owner_id ON job_applications (owner_id);
+ALTER TABLE task_records ADD COLUMN IF NOT EXISTS owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_task_records_owner_id ON task_records (owner_id);
+ALTER TABLE diet_records ADD COLUMN IF NOT EXISTS owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_diet_records_owner_id ON diet_records (owner_id);
+ALTER TABLE workout_records ADD COLUMN IF NOT EXISTS owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_workout_records_owner_id ON workout_records (owner_id);
+ALTER TABLE trading_signals ADD COLUMN IF NOT EXISTS owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_trading_signals_owner_id ON trading_signals (owner_id);
+ALTER TABLE research_reports ADD COLUMN IF NOT EXISTS owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_research_reports_owner_id ON research_reports (owner_id);
+ALTER TABLE system_events ADD COLUMN IF NOT EXISTS owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_system_events_owner_id ON system_events (owner_id);
+ALTER TABLE revenue_campaigns ADD COLUMN IF NOT EXISTS owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_revenue_campaigns_owner_id ON revenue_campaigns (owner_id);
+ALTER TABLE venture_campaigns ADD COLUMN IF NOT EXISTS owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_venture_campaigns_owner_id ON venture_campaigns (owner_id);
+ALTER TABLE security_audits ADD COLUMN IF NOT EXISTS owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_security_audits_owner_id ON security_audits (owner_id);
+ALTER TABLE knowledge_nodes ADD COLUMN IF NOT EXISTS owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_knowledge_nodes_owner_id ON knowledge_nodes (owner_id);
+ALTER TABLE code_snippets ADD COLUMN IF NOT EXISTS owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_code_snippets_owner_id ON code_snippets (owner_id);
+ALTER TABLE pending_actions ADD COLUMN IF NOT EXISTS owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_pending_actions_owner_id ON pending_actions (owner_id);
+ALTER TABLE scheduled_jobs ADD COLUMN IF NOT EXISTS owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_scheduled_jobs_owner_id ON scheduled_jobs (owner_id);
+ALTER TABLE job_runs ADD COLUMN IF NOT EXISTS owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_job_runs_owner_id ON job_runs (owner_id);
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_trading_signals_order_id;
+
+ALTER TABLE trading_signals DROP COLUMN IF EXISTS ordered_at;
+ALTER TABLE trading_signals DROP COLUMN IF EXISTS fee;
+ALTER TABLE trading_signals DROP COLUMN IF EXISTS avg_fill_price;
+ALTER TABLE trading_signals DROP COLUMN IF EXISTS filled_volume;
+ALTER TABLE trading_signals DROP COLUMN IF EXISTS order_status;
+ALTER TABLE trading_signals DROP COLUMN IF EXISTS order_id;
+ALTER TABLE trading_signals DROP COLUMN IF EXISTS notional;
+ALTER TABLE trading_signals DROP COLUMN IF EXISTS limit_price;
+ALTER TABLE trading_signals DROP COLUMN IF EXISTS volume;
+ALTER TABLE trading_signals DROP COLUMN IF EXISTS order_type;
@@ -0,0 +1,12 @@
+ALTER TABLE trading_signals ADD COLUMN IF NOT EXISTS order_type text;
+ALTER TABLE trading_signals ADD COLUMN IF NOT EXISTS volume decimal;
+ALTER TABLE trading_signals ADD COLUMN IF NOT EXISTS limit_price decimal;
+ALTER TABLE trading_signals ADD COLUMN IF NOT EXISTS notional decimal;
+ALTER TABLE trading_signals ADD COLUMN IF NOT EXISTS order_id text;
+ALTER TABLE trading_signals ADD COLUMN IF NOT EXISTS order_status text;
+ALTER TABLE trading_signals ADD COLUMN IF NOT EXISTS filled_volume decimal;
+ALTER TABLE trading_signals ADD COLUMN IF NOT EXISTS avg_fill_price decimal;
+ALTER TABLE trading_signals ADD COLUMN IF NOT EXISTS fee decimal;
+ALTER TABLE trading_signals ADD COLUMN IF NOT EXISTS ordered_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_trading_signals_order_id ON trading_signals (order_id);
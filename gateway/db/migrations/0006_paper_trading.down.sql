@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS paper_snapshots;
+DROP TABLE IF EXISTS paper_trades;
+DROP TABLE IF EXISTS paper_positions;
+DROP TABLE IF EXISTS paper_portfolios;
@@ -0,0 +1,70 @@
+CREATE TABLE IF NOT EXISTS paper_portfolios (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    owner_id uuid,
+    starting_cash decimal,
+    cash decimal,
+    trade_size decimal,
+    realized_pnl decimal
+);
+CREATE INDEX IF NOT EXISTS idx_paper_portfolios_deleted_at ON paper_portfolios (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_paper_portfolios_owner_id ON paper_portfolios (owner_id);
+
+CREATE TABLE IF NOT EXISTS paper_positions (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    owner_id uuid,
+    portfolio_id bigint,
+    asset text,
+    quantity decimal,
+    avg_cost decimal
+);
+CREATE INDEX IF NOT EXISTS idx_paper_positions_deleted_at ON paper_positions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_paper_positions_owner_id ON paper_positions (owner_id);
+CREATE INDEX IF NOT EXISTS idx_paper_positions_portfolio_id ON paper_positions (portfolio_id);
+
+CREATE TABLE IF NOT EXISTS paper_trades (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    owner_id uuid,
+    portfolio_id bigint,
+    signal_id bigint,
+    asset text,
+    side text,
+    price decimal,
+    price_source text,
+    quantity decimal,
+    signal_qty decimal,
+    realized_pnl decimal,
+    confidence decimal,
+    executed_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_paper_trades_deleted_at ON paper_trades (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_paper_trades_owner_id ON paper_trades (owner_id);
+CREATE INDEX IF NOT EXISTS idx_paper_trades_portfolio_id ON paper_trades (portfolio_id);
+CREATE INDEX IF NOT EXISTS idx_paper_trades_signal_id ON paper_trades (signal_id);
+
+CREATE TABLE IF NOT EXISTS paper_snapshots (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    owner_id uuid,
+    portfolio_id bigint,
+    taken_at timestamptz,
+    cash decimal,
+    market_value decimal,
+    equity decimal,
+    realized_pnl decimal,
+    unrealized_pnl decimal
+);
+CREATE INDEX IF NOT EXISTS idx_paper_snapshots_deleted_at ON paper_snapshots (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_paper_snapshots_owner_id ON paper_snapshots (owner_id);
+CREATE INDEX IF NOT EXISTS idx_paper_snapshots_portfolio_id ON paper_snapshots (portfolio_id);
+CREATE INDEX IF NOT EXISTS idx_paper_snapshots_taken_at ON paper_snapshots (taken_at);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS candles (
+    id bigserial PRIMARY KEY,
+    pair text NOT NULL,
+    interval_min bigint NOT NULL,
+    time timestamptz NOT NULL,
+    open decimal,
+    high decimal,
+    low decimal,
+    close decimal,
+    vwap decimal,
+    volume decimal,
+    trades bigint
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_candles_key ON candles (pair, interval_min, time);
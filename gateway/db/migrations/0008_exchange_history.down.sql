@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS exchange_trades;
+DROP TABLE IF EXISTS ledger_entries;
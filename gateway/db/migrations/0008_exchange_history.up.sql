@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    owner_id uuid,
+    ledger_id text NOT NULL,
+    ref_id text,
+    time timestamptz,
+    type text,
+    subtype text,
+    asset text,
+    raw_asset text,
+    amount decimal,
+    fee decimal,
+    balance decimal,
+    finance_record_id uuid
+);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_deleted_at ON ledger_entries (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_owner_id ON ledger_entries (owner_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ledger_entries_ledger_id ON ledger_entries (owner_id, ledger_id) NULLS NOT DISTINCT;
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_ref_id ON ledger_entries (ref_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_time ON ledger_entries (time);
+
+CREATE TABLE IF NOT EXISTS exchange_trades (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    owner_id uuid,
+    tx_id text NOT NULL,
+    order_tx_id text,
+    pair text,
+    time timestamptz,
+    type text,
+    order_type text,
+    price decimal,
+    cost decimal,
+    fee decimal,
+    volume decimal
+);
+CREATE INDEX IF NOT EXISTS idx_exchange_trades_deleted_at ON exchange_trades (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_exchange_trades_owner_id ON exchange_trades (owner_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_exchange_trades_tx_id ON exchange_trades (owner_id, tx_id) NULLS NOT DISTINCT;
+CREATE INDEX IF NOT EXISTS idx_exchange_trades_time ON exchange_trades (time);
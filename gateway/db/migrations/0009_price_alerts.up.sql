@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS price_alerts (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    owner_id uuid,
+    name text,
+    kind text,
+    asset text,
+    threshold decimal,
+    window_mins bigint,
+    cooldown_mins bigint,
+    enabled boolean,
+    triggered boolean,
+    last_value decimal,
+    last_checked timestamptz,
+    last_fired_at timestamptz,
+    fire_count bigint
+);
+CREATE INDEX IF NOT EXISTS idx_price_alerts_deleted_at ON price_alerts (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_price_alerts_owner_id ON price_alerts (owner_id);
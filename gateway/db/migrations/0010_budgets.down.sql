@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_finance_records_type_created_at;
+DROP TABLE IF EXISTS budgets;
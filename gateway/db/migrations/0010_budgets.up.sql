@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS budgets (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    owner_id uuid,
+    category text NOT NULL,
+    monthly_limit decimal,
+    rollover text,
+    max_rollover decimal,
+    starts_on timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_budgets_deleted_at ON budgets (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_budgets_owner_id ON budgets (owner_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_budgets_category ON budgets (owner_id, lower(category)) NULLS NOT DISTINCT WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_finance_records_type_created_at ON finance_records (type, created_at);
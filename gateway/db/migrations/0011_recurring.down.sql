@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_finance_records_recurring_id;
+ALTER TABLE finance_records DROP COLUMN IF EXISTS recurring_id;
+
+DROP TABLE IF EXISTS recurring_transactions;
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS recurring_transactions (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    owner_id uuid,
+    name text,
+    type text,
+    amount decimal,
+    category text,
+    description text,
+    frequency text,
+    day_of_month bigint,
+    weekday bigint,
+    cron text,
+    subscription boolean,
+    active boolean,
+    starts_on timestamptz,
+    ends_on timestamptz,
+    next_due_at timestamptz,
+    last_posted_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_recurring_transactions_deleted_at ON recurring_transactions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_recurring_transactions_owner_id ON recurring_transactions (owner_id);
+CREATE INDEX IF NOT EXISTS idx_recurring_transactions_next_due_at ON recurring_transactions (next_due_at);
+
+ALTER TABLE finance_records ADD COLUMN IF NOT EXISTS recurring_id bigint;
+CREATE INDEX IF NOT EXISTS idx_finance_records_recurring_id ON finance_records (recurring_id);
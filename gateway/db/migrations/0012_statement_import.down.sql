@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_finance_records_import_id;
+ALTER TABLE finance_records DROP COLUMN IF EXISTS import_id;
+
+DROP TABLE IF EXISTS category_rules;
+DROP TABLE IF EXISTS statement_imports;
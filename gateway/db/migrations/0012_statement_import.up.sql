@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS statement_imports (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    owner_id uuid,
+    filename text,
+    original text,
+    format text,
+    mapping text,
+    status text,
+    rows bigint,
+    duplicates bigint,
+    imported bigint,
+    committed_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_statement_imports_deleted_at ON statement_imports (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_statement_imports_owner_id ON statement_imports (owner_id);
+
+CREATE TABLE IF NOT EXISTS category_rules (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    owner_id uuid,
+    pattern text,
+    is_regex boolean,
+    category text,
+    type text,
+    priority bigint
+);
+CREATE INDEX IF NOT EXISTS idx_category_rules_deleted_at ON category_rules (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_category_rules_owner_id ON category_rules (owner_id);
+
+ALTER TABLE finance_records ADD COLUMN IF NOT EXISTS import_id bigint;
+CREATE INDEX IF NOT EXISTS idx_finance_records_import_id ON finance_records (import_id);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS fx_rates;
+
+ALTER TABLE statement_imports DROP COLUMN IF EXISTS currency;
+ALTER TABLE recurring_transactions DROP COLUMN IF EXISTS currency;
+ALTER TABLE venture_campaigns DROP COLUMN IF EXISTS currency;
+ALTER TABLE finance_records DROP COLUMN IF EXISTS currency;
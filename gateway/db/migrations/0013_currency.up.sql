@@ -0,0 +1,22 @@
+-- Existing rows were recorded in DEFAULT_CURRENCY, which `gateway migrate
+-- up` passes in as gateway.default_currency.
+ALTER TABLE finance_records ADD COLUMN IF NOT EXISTS currency text NOT NULL DEFAULT 'USD';
+ALTER TABLE venture_campaigns ADD COLUMN IF NOT EXISTS currency text NOT NULL DEFAULT 'USD';
+ALTER TABLE recurring_transactions ADD COLUMN IF NOT EXISTS currency text NOT NULL DEFAULT 'USD';
+ALTER TABLE statement_imports ADD COLUMN IF NOT EXISTS currency text NOT NULL DEFAULT 'USD';
+
+UPDATE finance_records SET currency = COALESCE(NULLIF(current_setting('gateway.default_currency', true), ''), 'USD');
+UPDATE venture_campaigns SET currency = COALESCE(NULLIF(current_setting('gateway.default_currency', true), ''), 'USD');
+UPDATE recurring_transactions SET currency = COALESCE(NULLIF(current_setting('gateway.default_currency', true), ''), 'USD');
+UPDATE statement_imports SET currency = COALESCE(NULLIF(current_setting('gateway.default_currency', true), ''), 'USD');
+
+CREATE TABLE IF NOT EXISTS fx_rates (
+    id bigserial PRIMARY KEY,
+    base text NOT NULL,
+    quote text NOT NULL,
+    as_of date NOT NULL,
+    rate decimal NOT NULL,
+    source text,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_fx_rates_key ON fx_rates (base, quote, as_of);
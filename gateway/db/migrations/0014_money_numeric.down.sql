@@ -0,0 +1,18 @@
+ALTER TABLE fx_rates ALTER COLUMN rate TYPE decimal;
+ALTER TABLE trading_signals ALTER COLUMN fee TYPE decimal;
+ALTER TABLE trading_signals ALTER COLUMN avg_fill_price TYPE decimal;
+ALTER TABLE trading_signals ALTER COLUMN filled_volume TYPE decimal;
+ALTER TABLE trading_signals ALTER COLUMN limit_price TYPE decimal;
+ALTER TABLE trading_signals ALTER COLUMN volume TYPE decimal;
+ALTER TABLE trading_signals ALTER COLUMN price TYPE decimal;
+ALTER TABLE crypto_holdings ALTER COLUMN balance TYPE decimal;
+ALTER TABLE trading_signals ALTER COLUMN notional TYPE decimal;
+ALTER TABLE crypto_holdings ALTER COLUMN usd_value TYPE decimal;
+ALTER TABLE crypto_holdings ALTER COLUMN cost_basis TYPE decimal;
+ALTER TABLE recurring_transactions ALTER COLUMN amount TYPE decimal;
+ALTER TABLE budgets ALTER COLUMN max_rollover TYPE decimal;
+ALTER TABLE budgets ALTER COLUMN monthly_limit TYPE decimal;
+ALTER TABLE revenue_campaigns ALTER COLUMN total_earned TYPE decimal;
+ALTER TABLE revenue_campaigns ALTER COLUMN budget TYPE decimal;
+ALTER TABLE venture_campaigns ALTER COLUMN revenue_earned TYPE decimal;
+ALTER TABLE finance_records ALTER COLUMN amount TYPE decimal;
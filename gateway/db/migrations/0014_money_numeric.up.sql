@@ -0,0 +1,21 @@
+-- Fiat amounts keep four places so sums round to the cent once; crypto
+-- quantities and prices keep ten, Kraken's finest.
+ALTER TABLE finance_records ALTER COLUMN amount TYPE numeric(20,4) USING round(amount, 4);
+ALTER TABLE venture_campaigns ALTER COLUMN revenue_earned TYPE numeric(20,4) USING round(revenue_earned, 4);
+ALTER TABLE revenue_campaigns ALTER COLUMN budget TYPE numeric(20,4) USING round(budget, 4);
+ALTER TABLE revenue_campaigns ALTER COLUMN total_earned TYPE numeric(20,4) USING round(total_earned, 4);
+ALTER TABLE budgets ALTER COLUMN monthly_limit TYPE numeric(20,4) USING round(monthly_limit, 4);
+ALTER TABLE budgets ALTER COLUMN max_rollover TYPE numeric(20,4) USING round(max_rollover, 4);
+ALTER TABLE recurring_transactions ALTER COLUMN amount TYPE numeric(20,4) USING round(amount, 4);
+ALTER TABLE crypto_holdings ALTER COLUMN cost_basis TYPE numeric(20,4) USING round(cost_basis, 4);
+ALTER TABLE crypto_holdings ALTER COLUMN usd_value TYPE numeric(20,4) USING round(usd_value, 4);
+ALTER TABLE trading_signals ALTER COLUMN notional TYPE numeric(20,4) USING round(notional, 4);
+
+ALTER TABLE crypto_holdings ALTER COLUMN balance TYPE numeric(30,10) USING round(balance, 10);
+ALTER TABLE trading_signals ALTER COLUMN price TYPE numeric(30,10) USING round(price, 10);
+ALTER TABLE trading_signals ALTER COLUMN volume TYPE numeric(30,10) USING round(volume, 10);
+ALTER TABLE trading_signals ALTER COLUMN limit_price TYPE numeric(30,10) USING round(limit_price, 10);
+ALTER TABLE trading_signals ALTER COLUMN filled_volume TYPE numeric(30,10) USING round(filled_volume, 10);
+ALTER TABLE trading_signals ALTER COLUMN avg_fill_price TYPE numeric(30,10) USING round(avg_fill_price, 10);
+ALTER TABLE trading_signals ALTER COLUMN fee TYPE numeric(30,10) USING round(fee, 10);
+ALTER TABLE fx_rates ALTER COLUMN rate TYPE numeric(30,10) USING round(rate, 10);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS net_worth_snapshots;
+DROP TABLE IF EXISTS net_worth_accounts;
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS net_worth_accounts (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    owner_id uuid,
+    name text,
+    kind text,
+    balance numeric(20,4),
+    currency text NOT NULL DEFAULT 'USD',
+    notes text
+);
+CREATE INDEX IF NOT EXISTS idx_net_worth_accounts_deleted_at ON net_worth_accounts (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_net_worth_accounts_owner_id ON net_worth_accounts (owner_id);
+
+CREATE TABLE IF NOT EXISTS net_worth_snapshots (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    owner_id uuid,
+    day date,
+    currency text,
+    crypto numeric(20,4),
+    cash numeric(20,4),
+    assets numeric(20,4),
+    liabilities numeric(20,4),
+    total_assets numeric(20,4),
+    net_worth numeric(20,4)
+);
+CREATE INDEX IF NOT EXISTS idx_net_worth_snapshots_deleted_at ON net_worth_snapshots (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_net_worth_snapshots_owner_id ON net_worth_snapshots (owner_id);
+CREATE INDEX IF NOT EXISTS idx_net_worth_snapshots_day ON net_worth_snapshots (day);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_scheduled_jobs_owner_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scheduled_jobs_name ON scheduled_jobs (name);
@@ -0,0 +1,5 @@
+-- Job names are unique per owner, and a deleted job frees its name.
+DROP INDEX IF EXISTS idx_scheduled_jobs_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scheduled_jobs_owner_name ON scheduled_jobs
+    (COALESCE(owner_id, '00000000-0000-0000-0000-000000000000'::uuid), name)
+    WHERE deleted_at IS NULL;
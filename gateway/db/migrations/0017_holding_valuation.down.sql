@@ -0,0 +1,2 @@
+ALTER TABLE crypto_holdings DROP COLUMN IF EXISTS price_stale;
+ALTER TABLE crypto_holdings DROP COLUMN IF EXISTS priced_at;
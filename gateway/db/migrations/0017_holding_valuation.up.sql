@@ -0,0 +1,2 @@
+ALTER TABLE crypto_holdings ADD COLUMN IF NOT EXISTS priced_at timestamptz;
+ALTER TABLE crypto_holdings ADD COLUMN IF NOT EXISTS price_stale boolean NOT NULL DEFAULT false;
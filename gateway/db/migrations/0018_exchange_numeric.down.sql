@@ -0,0 +1,30 @@
+ALTER TABLE paper_snapshots ALTER COLUMN unrealized_pnl TYPE decimal;
+ALTER TABLE paper_snapshots ALTER COLUMN realized_pnl TYPE decimal;
+ALTER TABLE paper_snapshots ALTER COLUMN equity TYPE decimal;
+ALTER TABLE paper_snapshots ALTER COLUMN market_value TYPE decimal;
+ALTER TABLE paper_snapshots ALTER COLUMN cash TYPE decimal;
+ALTER TABLE paper_trades ALTER COLUMN realized_pnl TYPE decimal;
+ALTER TABLE paper_trades ALTER COLUMN signal_qty TYPE decimal;
+ALTER TABLE paper_trades ALTER COLUMN quantity TYPE decimal;
+ALTER TABLE paper_trades ALTER COLUMN price TYPE decimal;
+ALTER TABLE paper_positions ALTER COLUMN avg_cost TYPE decimal;
+ALTER TABLE paper_positions ALTER COLUMN quantity TYPE decimal;
+ALTER TABLE paper_portfolios ALTER COLUMN realized_pnl TYPE decimal;
+ALTER TABLE paper_portfolios ALTER COLUMN trade_size TYPE decimal;
+ALTER TABLE paper_portfolios ALTER COLUMN cash TYPE decimal;
+ALTER TABLE paper_portfolios ALTER COLUMN starting_cash TYPE decimal;
+ALTER TABLE price_alerts ALTER COLUMN last_value TYPE decimal;
+ALTER TABLE price_alerts ALTER COLUMN threshold TYPE decimal;
+ALTER TABLE candles ALTER COLUMN volume TYPE decimal;
+ALTER TABLE candles ALTER COLUMN vwap TYPE decimal;
+ALTER TABLE candles ALTER COLUMN close TYPE decimal;
+ALTER TABLE candles ALTER COLUMN low TYPE decimal;
+ALTER TABLE candles ALTER COLUMN high TYPE decimal;
+ALTER TABLE candles ALTER COLUMN open TYPE decimal;
+ALTER TABLE exchange_trades ALTER COLUMN volume TYPE decimal;
+ALTER TABLE exchange_trades ALTER COLUMN fee TYPE decimal;
+ALTER TABLE exchange_trades ALTER COLUMN cost TYPE decimal;
+ALTER TABLE exchange_trades ALTER COLUMN price TYPE decimal;
+ALTER TABLE ledger_entries ALTER COLUMN balance TYPE decimal;
+ALTER TABLE ledger_entries ALTER COLUMN fee TYPE decimal;
+ALTER TABLE ledger_entries ALTER COLUMN amount TYPE decimal;
@@ -0,0 +1,36 @@
+-- Exchange history, market data, alerts and paper trading move to fixed
+-- scales as 0014 did for finance: USD totals keep four places, crypto
+-- quantities and prices ten.
+ALTER TABLE ledger_entries ALTER COLUMN amount TYPE numeric(30,10) USING round(amount, 10);
+ALTER TABLE ledger_entries ALTER COLUMN fee TYPE numeric(30,10) USING round(fee, 10);
+ALTER TABLE ledger_entries ALTER COLUMN balance TYPE numeric(30,10) USING round(balance, 10);
+ALTER TABLE exchange_trades ALTER COLUMN price TYPE numeric(30,10) USING round(price, 10);
+ALTER TABLE exchange_trades ALTER COLUMN cost TYPE numeric(30,10) USING round(cost, 10);
+ALTER TABLE exchange_trades ALTER COLUMN fee TYPE numeric(30,10) USING round(fee, 10);
+ALTER TABLE exchange_trades ALTER COLUMN volume TYPE numeric(30,10) USING round(volume, 10);
+
+ALTER TABLE candles ALTER COLUMN open TYPE numeric(30,10) USING round(open, 10);
+ALTER TABLE candles ALTER COLUMN high TYPE numeric(30,10) USING round(high, 10);
+ALTER TABLE candles ALTER COLUMN low TYPE numeric(30,10) USING round(low, 10);
+ALTER TABLE candles ALTER COLUMN close TYPE numeric(30,10) USING round(close, 10);
+ALTER TABLE candles ALTER COLUMN vwap TYPE numeric(30,10) USING round(vwap, 10);
+ALTER TABLE candles ALTER COLUMN volume TYPE numeric(30,10) USING round(volume, 10);
+
+ALTER TABLE price_alerts ALTER COLUMN threshold TYPE numeric(30,10) USING round(threshold, 10);
+ALTER TABLE price_alerts ALTER COLUMN last_value TYPE numeric(30,10) USING round(last_value, 10);
+
+ALTER TABLE paper_portfolios ALTER COLUMN starting_cash TYPE numeric(20,4) USING round(starting_cash, 4);
+ALTER TABLE paper_portfolios ALTER COLUMN cash TYPE numeric(20,4) USING round(cash, 4);
+ALTER TABLE paper_portfolios ALTER COLUMN trade_size TYPE numeric(20,4) USING round(trade_size, 4);
+ALTER TABLE paper_portfolios ALTER COLUMN realized_pnl TYPE numeric(20,4) USING round(realized_pnl, 4);
+ALTER TABLE paper_positions ALTER COLUMN quantity TYPE numeric(30,10) USING round(quantity, 10);
+ALTER TABLE paper_positions ALTER COLUMN avg_cost TYPE numeric(30,10) USING round(avg_cost, 10);
+ALTER TABLE paper_trades ALTER COLUMN price TYPE numeric(30,10) USING round(price, 10);
+ALTER TABLE paper_trades ALTER COLUMN quantity TYPE numeric(30,10) USING round(quantity, 10);
+ALTER TABLE paper_trades ALTER COLUMN signal_qty TYPE numeric(30,10) USING round(signal_qty, 10);
+ALTER TABLE paper_trades ALTER COLUMN realized_pnl TYPE numeric(20,4) USING round(realized_pnl, 4);
+ALTER TABLE paper_snapshots ALTER COLUMN cash TYPE numeric(20,4) USING round(cash, 4);
+ALTER TABLE paper_snapshots ALTER COLUMN market_value TYPE numeric(20,4) USING round(market_value, 4);
+ALTER TABLE paper_snapshots ALTER COLUMN equity TYPE numeric(20,4) USING round(equity, 4);
+ALTER TABLE paper_snapshots ALTER COLUMN realized_pnl TYPE numeric(20,4) USING round(realized_pnl, 4);
+ALTER TABLE paper_snapshots ALTER COLUMN unrealized_pnl TYPE numeric(20,4) USING round(unrealized_pnl, 4);
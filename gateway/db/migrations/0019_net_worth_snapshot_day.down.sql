@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_net_worth_snapshots_owner_day;
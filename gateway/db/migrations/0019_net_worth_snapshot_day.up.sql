@@ -0,0 +1,4 @@
+-- One snapshot per owner and day. Keep the latest of any duplicates.
+DELETE FROM net_worth_snapshots a USING net_worth_snapshots b
+    WHERE a.owner_id IS NOT DISTINCT FROM b.owner_id AND a.day = b.day AND a.id < b.id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_net_worth_snapshots_owner_day ON net_worth_snapshots (owner_id, day) NULLS NOT DISTINCT;
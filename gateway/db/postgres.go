@@ -6,38 +6,96 @@ import (
 	"log"
 	"os"
 
+	"github.com/google/uuid"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 var Instance *gorm.DB
 
-func Connect() error {
-	dsn := buildDSN()
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// ownedModels are the tables carrying an owner_id column. Their schema
+// lives in migrations/; keep the two in step.
+var ownedModels = []interface{}{
+	&models.ChatHistory{}, &models.ChatSession{},
+	&models.FinanceRecord{}, &models.CryptoHoldings{},
+	&models.SocialPost{}, &models.JobApplication{},
+	&models.TaskRecord{}, &models.DietRecord{},
+	&models.WorkoutRecord{}, &models.TradingSignal{},
+	&models.ResearchReports{}, &models.SystemEvent{},
+	&models.RevenueCampaign{}, &models.VentureCampaign{},
+	&models.SecurityAudit{}, &models.KnowledgeNode{},
+	&models.CodeSnippet{}, &models.PendingAction{},
+	&models.ScheduledJob{}, &models.JobRun{},
+	&models.PaperPortfolio{}, &models.PaperPosition{},
+	&models.PaperTrade{}, &models.PaperSnapshot{},
+	&models.LedgerEntry{}, &models.ExchangeTrade{},
+	&models.PriceAlert{}, &models.Budget{},
+	&models.RecurringTransaction{}, &models.StatementImport{},
+	&models.CategoryRule{}, &models.NetWorthAccount{},
+	&models.NetWorthSnapshot{},
+}
 
+// Open connects without touching or checking the schema; the migrate
+// command uses it directly.
+func Open() (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(buildDSN()), &gorm.Config{})
 	if err != nil {
-		return fmt.Errorf("db connect: %w", err)
+		return nil, fmt.Errorf("db connect: %w", err)
 	}
+	return db, nil
+}
 
-	if err := db.AutoMigrate(
-		&models.ChatHistory{}, &models.ChatSession{},
-		&models.FinanceRecord{}, &models.CryptoHoldings{},
-		&models.SocialPost{}, &models.JobApplication{},
-		&models.TaskRecord{}, &models.DietRecord{},
-		&models.WorkoutRecord{}, &models.TradingSignal{},
-		&models.ResearchReports{}, &models.SystemEvent{},
-		&models.RevenueCampaign{}, &models.VentureCampaign{},
-		&models.AgentConfig{}, &models.SecurityAudit{},
-		&models.KnowledgeNode{}, &models.CodeSnippet{},
-		&models.PendingAction{},
-	); err != nil {
-		return fmt.Errorf("automigrate: %w", err)
+// Connect opens the database for the server and refuses to continue while
+// migrations are pending, rather than running against a stale schema.
+func Connect() error {
+	db, err := Open()
+	if err != nil {
+		return err
+	}
+
+	pending, err := PendingMigrations(db)
+	if err != nil {
+		return fmt.Errorf("check migrations: %w", err)
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("schema is behind: %d pending migration(s), run `gateway migrate up`", len(pending))
 	}
 
-	Instance = db
+	if err := Use(db); err != nil {
+		return err
+	}
 	// SeedAgents(Instance)
-	log.Println("[DB] Connected and migrated")
+	log.Println("[DB] Connected, schema up to date")
+	return nil
+}
+
+// Use registers owner scoping on g and makes it the Instance. Connect calls
+// it once the schema is checked; tests call it with their own connection.
+func Use(g *gorm.DB) error {
+	if err := registerOwnerScope(g); err != nil {
+		return fmt.Errorf("owner scope: %w", err)
+	}
+	Instance = g
+	return nil
+}
+
+// ClaimUnowned assigns every row without an owner to owner. It runs once,
+// in the transaction that registers the first user, so data from before
+// authentication existed is not orphaned. Jobs running a built-in Task are
+// the gateway's own, run for every user, and stay unowned with their runs.
+func ClaimUnowned(tx *gorm.DB, owner uuid.UUID) error {
+	for _, m := range ownedModels {
+		q := tx.Model(m).Where("owner_id IS NULL")
+		switch m.(type) {
+		case *models.ScheduledJob:
+			q = q.Where("COALESCE(task, '') = ''")
+		case *models.JobRun:
+			q = q.Where("job_id NOT IN (SELECT id FROM scheduled_jobs WHERE COALESCE(task, '') <> '')")
+		}
+		if err := q.Update("owner_id", owner).Error; err != nil {
+			return fmt.Errorf("claim %T: %w", m, err)
+		}
+	}
 	return nil
 }
 
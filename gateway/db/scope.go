@@ -0,0 +1,93 @@
+package db
+
+// Per-user data scoping. A context carrying an owner (see WithOwner) makes
+// every statement on a model with an OwnerID field filter by that owner, and
+// every insert stamp it. Statements without an owner in their context, such
+// as the scheduler's bookkeeping, run unscoped.
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ownerKey struct{}
+
+func WithOwner(ctx context.Context, owner uuid.UUID) context.Context {
+	return context.WithValue(ctx, ownerKey{}, owner)
+}
+
+func OwnerFrom(ctx context.Context) (uuid.UUID, bool) {
+	if ctx == nil {
+		return uuid.Nil, false
+	}
+	owner, ok := ctx.Value(ownerKey{}).(uuid.UUID)
+	return owner, ok && owner != uuid.Nil
+}
+
+// For returns a handle whose statements are scoped to the owner in ctx.
+func For(ctx context.Context) *gorm.DB {
+	return Instance.WithContext(ctx)
+}
+
+func registerOwnerScope(g *gorm.DB) error {
+	cb := g.Callback()
+	if err := cb.Create().Before("gorm:create").Register("owner:stamp", stampOwner); err != nil {
+		return err
+	}
+	if err := cb.Query().Before("gorm:query").Register("owner:scope", scopeOwner); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("owner:scope", scopeOwner); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("owner:scope", scopeOwner); err != nil {
+		return err
+	}
+	return cb.Delete().Before("gorm:delete").Register("owner:scope", scopeOwner)
+}
+
+func scopeOwner(tx *gorm.DB) {
+	owner, ok := OwnerFrom(tx.Statement.Context)
+	if !ok || tx.Statement.Schema == nil {
+		return
+	}
+	field := tx.Statement.Schema.LookUpField("OwnerID")
+	if field == nil {
+		return
+	}
+	tx.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: owner},
+	}})
+}
+
+func stampOwner(tx *gorm.DB) {
+	owner, ok := OwnerFrom(tx.Statement.Context)
+	if !ok || tx.Statement.Schema == nil {
+		return
+	}
+	field := tx.Statement.Schema.LookUpField("OwnerID")
+	if field == nil {
+		return
+	}
+
+	ctx := tx.Statement.Context
+	rv := tx.Statement.ReflectValue
+	stamp := func(v reflect.Value) {
+		if _, zero := field.ValueOf(ctx, v); zero {
+			field.Set(ctx, v, &owner)
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			stamp(reflect.Indirect(rv.Index(i)))
+		}
+	case reflect.Struct:
+		stamp(rv)
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"gateway/fakeexchange"
+	"log"
+	"net/http"
+)
+
+// runFakeExchange implements `gateway fake-exchange [addr]`, serving the
+// fixture exchange for KRAKEN_BASE_URL to point at.
+func runFakeExchange(args []string) {
+	addr := ":8090"
+	if len(args) > 0 {
+		addr = args[0]
+	}
+
+	srv, err := fakeexchange.New()
+	if err != nil {
+		log.Fatal("[FAKE] ", err)
+	}
+	log.Printf("[FAKE] Kraken-compatible fake exchange on %s", addr)
+	log.Fatal(http.ListenAndServe(addr, srv.Handler()))
+}
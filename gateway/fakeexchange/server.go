@@ -0,0 +1,331 @@
+// Package fakeexchange serves a Kraken-compatible REST API from fixture
+// data, so the gateway can be run and demoed without exchange credentials.
+// Point KRAKEN_BASE_URL at it. Signatures are not checked; orders are kept
+// in memory and market orders fill at the fixture ticker.
+package fakeexchange
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed fixtures/*.json
+var fixtureFS embed.FS
+
+// pageSize matches Kraken's 50 results per history page.
+const pageSize = 50
+
+// takerFee is charged on filled orders, as a fraction of cost.
+const takerFee = 0.0026
+
+type assetPair struct {
+	Altname string `json:"altname"`
+	Base    string `json:"base"`
+	Quote   string `json:"quote"`
+}
+
+type ticker struct {
+	Ask  []string `json:"a"`
+	Bid  []string `json:"b"`
+	Last []string `json:"c"`
+	Vol  []string `json:"v"`
+	Open string   `json:"o"`
+	High []string `json:"h"`
+	Low  []string `json:"l"`
+}
+
+type order struct {
+	Status    string  `json:"status"`
+	OpenTime  float64 `json:"opentm"`
+	CloseTime float64 `json:"closetm,omitempty"`
+	Descr     struct {
+		Pair      string `json:"pair"`
+		Type      string `json:"type"`
+		OrderType string `json:"ordertype"`
+		Price     string `json:"price"`
+		Order     string `json:"order"`
+	} `json:"descr"`
+	Vol     string `json:"vol"`
+	VolExec string `json:"vol_exec"`
+	Cost    string `json:"cost"`
+	Fee     string `json:"fee"`
+	Price   string `json:"price"`
+}
+
+// Server holds the fixtures and any orders placed against it.
+type Server struct {
+	pairs   map[string]assetPair
+	tickers map[string]ticker
+	ohlc    map[string]map[string][][]json.Number
+	balance map[string]string
+	trades  map[string]json.RawMessage
+	ledger  map[string]json.RawMessage
+
+	mu     sync.Mutex
+	orders map[string]*order
+	seq    int
+}
+
+// New loads the embedded fixtures.
+func New() (*Server, error) {
+	s := &Server{orders: map[string]*order{}}
+	var trades, ledger struct {
+		Trades map[string]json.RawMessage `json:"trades"`
+		Ledger map[string]json.RawMessage `json:"ledger"`
+	}
+	for name, dst := range map[string]interface{}{
+		"pairs.json":   &s.pairs,
+		"ticker.json":  &s.tickers,
+		"ohlc.json":    &s.ohlc,
+		"balance.json": &s.balance,
+		"trades.json":  &trades,
+		"ledger.json":  &ledger,
+	} {
+		body, err := fixtureFS.ReadFile("fixtures/" + name)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(body, dst); err != nil {
+			return nil, fmt.Errorf("fixture %s: %w", name, err)
+		}
+	}
+	s.trades, s.ledger = trades.Trades, ledger.Ledger
+	return s, nil
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /0/public/AssetPairs", s.assetPairs)
+	mux.HandleFunc("GET /0/public/Ticker", s.ticker)
+	mux.HandleFunc("GET /0/public/OHLC", s.ohlcHandler)
+	mux.HandleFunc("POST /0/private/Balance", s.balanceHandler)
+	mux.HandleFunc("POST /0/private/TradesHistory", s.tradesHistory)
+	mux.HandleFunc("POST /0/private/Ledgers", s.ledgers)
+	mux.HandleFunc("POST /0/private/AddOrder", s.addOrder)
+	mux.HandleFunc("POST /0/private/QueryOrders", s.queryOrders)
+	return mux
+}
+
+func reply(w http.ResponseWriter, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": []string{}, "result": result})
+}
+
+func fail(w http.ResponseWriter, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": []string{msg}})
+}
+
+// resolvePair accepts a pair's canonical name or its altname.
+func (s *Server) resolvePair(name string) (string, bool) {
+	if _, ok := s.pairs[name]; ok {
+		return name, true
+	}
+	for key, p := range s.pairs {
+		if p.Altname == name {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+func (s *Server) assetPairs(w http.ResponseWriter, r *http.Request) {
+	reply(w, s.pairs)
+}
+
+func (s *Server) ticker(w http.ResponseWriter, r *http.Request) {
+	out := map[string]ticker{}
+	for _, name := range strings.Split(r.URL.Query().Get("pair"), ",") {
+		key, ok := s.resolvePair(name)
+		if !ok {
+			fail(w, "EQuery:Unknown asset pair")
+			return
+		}
+		out[key] = s.tickers[key]
+	}
+	reply(w, out)
+}
+
+// ohlcHandler replays the fixture candles shifted forward in time so the
+// last one is the most recent complete interval.
+func (s *Server) ohlcHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	key, ok := s.resolvePair(q.Get("pair"))
+	if !ok {
+		fail(w, "EQuery:Unknown asset pair")
+		return
+	}
+	interval := q.Get("interval")
+	if interval == "" {
+		interval = "1"
+	}
+	minutes, err := strconv.ParseInt(interval, 10, 64)
+	if err != nil {
+		fail(w, "EGeneral:Invalid arguments")
+		return
+	}
+	since, _ := strconv.ParseInt(q.Get("since"), 10, 64)
+
+	rows := s.ohlc[key][interval]
+	step := minutes * 60
+	var shift int64
+	if len(rows) > 0 {
+		newest, _ := rows[len(rows)-1][0].Int64()
+		shift = (time.Now().Unix()/step-1)*step - newest
+	}
+
+	out := [][]interface{}{}
+	var last int64
+	for _, row := range rows {
+		t, _ := row[0].Int64()
+		t += shift
+		if t <= since {
+			continue
+		}
+		shifted := []interface{}{t}
+		for _, v := range row[1:] {
+			shifted = append(shifted, v)
+		}
+		out = append(out, shifted)
+		last = t
+	}
+	reply(w, map[string]interface{}{key: out, "last": last})
+}
+
+func (s *Server) balanceHandler(w http.ResponseWriter, r *http.Request) {
+	reply(w, s.balance)
+}
+
+// page returns the entries of a history fixture at or after start, newest
+// first as Kraken orders them, from offset ofs.
+func page(r *http.Request, entries map[string]json.RawMessage) (map[string]json.RawMessage, int) {
+	r.ParseForm()
+	start, _ := strconv.ParseFloat(r.PostForm.Get("start"), 64)
+	ofs, _ := strconv.Atoi(r.PostForm.Get("ofs"))
+
+	type entry struct {
+		id   string
+		time float64
+	}
+	var matched []entry
+	for id, body := range entries {
+		var e struct {
+			Time float64 `json:"time"`
+		}
+		json.Unmarshal(body, &e)
+		if e.Time > start {
+			matched = append(matched, entry{id, e.Time})
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].time > matched[j].time })
+
+	out := map[string]json.RawMessage{}
+	for i := ofs; i < len(matched) && i < ofs+pageSize; i++ {
+		out[matched[i].id] = entries[matched[i].id]
+	}
+	return out, len(matched)
+}
+
+func (s *Server) tradesHistory(w http.ResponseWriter, r *http.Request) {
+	trades, count := page(r, s.trades)
+	reply(w, map[string]interface{}{"trades": trades, "count": count})
+}
+
+func (s *Server) ledgers(w http.ResponseWriter, r *http.Request) {
+	ledger, count := page(r, s.ledger)
+	reply(w, map[string]interface{}{"ledger": ledger, "count": count})
+}
+
+func (s *Server) addOrder(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	f := r.PostForm
+	key, ok := s.resolvePair(f.Get("pair"))
+	if !ok {
+		fail(w, "EQuery:Unknown asset pair")
+		return
+	}
+	side, orderType := f.Get("type"), f.Get("ordertype")
+	if side != "buy" && side != "sell" {
+		fail(w, "EGeneral:Invalid arguments:type")
+		return
+	}
+	if orderType != "market" && orderType != "limit" {
+		fail(w, "EGeneral:Invalid arguments:ordertype")
+		return
+	}
+	vol, err := strconv.ParseFloat(f.Get("volume"), 64)
+	if err != nil || vol <= 0 {
+		fail(w, "EGeneral:Invalid arguments:volume")
+		return
+	}
+	limit, _ := strconv.ParseFloat(f.Get("price"), 64)
+	if orderType == "limit" && limit <= 0 {
+		fail(w, "EGeneral:Invalid arguments:price")
+		return
+	}
+
+	alt := s.pairs[key].Altname
+	desc := fmt.Sprintf("%s %s %s @ market", side, f.Get("volume"), alt)
+	if orderType == "limit" {
+		desc = fmt.Sprintf("%s %s %s @ limit %s", side, f.Get("volume"), alt, f.Get("price"))
+	}
+	result := map[string]interface{}{"descr": map[string]string{"order": desc}}
+	if f.Get("validate") == "true" {
+		reply(w, result)
+		return
+	}
+
+	o := &order{Status: "open", OpenTime: float64(time.Now().Unix()), Vol: f.Get("volume"), VolExec: "0", Cost: "0", Fee: "0", Price: "0"}
+	o.Descr.Pair, o.Descr.Type, o.Descr.OrderType, o.Descr.Price, o.Descr.Order = alt, side, orderType, f.Get("price"), desc
+
+	// Market orders fill at the last price; limits fill if they cross it.
+	tick := s.tickers[key]
+	if len(tick.Last) == 0 {
+		fail(w, "EService:Unavailable")
+		return
+	}
+	last, _ := strconv.ParseFloat(tick.Last[0], 64)
+	if orderType == "market" || (side == "buy" && limit >= last) || (side == "sell" && limit <= last) {
+		cost := vol * last
+		o.Status, o.CloseTime = "closed", o.OpenTime
+		o.VolExec = f.Get("volume")
+		o.Price = strconv.FormatFloat(last, 'f', -1, 64)
+		o.Cost = strconv.FormatFloat(cost, 'f', 5, 64)
+		o.Fee = strconv.FormatFloat(cost*takerFee, 'f', 5, 64)
+	}
+
+	s.mu.Lock()
+	s.seq++
+	txid := fmt.Sprintf("OFAKE-%06d", s.seq)
+	s.orders[txid] = o
+	s.mu.Unlock()
+
+	log.Printf("[FAKE] %s %s -> %s", txid, desc, o.Status)
+	result["txid"] = []string{txid}
+	reply(w, result)
+}
+
+func (s *Server) queryOrders(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := map[string]*order{}
+	for _, id := range strings.Split(r.PostForm.Get("txid"), ",") {
+		o, ok := s.orders[id]
+		if !ok {
+			fail(w, "EOrder:Invalid order")
+			return
+		}
+		out[id] = o
+	}
+	reply(w, out)
+}
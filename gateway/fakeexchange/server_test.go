@@ -0,0 +1,37 @@
+package fakeexchange
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAddOrderWithoutTicker(t *testing.T) {
+	s, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.tickers["DOTUSD"] = ticker{}
+
+	form := url.Values{"pair": {"DOTUSD"}, "type": {"buy"}, "ordertype": {"market"}, "volume": {"1"}}
+	req := httptest.NewRequest(http.MethodPost, "/0/private/AddOrder", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+
+	var body struct {
+		Error []string `json:"error"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Error) != 1 || body.Error[0] != "EService:Unavailable" {
+		t.Fatalf("error = %v, want EService:Unavailable", body.Error)
+	}
+	if len(s.orders) != 0 {
+		t.Fatalf("%d orders recorded, want none", len(s.orders))
+	}
+}
@@ -18,11 +18,24 @@ func main() {
 		log.Println("[CONFIG] No .env file found — using environment variables")
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "fake-exchange" {
+		runFakeExchange(os.Args[2:])
+		return
+	}
+
+	if err := services.SetupExchange(); err != nil {
+		log.Fatal("[CONFIG] Fatal:", err)
+	}
+
 	if err := db.Connect(); err != nil {
 		log.Fatal("[DB] Fatal:", err)
 	}
 
-	go services.StartBrainHeartbeat()
+	go services.StartScheduler()
 
 	app := fiber.New(fiber.Config{
 		ErrorHandler: func(c fiber.Ctx, err error) error {
@@ -34,7 +47,20 @@ func main() {
 	app.Use(cors.New())
 	app.Get("/uploads/*", static.New("./uploads"))
 
-	v1 := app.Group("/api/v1")
+	// Public
+	public := app.Group("/api/v1")
+	public.Post("/auth/register", api.Register)
+	public.Post("/auth/login", api.Login)
+	public.Get("/tools", api.GetToolSchemas)
+
+	v1 := app.Group("/api/v1", api.RequireAuth)
+
+	// Auth
+	v1.Post("/auth/logout", api.Logout)
+	v1.Get("/auth/me", api.GetMe)
+	v1.Get("/auth/keys", api.GetAPIKeys)
+	v1.Post("/auth/keys", api.CreateAPIKey)
+	v1.Delete("/auth/keys/:id", api.DeleteAPIKey)
 
 	// Sessions
 	v1.Get("/sessions", api.GetSessions)
@@ -45,14 +71,17 @@ func main() {
 
 	// Agents
 	v1.Get("/agents", api.GetAgents)
-	v1.Patch("/agents/:slug", api.UpdateAgentPrompt)
+	v1.Patch("/agents/:slug", api.RequireAdmin, api.UpdateAgentPrompt)
 
 	// Core
 	v1.Get("/overview", api.GetOverviewSnapshot)
 	v1.Post("/intent", api.HandleIntent)
+	v1.Post("/intent/stream", api.HandleIntentStream)
 	v1.Get("/modules", api.GetModules)
 	v1.Get("/history", api.GetHistory)
 	v1.Post("/upload", api.UploadHandler)
+	v1.Get("/events", api.GetEvents)
+	v1.Get("/events/stream", api.StreamEvents)
 
 	// Modules
 	v1.Get("/social/posts", api.GetSocialPosts)
@@ -60,17 +89,84 @@ func main() {
 	v1.Get("/jobs", api.GetJobs)
 	v1.Get("/research", api.GetResearch)
 	v1.Get("/health/stats", api.GetHealthStats)
+
+	// Action Center
 	v1.Get("/actions", api.GetAllActions)
 	v1.Get("/actions/pending", api.GetPendingActions)
+	v1.Get("/actions/:id", api.GetAction)
+	v1.Patch("/actions/:id", api.EditAction)
+	v1.Post("/actions/:id/approve", api.RequireTradeAdmin, api.ApproveAction)
+	v1.Post("/actions/:id/reject", api.RejectAction)
+	v1.Post("/actions/:id/retry", api.RequireTradeAdmin, api.RetryAction)
+
+	// Scheduler
+	v1.Get("/schedules", api.GetSchedules)
+	v1.Post("/schedules", api.CreateSchedule)
+	v1.Get("/schedules/:id", api.GetSchedule)
+	v1.Patch("/schedules/:id", api.UpdateSchedule)
+	v1.Delete("/schedules/:id", api.DeleteSchedule)
+	v1.Get("/schedules/:id/runs", api.GetScheduleRuns)
+	v1.Post("/schedules/:id/run", api.TriggerSchedule)
 
 	// Finance
 	v1.Get("/finance/summary", api.GetFinanceSummary)
 	v1.Get("/finance/ventures", api.GetVentures)
 	v1.Get("/finance/holdings", api.GetCryptoHoldings)
 	v1.Get("/finance/sync", api.SyncHoldings)
+	v1.Get("/finance/ledger", api.GetLedger)
+	v1.Get("/finance/trades", api.GetExchangeTrades)
+	v1.Post("/finance/import/kraken", api.ImportKrakenHistory)
+	v1.Get("/finance/tax/report", api.GetTaxReport)
 	v1.Get("/finance/signals", api.GetSignals)
 	v1.Patch("/finance/signals/:id", api.UpdateSignalStatus)
+	v1.Post("/finance/signals/:id/order", api.RequireAdmin, api.OrderSignal)
+	v1.Post("/finance/signals/:id/refresh", api.RequireAdmin, api.RefreshSignalOrder)
+	v1.Get("/finance/trade-limits", api.GetTradeLimits)
 	v1.Get("/finance/ohlc", api.GetOHLCData)
+	v1.Get("/finance/paper", api.GetPaperPortfolio)
+	v1.Get("/finance/paper/trades", api.GetPaperTrades)
+	v1.Get("/finance/paper/history", api.GetPaperHistory)
+	v1.Get("/finance/paper/stats", api.GetPaperStats)
+	v1.Post("/finance/paper/run", api.RunPaperTrading)
+	v1.Post("/finance/paper/reset", api.ResetPaperPortfolio)
+	v1.Post("/finance/candles/ingest", api.IngestCandles)
+	v1.Post("/finance/backtest", api.RunBacktest)
+	v1.Get("/finance/alerts", api.GetAlerts)
+	v1.Post("/finance/alerts", api.CreateAlert)
+	v1.Patch("/finance/alerts/:id", api.UpdateAlert)
+	v1.Delete("/finance/alerts/:id", api.DeleteAlert)
+	v1.Post("/finance/alerts/check", api.CheckAlerts)
+	v1.Get("/finance/budgets", api.GetBudgets)
+	v1.Post("/finance/budgets", api.CreateBudget)
+	v1.Get("/finance/budgets/status", api.GetBudgetStatus)
+	v1.Patch("/finance/budgets/:id", api.UpdateBudget)
+	v1.Delete("/finance/budgets/:id", api.DeleteBudget)
+	v1.Get("/finance/recurring", api.GetRecurring)
+	v1.Post("/finance/recurring", api.CreateRecurring)
+	v1.Get("/finance/recurring/upcoming", api.GetUpcomingBills)
+	v1.Get("/finance/recurring/detect", api.DetectSubscriptions)
+	v1.Patch("/finance/recurring/:id", api.UpdateRecurring)
+	v1.Delete("/finance/recurring/:id", api.DeleteRecurring)
+	v1.Get("/finance/import/statements", api.GetStatementImports)
+	v1.Post("/finance/import/statement", api.ImportStatement)
+	v1.Get("/finance/import/statement/:id", api.PreviewStatementImport)
+	v1.Post("/finance/import/statement/:id/commit", api.CommitStatementImport)
+	v1.Get("/finance/category-rules", api.GetCategoryRules)
+	v1.Post("/finance/category-rules", api.CreateCategoryRule)
+	v1.Patch("/finance/category-rules/:id", api.UpdateCategoryRule)
+	v1.Delete("/finance/category-rules/:id", api.DeleteCategoryRule)
+	v1.Get("/finance/fx/rates", api.GetFXRates)
+	v1.Post("/finance/fx/rates", api.RequireAdmin, api.SetFXRate)
+	v1.Post("/finance/fx/rates/import", api.RequireAdmin, api.ImportFXRates)
+	v1.Get("/finance/fx/convert", api.ConvertCurrency)
+	v1.Get("/finance/forecast", api.GetCashFlowForecast)
+	v1.Get("/finance/networth", api.GetNetWorthAnalysis)
+	v1.Get("/finance/networth/history", api.GetNetWorthHistory)
+	v1.Post("/finance/networth/snapshot", api.SnapshotNetWorth)
+	v1.Get("/finance/networth/accounts", api.GetNetWorthAccounts)
+	v1.Post("/finance/networth/accounts", api.CreateNetWorthAccount)
+	v1.Patch("/finance/networth/accounts/:id", api.UpdateNetWorthAccount)
+	v1.Delete("/finance/networth/accounts/:id", api.DeleteNetWorthAccount)
 
 	port := os.Getenv("PORT")
 	if port == "" {
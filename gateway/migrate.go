@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"gateway/db"
+	"gateway/services"
+	"log"
+	"os"
+	"strconv"
+)
+
+const migrateUsage = "usage: gateway migrate up | down [steps] | status"
+
+// runMigrate implements `gateway migrate up|down|status`.
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+
+	conn, err := db.Open()
+	if err != nil {
+		log.Fatal("[DB] Fatal:", err)
+	}
+
+	switch args[0] {
+	case "up":
+		// Rows that predate a currency column are in the configured
+		// default currency, not necessarily USD.
+		applied, err := db.MigrateUp(conn, map[string]string{"gateway.default_currency": services.DefaultCurrency()})
+		for _, m := range applied {
+			log.Printf("[MIGRATE] applied %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal("[MIGRATE] ", err)
+		}
+		if len(applied) == 0 {
+			log.Println("[MIGRATE] schema already up to date")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				log.Fatal(migrateUsage)
+			}
+		}
+		reverted, err := db.MigrateDown(conn, steps)
+		for _, m := range reverted {
+			log.Printf("[MIGRATE] reverted %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal("[MIGRATE] ", err)
+		}
+
+	case "status":
+		states, err := db.MigrationStatus(conn)
+		if err != nil {
+			log.Fatal("[MIGRATE] ", err)
+		}
+		for _, s := range states {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(os.Stdout, "%04d  %-24s %s\n", s.Version, s.Name, applied)
+		}
+
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
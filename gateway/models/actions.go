@@ -1,12 +1,37 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ActionPending   = "Pending"
+	ActionEdited    = "Edited"
+	ActionApproved  = "Approved"
+	ActionRejected  = "Rejected"
+	ActionExecuting = "Executing"
+	ActionExecuted  = "Executed"
+	ActionFailed    = "Failed"
+)
 
 type PendingAction struct {
 	gorm.Model
+	Owned
 	Type     string `json:"type"`
 	Title    string `json:"title"`
 	Content  string `json:"content" gorm:"type:text"`
-	Status   string `json:"status"`
+	Status   string `json:"status" gorm:"index"`
 	Priority string `json:"priority"`
-}
\ No newline at end of file
+	RefID    string `json:"ref_id" gorm:"index"` // ID of the record that produced the draft
+
+	// Audit trail
+	EditedBy   string     `json:"edited_by"`
+	EditedAt   *time.Time `json:"edited_at"`
+	DecidedBy  string     `json:"decided_by"` // approver or rejecter
+	DecidedAt  *time.Time `json:"decided_at"`
+	ExecutedAt *time.Time `json:"executed_at"`
+	Attempts   int        `json:"attempts"`
+	LastError  string     `json:"last_error" gorm:"type:text"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const (
+	AlertPriceAbove     = "price_above"
+	AlertPriceBelow     = "price_below"
+	AlertPercentChange  = "percent_change"
+	AlertPortfolioAbove = "portfolio_above"
+	AlertPortfolioBelow = "portfolio_below"
+)
+
+// PriceAlert is a user-defined watch the gateway checks against exchange
+// tickers. It fires when its condition starts to hold, then not again until
+// the condition has cleared and CooldownMins have passed.
+type PriceAlert struct {
+	gorm.Model
+	Owned
+	Name         string          `json:"name"`
+	Kind         string          `json:"kind"`
+	Asset        string          `json:"asset"`         // normalized ticker; empty for portfolio alerts
+	Threshold    decimal.Decimal `json:"threshold"`     // USD level, or percent for percent_change
+	WindowMins   int             `json:"window_mins"`   // percent_change lookback
+	CooldownMins int             `json:"cooldown_mins"` // minimum gap between firings
+	Enabled      bool            `json:"enabled"`
+	Triggered    bool            `json:"triggered"` // condition held when it last fired and has not cleared
+	LastValue    decimal.Decimal `json:"last_value"`
+	LastChecked  *time.Time      `json:"last_checked"`
+	LastFiredAt  *time.Time      `json:"last_fired_at"`
+	FireCount    int             `json:"fire_count"`
+}
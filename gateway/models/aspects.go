@@ -5,6 +5,7 @@ import "gorm.io/gorm"
 // Vanguard: Privacy/Security logs
 type SecurityAudit struct {
 	gorm.Model
+	Owned
 	Issue    string `json:"issue"`
 	Severity string `json:"severity"` // "Low", "Medium", "High", "Critical"
 	Status   string `json:"status"`   // "Open", "Patched"
@@ -13,6 +14,7 @@ type SecurityAudit struct {
 // Oracle: Knowledge Base
 type KnowledgeNode struct {
 	gorm.Model
+	Owned
 	Topic    string `json:"topic" gorm:"index"`
 	Content  string `json:"content" gorm:"type:text"`
 	Tags     string `json:"tags"`
@@ -21,6 +23,7 @@ type KnowledgeNode struct {
 // Builder: Code/Automation logs
 type CodeSnippet struct {
 	gorm.Model
+	Owned
 	FileName    string `json:"file_name"`
 	Language    string `json:"language"`
 	Code        string `json:"code" gorm:"type:text"`
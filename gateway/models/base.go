@@ -11,6 +11,12 @@ type Base struct {
 	UpdatedAt time.Time
 }
 
+// Owned marks a row as belonging to one user. Rows created before
+// authentication existed have a NULL owner until claimed.
+type Owned struct {
+	OwnerID *uuid.UUID `json:"owner_id" gorm:"type:uuid;index"`
+}
+
 type HistoryMessage struct {
 	Role string `json:"role"`
 	Text string `json:"text"`
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const (
+	RolloverNone    = "none"    // each month starts at the limit
+	RolloverSurplus = "surplus" // unspent budget carries into the next month
+	RolloverFull    = "full"    // surplus and overspend both carry
+)
+
+// Budget caps monthly expenses in one FinanceRecord category, matched
+// case-insensitively.
+type Budget struct {
+	gorm.Model
+	Owned
+	Category     string          `json:"category"`
+	MonthlyLimit decimal.Decimal `json:"monthly_limit"`
+	Rollover     string          `json:"rollover"`
+	MaxRollover  decimal.Decimal `json:"max_rollover"` // cap on carried surplus; 0 means no cap
+	StartsOn     time.Time       `json:"starts_on"`    // first month rollover is counted from
+}
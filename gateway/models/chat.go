@@ -4,6 +4,7 @@ import "gorm.io/gorm"
 
 type ChatSession struct {
 	gorm.Model
+	Owned
 	SessionID string `json:"session_id" gorm:"uniqueIndex"`
 	Title     string `json:"title"`
 	UserID    string `json:"user_id"`
@@ -11,6 +12,7 @@ type ChatSession struct {
 
 type ChatHistory struct {
 	gorm.Model
+	Owned
 	UserID    string `json:"user_id" gorm:"index"`
 	SessionID string `json:"session_id" gorm:"index"`
 	Role      string `json:"role"`
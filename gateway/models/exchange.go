@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// LedgerEntry is one imported line of the exchange ledger: a trade leg,
+// deposit, withdrawal, staking reward, transfer or fee. Fees and rewards
+// are mirrored into FinanceRecord so they appear with the rest of the
+// cash flow.
+type LedgerEntry struct {
+	gorm.Model
+	Owned
+	LedgerID        string          `json:"ledger_id" gorm:"index"` // exchange's ID, unique per owner
+	RefID           string          `json:"ref_id" gorm:"index"`    // trade txid for trade legs
+	Time            time.Time       `json:"time" gorm:"index"`
+	Type            string          `json:"type"` // "trade", "deposit", "withdrawal", "staking", "earn", ...
+	Subtype         string          `json:"subtype"`
+	Asset           string          `json:"asset"` // normalized, e.g. "BTC"
+	RawAsset        string          `json:"raw_asset"`
+	Amount          decimal.Decimal `json:"amount"`
+	Fee             decimal.Decimal `json:"fee"`
+	Balance         decimal.Decimal `json:"balance"`
+	FinanceRecordID *uuid.UUID      `json:"finance_record_id" gorm:"type:uuid"`
+}
+
+// ExchangeTrade is one imported fill from the exchange's trade history.
+type ExchangeTrade struct {
+	gorm.Model
+	Owned
+	TxID      string          `json:"txid" gorm:"index"` // unique per owner
+	OrderTxID string          `json:"order_txid"`
+	Pair      string          `json:"pair"`
+	Time      time.Time       `json:"time" gorm:"index"`
+	Type      string          `json:"type"` // "buy" or "sell"
+	OrderType string          `json:"order_type"`
+	Price     decimal.Decimal `json:"price"`
+	Cost      decimal.Decimal `json:"cost"`
+	Fee       decimal.Decimal `json:"fee"`
+	Volume    decimal.Decimal `json:"volume"`
+}
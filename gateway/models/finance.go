@@ -1,49 +1,92 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+func init() {
+	// Money fields are decimals; keep them JSON numbers as when they were
+	// float64.
+	decimal.MarshalJSONWithoutQuotes = true
+}
+
+const (
+	SignalPending   = "Pending"
+	SignalAwaiting  = "Awaiting Approval"
+	SignalPlacing   = "Placing"   // order sent to the exchange, no txid recorded yet
+	SignalSubmitted = "Submitted" // order placed, not fully filled
+	SignalFilled    = "Filled"
+)
+
 type FinanceRecord struct {
 	Base
-	Amount      float64 `json:"amount"`
-	Category    string  `json:"category"`
-	Description string  `json:"description"`
-	Type        string  `json:"type"` // "Income" or "Expense"
+	Owned
+	Amount      decimal.Decimal `json:"amount"`
+	Currency    string          `json:"currency" gorm:"default:USD"`
+	Category    string          `json:"category"`
+	Description string          `json:"description"`
+	Type        string          `json:"type"`                      // "Income" or "Expense"
+	RecurringID *uint           `json:"recurring_id" gorm:"index"` // template that posted it
+	ImportID    *uint           `json:"import_id" gorm:"index"`    // statement it was imported from
 }
 
 type CryptoHoldings struct {
 	gorm.Model
-	Asset     string  `json:"asset"`      // e.g., "BTC", "ETH", "USD"
-	Balance   float64 `json:"balance"`    // Amount held
-	CostBasis float64 `json:"cost_basis"` // Optional: For profit/loss calculation
-	USDValue  float64 `json:"usd_value"`  // Last synced USD value
+	Owned
+	Asset      string           `json:"asset"`       // e.g., "BTC", "ETH", "USD"
+	Balance    decimal.Decimal  `json:"balance"`     // Amount held
+	CostBasis  *decimal.Decimal `json:"cost_basis"`  // nil when some of it was not bought for USD
+	USDValue   decimal.Decimal  `json:"usd_value"`   // Last synced USD value
+	PricedAt   *time.Time       `json:"priced_at"`   // when the price behind USDValue was fetched
+	PriceStale bool             `json:"price_stale"` // the last sync had no price; USDValue uses the one before
 }
 
 type TradingSignal struct {
-    gorm.Model
-    Asset      string  `json:"asset"`
-    Action     string  `json:"action"`
-    Price      float64 `json:"price"`
-    Reasoning  string  `json:"reasoning"`
-    Confidence float64 `json:"confidence"`
-    Status     string  `json:"status"`
+	gorm.Model
+	Owned
+	Asset      string          `json:"asset"`
+	Action     string          `json:"action"`
+	Price      decimal.Decimal `json:"price"`
+	Reasoning  string          `json:"reasoning"`
+	Confidence float64         `json:"confidence"`
+	Status     string          `json:"status"`
+
+	// Order placed from this signal once approved in the Action Center.
+	OrderType    string          `json:"order_type"` // "market" or "limit"
+	Volume       decimal.Decimal `json:"volume"`
+	LimitPrice   decimal.Decimal `json:"limit_price"`
+	Notional     decimal.Decimal `json:"notional"` // USD value counted against trade limits
+	OrderID      string          `json:"order_id" gorm:"index"`
+	OrderStatus  string          `json:"order_status"`
+	FilledVolume decimal.Decimal `json:"filled_volume"`
+	AvgFillPrice decimal.Decimal `json:"avg_fill_price"`
+	Fee          decimal.Decimal `json:"fee"`
+	OrderedAt    *time.Time      `json:"ordered_at"`
 }
 
 type RevenueCampaign struct {
 	gorm.Model
-	Name        string  `json:"name"`        // e.g., "AI Tool Affiliate Bot"
-	Status      string  `json:"status"`      // "Active", "Paused", "Researching"
-	Platform    string  `json:"platform"`    // "X", "Substack", "Kraken"
-	Strategy    string  `json:"strategy"`    // AI's internal logic
-	Budget      float64 `json:"budget"`      // Initial capital
-	TotalEarned float64 `json:"total_earned"` // Total ROI
+	Owned
+	Name        string          `json:"name"`         // e.g., "AI Tool Affiliate Bot"
+	Status      string          `json:"status"`       // "Active", "Paused", "Researching"
+	Platform    string          `json:"platform"`     // "X", "Substack", "Kraken"
+	Strategy    string          `json:"strategy"`     // AI's internal logic
+	Budget      decimal.Decimal `json:"budget"`       // Initial capital
+	TotalEarned decimal.Decimal `json:"total_earned"` // Total ROI
 }
 
 type VentureCampaign struct {
 	gorm.Model
-	Name            string  `json:"name"`
-	Status          string  `json:"status"`           // "Incubating", "Active", "Scaling"
-	Category        string  `json:"category"`         // "Affiliate", "SaaS", "Content"
-	StrategySummary string  `json:"strategy_summary"`
-	ProjectedROI    string  `json:"projected_roi"`
-	Platform        string  `json:"platform"`
-	RevenueEarned   float64 `json:"revenue_earned"`
-}
\ No newline at end of file
+	Owned
+	Name            string          `json:"name"`
+	Status          string          `json:"status"`   // "Incubating", "Active", "Scaling"
+	Category        string          `json:"category"` // "Affiliate", "SaaS", "Content"
+	StrategySummary string          `json:"strategy_summary"`
+	ProjectedROI    string          `json:"projected_roi"`
+	Platform        string          `json:"platform"`
+	RevenueEarned   decimal.Decimal `json:"revenue_earned"`
+	Currency        string          `json:"currency" gorm:"default:USD"` // of RevenueEarned
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// FXRate is the price of one unit of Base in Quote on the day AsOf. Rates
+// are shared reference data, so they have no owner.
+type FXRate struct {
+	ID        uint            `json:"id" gorm:"primaryKey"`
+	Base      string          `json:"base" gorm:"uniqueIndex:idx_fx_rates_key"`
+	Quote     string          `json:"quote" gorm:"uniqueIndex:idx_fx_rates_key"`
+	AsOf      time.Time       `json:"as_of" gorm:"type:date;uniqueIndex:idx_fx_rates_key"`
+	Rate      decimal.Decimal `json:"rate"`
+	Source    string          `json:"source"` // "manual" or the file it was loaded from
+	UpdatedAt time.Time       `json:"updated_at"`
+}
@@ -2,6 +2,7 @@ package models
 
 type DietRecord struct {
 	Base
+	Owned
 	FoodItem string  `json:"food_item"`
 	Calories int     `json:"calories"`
 	Protein  int     `json:"protein"`
@@ -11,6 +12,7 @@ type DietRecord struct {
 
 type WorkoutRecord struct {
 	Base
+	Owned
 	Exercise     string  `json:"exercise"`
 	Sets         int     `json:"sets"`
 	Reps         int     `json:"reps"`
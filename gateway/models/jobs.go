@@ -2,6 +2,7 @@ package models
 
 type JobApplication struct {
 	Base
+	Owned
 	Company      string `json:"company"`
 	Role         string `json:"role"`
 	Status       string `json:"status"` // "applied", "interviewing", "offer", "rejected", "noresp"
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Candle is one stored OHLC bar. Market data is shared, so candles have no
+// owner. Interval is in minutes, as Kraken names it.
+type Candle struct {
+	ID       uint            `json:"-" gorm:"primaryKey"`
+	Pair     string          `json:"pair" gorm:"uniqueIndex:idx_candles_key"`
+	Interval int             `json:"interval" gorm:"column:interval_min;uniqueIndex:idx_candles_key"`
+	Time     time.Time       `json:"time" gorm:"uniqueIndex:idx_candles_key"`
+	Open     decimal.Decimal `json:"open"`
+	High     decimal.Decimal `json:"high"`
+	Low      decimal.Decimal `json:"low"`
+	Close    decimal.Decimal `json:"close"`
+	VWAP     decimal.Decimal `json:"vwap"`
+	Volume   decimal.Decimal `json:"volume"`
+	Trades   int             `json:"trades"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const (
+	AccountCash      = "cash"      // bank and savings balances
+	AccountAsset     = "asset"     // property, vehicles, anything else owned
+	AccountLiability = "liability" // loans, mortgages, card balances
+)
+
+// NetWorthAccount is a balance the exchange sync cannot see, kept up to
+// date by hand.
+type NetWorthAccount struct {
+	gorm.Model
+	Owned
+	Name     string          `json:"name"`
+	Kind     string          `json:"kind"`    // "cash", "asset" or "liability"
+	Balance  decimal.Decimal `json:"balance"` // liabilities are the positive amount owed
+	Currency string          `json:"currency" gorm:"default:USD"`
+	Notes    string          `json:"notes"`
+}
+
+// NetWorthSnapshot is one day's assets and liabilities, all in Currency.
+type NetWorthSnapshot struct {
+	gorm.Model
+	Owned
+	Day         time.Time       `json:"day" gorm:"type:date;index"`
+	Currency    string          `json:"currency"`
+	Crypto      decimal.Decimal `json:"crypto"` // holdings at their last synced USD value
+	Cash        decimal.Decimal `json:"cash"`
+	Assets      decimal.Decimal `json:"assets"` // manual assets other than cash
+	Liabilities decimal.Decimal `json:"liabilities"`
+	TotalAssets decimal.Decimal `json:"total_assets"`
+	NetWorth    decimal.Decimal `json:"net_worth"`
+}
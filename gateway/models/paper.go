@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// PaperPortfolio is a simulated account that trades every TradingSignal so
+// the finance agent can be judged without risking money.
+type PaperPortfolio struct {
+	gorm.Model
+	Owned
+	StartingCash decimal.Decimal `json:"starting_cash"`
+	Cash         decimal.Decimal `json:"cash"`
+	TradeSize    decimal.Decimal `json:"trade_size"` // USD committed per signal
+	RealizedPnL  decimal.Decimal `json:"realized_pnl"`
+}
+
+type PaperPosition struct {
+	gorm.Model
+	Owned
+	PortfolioID uint            `json:"portfolio_id" gorm:"index"`
+	Asset       string          `json:"asset"` // Kraken pair, as on the signal
+	Quantity    decimal.Decimal `json:"quantity"`
+	AvgCost     decimal.Decimal `json:"avg_cost"`
+}
+
+// PaperTrade is one signal's simulated execution. Quantity is what the
+// portfolio could actually fill (a SELL without a position fills nothing);
+// SignalQty is what the signal called for and is what stats are scored on.
+type PaperTrade struct {
+	gorm.Model
+	Owned
+	PortfolioID uint            `json:"portfolio_id" gorm:"index"`
+	SignalID    uint            `json:"signal_id" gorm:"index"`
+	Asset       string          `json:"asset"`
+	Side        string          `json:"side"` // "BUY" or "SELL"
+	Price       decimal.Decimal `json:"price"`
+	PriceSource string          `json:"price_source"` // "signal" or "candle"
+	Quantity    decimal.Decimal `json:"quantity"`
+	SignalQty   decimal.Decimal `json:"signal_qty"`
+	RealizedPnL decimal.Decimal `json:"realized_pnl"`
+	Confidence  float64         `json:"confidence"`
+	ExecutedAt  time.Time       `json:"executed_at"`
+}
+
+// PaperSnapshot is the portfolio marked to market at a point in time.
+type PaperSnapshot struct {
+	gorm.Model
+	Owned
+	PortfolioID   uint            `json:"portfolio_id" gorm:"index"`
+	TakenAt       time.Time       `json:"taken_at" gorm:"index"`
+	Cash          decimal.Decimal `json:"cash"`
+	MarketValue   decimal.Decimal `json:"market_value"`
+	Equity        decimal.Decimal `json:"equity"`
+	RealizedPnL   decimal.Decimal `json:"realized_pnl"`
+	UnrealizedPnL decimal.Decimal `json:"unrealized_pnl"`
+}
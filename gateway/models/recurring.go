@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const (
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+	FrequencyCron    = "cron"
+)
+
+// RecurringTransaction is a template the scheduler posts as a FinanceRecord
+// each time it falls due, e.g. rent, salary or a SaaS subscription.
+type RecurringTransaction struct {
+	gorm.Model
+	Owned
+	Name         string          `json:"name"`
+	Type         string          `json:"type"` // "expense" or "income", as on FinanceRecord
+	Amount       decimal.Decimal `json:"amount"`
+	Currency     string          `json:"currency" gorm:"default:USD"`
+	Category     string          `json:"category"`
+	Description  string          `json:"description"`
+	Frequency    string          `json:"frequency"`
+	DayOfMonth   int             `json:"day_of_month"` // monthly: 1-31, clamped to short months
+	Weekday      int             `json:"weekday"`      // weekly: 0 (Sunday) to 6
+	Cron         string          `json:"cron"`         // custom schedule when Frequency is "cron"
+	Subscription bool            `json:"subscription"`
+	Active       bool            `json:"active"`
+	StartsOn     time.Time       `json:"starts_on"`
+	EndsOn       *time.Time      `json:"ends_on"`
+	NextDueAt    *time.Time      `json:"next_due_at" gorm:"index"`
+	LastPostedAt *time.Time      `json:"last_posted_at"`
+}
@@ -1,6 +1,7 @@
 package models
 type ResearchReports struct {
 	Base
+	Owned
 	Query    string `json:"query"`
 	Findings string `json:"findings"` // The raw search dump
 	Category string `json:"category"` // AI assigned (jobs, finance, etc)
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	RunRunning   = "Running"
+	RunSucceeded = "Succeeded"
+	RunFailed    = "Failed"
+	RunSkipped   = "Skipped"
+)
+
+// ScheduledJob is a proactive job fired by the gateway scheduler. A job
+// either sends Prompt to the Brain (optionally routed to Agent) or, when
+// Task is set, runs a built-in gateway task such as "kraken_sync".
+type ScheduledJob struct {
+	gorm.Model
+	Owned
+	Name      string     `json:"name"`     // unique per owner among live jobs
+	Cron      string     `json:"cron"`     // standard 5-field expression or @hourly/@daily
+	Timezone  string     `json:"timezone"` // IANA name; empty means server local time
+	Agent     string     `json:"agent"`    // AgentConfig slug
+	Prompt    string     `json:"prompt" gorm:"type:text"`
+	Task      string     `json:"task"`
+	Enabled   bool       `json:"enabled"`
+	CatchUp   bool       `json:"catch_up"` // run once after downtime if an occurrence was missed
+	LastRunAt *time.Time `json:"last_run_at"`
+	NextRunAt *time.Time `json:"next_run_at" gorm:"index"`
+}
+
+type JobRun struct {
+	gorm.Model
+	Owned
+	JobID        uint       `json:"job_id" gorm:"index"`
+	ScheduledFor time.Time  `json:"scheduled_for"`
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at"`
+	Status       string     `json:"status"` // "Running", "Succeeded", "Failed", "Skipped"
+	Output       string     `json:"output" gorm:"type:text"`
+	Error        string     `json:"error" gorm:"type:text"`
+}
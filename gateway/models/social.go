@@ -2,6 +2,7 @@ package models
 
 type SocialPost struct {
 	Base
+	Owned
 	Content  string `json:"content"`
 	Platform string `json:"platform"` // "x", "linkedin", "instagram"
 	Status   string `json:"status"`   // "draft", "scheduled", "posted"
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ImportPreview   = "preview"
+	ImportCommitted = "committed"
+)
+
+// StatementImport is a bank export uploaded for import. It stays in
+// preview until committed, when its new rows become FinanceRecords.
+type StatementImport struct {
+	gorm.Model
+	Owned
+	Filename    string     `json:"filename"` // stored under ./uploads
+	Original    string     `json:"original"`
+	Format      string     `json:"format"`                      // "csv" or "ofx" (QFX included)
+	Mapping     string     `json:"mapping" gorm:"type:text"`    // CSV column mapping, JSON
+	Currency    string     `json:"currency" gorm:"default:USD"` // of every row
+	Status      string     `json:"status"`
+	Rows        int        `json:"rows"`
+	Duplicates  int        `json:"duplicates"`
+	Imported    int        `json:"imported"`
+	CommittedAt *time.Time `json:"committed_at"`
+}
+
+// CategoryRule assigns Category to imported transactions whose description
+// contains Pattern (case-insensitive), or matches it when IsRegex. Rules
+// are tried by descending Priority; the first match wins.
+type CategoryRule struct {
+	gorm.Model
+	Owned
+	Pattern  string `json:"pattern"`
+	IsRegex  bool   `json:"is_regex"`
+	Category string `json:"category"`
+	Type     string `json:"type"` // only match "expense" or "income"; empty for both
+	Priority int    `json:"priority"`
+}
@@ -4,6 +4,7 @@ import "time"
 
 type TaskRecord struct {
 	Base
+	Owned
 	Title     string    `json:"title"`
 	Status    string    `json:"status"` // "pending", "done"
 	DueDate   time.Time `json:"due_date"`
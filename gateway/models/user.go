@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+type User struct {
+	Base
+	Email        string `json:"email" gorm:"uniqueIndex"`
+	Name         string `json:"name"`
+	PasswordHash string `json:"-"`
+	Role         string `json:"role"` // "admin", "member"
+}
+
+// APIKey is a long-lived credential for scripts and the Brain. Only the
+// SHA-256 of the key is stored; Prefix is kept so keys can be told apart.
+type APIKey struct {
+	Base
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:uuid;index"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Hash       string     `json:"-" gorm:"uniqueIndex"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// UserSession is a short-lived login token for the dashboard.
+type UserSession struct {
+	Base
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;index"`
+	Hash      string    `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gateway/db"
+	"gateway/models"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrActionNotFound    = errors.New("action not found")
+	ErrInvalidTransition = errors.New("invalid action transition")
+)
+
+// actionTransitions lists the states each status may move to.
+var actionTransitions = map[string][]string{
+	models.ActionPending:   {models.ActionApproved, models.ActionRejected, models.ActionEdited},
+	models.ActionEdited:    {models.ActionApproved, models.ActionRejected, models.ActionEdited},
+	models.ActionApproved:  {models.ActionExecuting},
+	models.ActionExecuting: {models.ActionExecuted, models.ActionFailed},
+	models.ActionFailed:    {models.ActionExecuting, models.ActionRejected},
+}
+
+func canTransition(from, to string) bool {
+	for _, s := range actionTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ActionDeployer performs the real-world side effect of an approved action.
+type ActionDeployer interface {
+	Deploy(tx *gorm.DB, action *models.PendingAction) error
+}
+
+// DeployerFunc adapts a plain function to ActionDeployer.
+type DeployerFunc func(tx *gorm.DB, action *models.PendingAction) error
+
+func (f DeployerFunc) Deploy(tx *gorm.DB, action *models.PendingAction) error {
+	return f(tx, action)
+}
+
+var deployers = map[string]ActionDeployer{}
+
+// RegisterDeployer binds an action Type to its deployer. Types without a
+// deployer are treated as manual: approving them only records the decision.
+func RegisterDeployer(actionType string, d ActionDeployer) {
+	deployers[actionType] = d
+}
+
+func loadAction(ctx context.Context, id string) (*models.PendingAction, error) {
+	var action models.PendingAction
+	if err := db.For(ctx).First(&action, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrActionNotFound
+		}
+		return nil, err
+	}
+	return &action, nil
+}
+
+// transitionAction moves an action from its current status to `to`, applying
+// extra column updates. The status check is part of the UPDATE so two
+// concurrent reviewers cannot both win.
+func transitionAction(ctx context.Context, action *models.PendingAction, to string, updates map[string]interface{}) error {
+	from := action.Status
+	if !canTransition(from, to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, to)
+	}
+
+	if updates == nil {
+		updates = map[string]interface{}{}
+	}
+	updates["status"] = to
+
+	res := db.For(ctx).Model(&models.PendingAction{}).
+		Where("id = ? AND status = ?", action.ID, from).
+		Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s changed concurrently", ErrInvalidTransition, from)
+	}
+
+	return db.For(ctx).First(action, action.ID).Error
+}
+
+func EditAction(ctx context.Context, id, actor, title, content string) (*models.PendingAction, error) {
+	action, err := loadAction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"edited_by": actor, "edited_at": &now}
+	if title != "" {
+		updates["title"] = title
+	}
+	if content != "" {
+		updates["content"] = content
+	}
+
+	if err := transitionAction(ctx, action, models.ActionEdited, updates); err != nil {
+		return nil, err
+	}
+	EmitEvent(ctx, "ACTIONS", "Edited: "+action.Title, "INFO")
+	return action, nil
+}
+
+func RejectAction(ctx context.Context, id, actor string) (*models.PendingAction, error) {
+	action, err := loadAction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := transitionAction(ctx, action, models.ActionRejected, map[string]interface{}{
+		"decided_by": actor,
+		"decided_at": &now,
+	}); err != nil {
+		return nil, err
+	}
+	EmitEvent(ctx, "ACTIONS", "Rejected: "+action.Title, "WARN")
+	return action, nil
+}
+
+// ApproveAction records the decision and deploys the action immediately.
+// A deploy failure leaves the action in Failed and is reported on the
+// returned action, not as an error.
+func ApproveAction(ctx context.Context, id, actor string) (*models.PendingAction, error) {
+	action, err := loadAction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := transitionAction(ctx, action, models.ActionApproved, map[string]interface{}{
+		"decided_by": actor,
+		"decided_at": &now,
+	}); err != nil {
+		return nil, err
+	}
+	EmitEvent(ctx, "ACTIONS", "Approved: "+action.Title, "INFO")
+
+	return deployAction(ctx, action)
+}
+
+// RetryAction re-runs the deployer for a Failed action.
+func RetryAction(ctx context.Context, id string) (*models.PendingAction, error) {
+	action, err := loadAction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if action.Status != models.ActionFailed {
+		return nil, fmt.Errorf("%w: only Failed actions can be retried", ErrInvalidTransition)
+	}
+	return deployAction(ctx, action)
+}
+
+func deployAction(ctx context.Context, action *models.PendingAction) (*models.PendingAction, error) {
+	if err := transitionAction(ctx, action, models.ActionExecuting, map[string]interface{}{
+		"attempts": gorm.Expr("attempts + 1"),
+	}); err != nil {
+		return nil, err
+	}
+
+	deployErr := db.For(ctx).Transaction(func(tx *gorm.DB) error {
+		d, ok := deployers[action.Type]
+		if !ok {
+			log.Printf("[ACTIONS] No deployer for type %q, recording as manual", action.Type)
+			return nil
+		}
+		return d.Deploy(tx, action)
+	})
+
+	if deployErr != nil {
+		log.Printf("[ACTIONS] Deploy %d failed: %v", action.ID, deployErr)
+		if err := transitionAction(ctx, action, models.ActionFailed, map[string]interface{}{
+			"last_error": deployErr.Error(),
+		}); err != nil {
+			return nil, err
+		}
+		EmitEvent(ctx, "ACTIONS", "Deploy failed: "+action.Title, "ERROR")
+		return action, nil
+	}
+
+	now := time.Now()
+	if err := transitionAction(ctx, action, models.ActionExecuted, map[string]interface{}{
+		"executed_at": &now,
+		"last_error":  "",
+	}); err != nil {
+		return nil, err
+	}
+	EmitEvent(ctx, "BRAIN", "Action Deployed: "+action.Title, "SUCCESS")
+	return action, nil
+}
+
+func init() {
+	RegisterDeployer("Social_Post", DeployerFunc(func(tx *gorm.DB, a *models.PendingAction) error {
+		if a.RefID == "" {
+			return errors.New("social action has no linked post")
+		}
+		res := tx.Model(&models.SocialPost{}).Where("id = ?", a.RefID).
+			Updates(map[string]interface{}{"content": a.Content, "status": "scheduled"})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("social post %s not found", a.RefID)
+		}
+		return nil
+	}))
+
+	RegisterDeployer("Job_App", DeployerFunc(func(tx *gorm.DB, a *models.PendingAction) error {
+		title := "Follow up: " + a.Title
+		var job models.JobApplication
+		if a.RefID != "" && tx.First(&job, "id = ?", a.RefID).Error == nil {
+			title = fmt.Sprintf("Follow up on %s application at %s", job.Role, job.Company)
+		}
+		return tx.Create(&models.TaskRecord{
+			Title:   title,
+			Status:  "Pending",
+			DueDate: time.Now().AddDate(0, 0, 7),
+		}).Error
+	}))
+
+	RegisterDeployer("Venture_Plan", DeployerFunc(func(tx *gorm.DB, a *models.PendingAction) error {
+		if a.RefID == "" {
+			return errors.New("venture action has no linked venture")
+		}
+		res := tx.Model(&models.VentureCampaign{}).Where("id = ?", a.RefID).
+			Updates(map[string]interface{}{"strategy_summary": a.Content, "status": "Active"})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("venture %s not found", a.RefID)
+		}
+		return nil
+	}))
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gateway/db"
+	"gateway/models"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Price alerts are checked by the price_alerts task against live tickers,
+// replacing the old LLM heartbeat that was asked to "check crypto
+// volatility". A firing alert emits a SystemEvent and queues a Price_Alert
+// in the Action Center; approving or rejecting it only acknowledges it.
+
+const (
+	alertActionType      = "Price_Alert"
+	DefaultAlertCooldown = 60 // minutes
+)
+
+var alertKinds = map[string]bool{
+	models.AlertPriceAbove: true, models.AlertPriceBelow: true, models.AlertPercentChange: true,
+	models.AlertPortfolioAbove: true, models.AlertPortfolioBelow: true,
+}
+
+func isPortfolioAlert(kind string) bool {
+	return kind == models.AlertPortfolioAbove || kind == models.AlertPortfolioBelow
+}
+
+// ValidateAlert normalizes the asset and checks the alert is complete.
+func ValidateAlert(a *models.PriceAlert) error {
+	if !alertKinds[a.Kind] {
+		return fmt.Errorf("unknown kind %q", a.Kind)
+	}
+	a.Asset = ActiveExchange().NormalizeAsset(strings.TrimSpace(a.Asset))
+	if isPortfolioAlert(a.Kind) {
+		a.Asset = ""
+	} else if a.Asset == "" {
+		return errors.New("asset is required")
+	}
+	if !a.Threshold.IsPositive() {
+		return errors.New("threshold must be positive")
+	}
+	if a.Kind == models.AlertPercentChange && a.WindowMins <= 0 {
+		return errors.New("window_mins is required for percent_change")
+	}
+	if a.CooldownMins < 0 {
+		return errors.New("cooldown_mins cannot be negative")
+	}
+	if a.Name == "" {
+		a.Name = describeAlert(a)
+	}
+	return nil
+}
+
+func describeAlert(a *models.PriceAlert) string {
+	switch a.Kind {
+	case models.AlertPriceAbove:
+		return fmt.Sprintf("%s above $%s", a.Asset, a.Threshold)
+	case models.AlertPriceBelow:
+		return fmt.Sprintf("%s below $%s", a.Asset, a.Threshold)
+	case models.AlertPercentChange:
+		return fmt.Sprintf("%s moves %s%% in %dm", a.Asset, a.Threshold, a.WindowMins)
+	case models.AlertPortfolioAbove:
+		return fmt.Sprintf("Portfolio above $%s", a.Threshold)
+	default:
+		return fmt.Sprintf("Portfolio below $%s", a.Threshold)
+	}
+}
+
+// changeInterval picks the finest candle interval whose 720-candle window
+// still reaches back window minutes.
+func changeInterval(window int) int {
+	for _, iv := range []int{1, 5, 15, 30, 60, 240, 1440} {
+		if iv*chartWindow >= window {
+			return iv
+		}
+	}
+	return 10080
+}
+
+// alertPrices caches tickers and candles for the length of one check.
+type alertPrices struct {
+	prices map[string]decimal.Decimal
+}
+
+func (p *alertPrices) price(asset string) (decimal.Decimal, error) {
+	if v, ok := p.prices[asset]; ok {
+		return v, nil
+	}
+	v, err := ActiveExchange().USDPrice(asset)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	p.prices[asset] = v
+	return v, nil
+}
+
+// percentChange compares the price now with the close of the last candle
+// at least window minutes old.
+func (p *alertPrices) percentChange(asset string, window int) (decimal.Decimal, error) {
+	now, err := p.price(asset)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	candles, err := CachedCandles(ActiveExchange().USDPair(asset), changeInterval(window), 0)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	cutoff := time.Now().Add(-time.Duration(window) * time.Minute).Unix()
+	var ref decimal.Decimal
+	for _, c := range candles {
+		if c.Time > cutoff {
+			break
+		}
+		ref = c.Close
+	}
+	if ref.IsZero() {
+		return decimal.Zero, fmt.Errorf("no %s candle %dm back", asset, window)
+	}
+	return now.Sub(ref).Div(ref).Mul(decimal.NewFromInt(100)), nil
+}
+
+// portfolioValue marks the owner's synced balances to current tickers.
+func (p *alertPrices) portfolioValue(ctx context.Context) (decimal.Decimal, error) {
+	var holdings []models.CryptoHoldings
+	if err := db.For(ctx).Find(&holdings).Error; err != nil {
+		return decimal.Zero, err
+	}
+	total := decimal.Zero
+	for _, h := range holdings {
+		price, err := p.price(h.Asset)
+		if err != nil {
+			// Fall back to the value stored at the last sync.
+			total = total.Add(h.USDValue)
+			continue
+		}
+		total = total.Add(h.Balance.Mul(price))
+	}
+	return total, nil
+}
+
+// evaluate returns the alert's current value and whether its condition holds.
+func (p *alertPrices) evaluate(ctx context.Context, a *models.PriceAlert) (decimal.Decimal, bool, error) {
+	switch a.Kind {
+	case models.AlertPriceAbove, models.AlertPriceBelow:
+		v, err := p.price(a.Asset)
+		if err != nil {
+			return decimal.Zero, false, err
+		}
+		if a.Kind == models.AlertPriceAbove {
+			return v, v.GreaterThanOrEqual(a.Threshold), nil
+		}
+		return v, v.LessThanOrEqual(a.Threshold), nil
+	case models.AlertPercentChange:
+		v, err := p.percentChange(a.Asset, a.WindowMins)
+		if err != nil {
+			return decimal.Zero, false, err
+		}
+		return v, v.Abs().GreaterThanOrEqual(a.Threshold), nil
+	default:
+		v, err := p.portfolioValue(ctx)
+		if err != nil {
+			return decimal.Zero, false, err
+		}
+		if a.Kind == models.AlertPortfolioAbove {
+			return v, v.GreaterThanOrEqual(a.Threshold), nil
+		}
+		return v, v.LessThanOrEqual(a.Threshold), nil
+	}
+}
+
+func alertMessage(a *models.PriceAlert, value decimal.Decimal) string {
+	switch a.Kind {
+	case models.AlertPercentChange:
+		sign := ""
+		if !value.IsNegative() {
+			sign = "+"
+		}
+		return fmt.Sprintf("%s: %s moved %s%s%% in the last %dm", a.Name, a.Asset, sign, value.StringFixed(2), a.WindowMins)
+	case models.AlertPortfolioAbove, models.AlertPortfolioBelow:
+		return fmt.Sprintf("%s: portfolio is worth $%s", a.Name, value.StringFixed(2))
+	default:
+		return fmt.Sprintf("%s: %s is at $%s", a.Name, a.Asset, value.StringFixed(2))
+	}
+}
+
+// CheckAlerts evaluates every enabled alert visible to ctx and fires those
+// whose condition has started to hold. It backs the price_alerts task; run
+// unowned it checks every owner's alerts. Returns the alerts that fired.
+func CheckAlerts(ctx context.Context) ([]models.PriceAlert, error) {
+	var alerts []models.PriceAlert
+	if err := db.For(ctx).Where("enabled = ?", true).Order("id asc").Find(&alerts).Error; err != nil {
+		return nil, err
+	}
+
+	prices := &alertPrices{prices: map[string]decimal.Decimal{}}
+	var fired []models.PriceAlert
+	for i := range alerts {
+		a := &alerts[i]
+		actx := ctx
+		if a.OwnerID != nil {
+			actx = db.WithOwner(ctx, *a.OwnerID)
+		}
+
+		value, met, err := prices.evaluate(actx, a)
+		now := time.Now()
+		if err != nil {
+			log.Printf("[ALERTS] %s: %v", a.Name, err)
+			db.For(actx).Model(a).Update("last_checked", now)
+			continue
+		}
+		a.LastValue, a.LastChecked = value, &now
+
+		cooling := a.LastFiredAt != nil && now.Sub(*a.LastFiredAt) < time.Duration(a.CooldownMins)*time.Minute
+		switch {
+		case !met:
+			a.Triggered = false
+		case !a.Triggered && !cooling:
+			a.Triggered = true
+			a.LastFiredAt = &now
+			a.FireCount++
+			msg := alertMessage(a, value)
+			if err := mirrorToActionCenter(db.For(actx), alertActionType, fmt.Sprint(a.ID), "Alert: "+a.Name, msg); err != nil {
+				return fired, err
+			}
+			EmitEvent(actx, "ALERTS", msg, "WARN")
+			fired = append(fired, *a)
+		}
+		if err := db.For(actx).Save(a).Error; err != nil {
+			return fired, err
+		}
+	}
+	return fired, nil
+}
+
+func checkAlerts(ctx context.Context) (string, error) {
+	fired, err := CheckAlerts(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d alert(s) fired.", len(fired)), nil
+}
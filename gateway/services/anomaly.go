@@ -0,0 +1,304 @@
+package services
+
+import (
+	"fmt"
+	"gateway/models"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// New expenses are checked against the owner's history as they are
+// recorded, by the execute_record_expense tool and by statement imports.
+// Only history in the expense's own currency is compared, so no FX rate is
+// needed. Duplicates and spikes want a decision and are queued in the
+// Action Center; new merchants are only noted in the security audit log,
+// one entry per batch. Every comparison looks only at history before the
+// expense, so a backdated import is judged against what came before it.
+
+const (
+	AnomalyDuplicate   = "duplicate"      // same amount and merchant within duplicateWindow
+	AnomalySpike       = "category_spike" // far above the category's usual expense
+	AnomalyNewMerchant = "new_merchant"   // merchant not seen in merchantHistoryDays
+)
+
+const anomalyActionType = "Spending_Anomaly"
+
+const (
+	anomalyZ            = 3.0 // z-score of a spike
+	spikeRatio          = 2.0 // a spike is also at least this multiple of the mean
+	anomalyMinHistory   = 5   // expenses needed before spikes and new merchants count
+	anomalyHistoryDays  = 180
+	merchantHistoryDays = 365
+	duplicateWindow     = 3 * 24 * time.Hour
+)
+
+// Anomaly is one finding about a new expense.
+type Anomaly struct {
+	Kind        string          `json:"kind"`
+	RecordID    uuid.UUID       `json:"record_id"`
+	Category    string          `json:"category"`
+	Description string          `json:"description"`
+	Amount      decimal.Decimal `json:"amount"`
+	Currency    string          `json:"currency"`
+	Score       float64         `json:"score,omitempty"` // z-score of a spike
+	Explanation string          `json:"explanation"`
+}
+
+func (a *Anomaly) label() string {
+	if a.Description != "" {
+		return a.Description
+	}
+	return a.Category
+}
+
+// findDuplicate looks for an earlier expense with the same amount and
+// merchant within duplicateWindow, in the history or earlier in the batch.
+func findDuplicate(tx *gorm.DB, r *models.FinanceRecord, batch []models.FinanceRecord, ids []uuid.UUID) (*models.FinanceRecord, error) {
+	key := descriptionKey(r.Description)
+	same := func(o *models.FinanceRecord) bool {
+		if key == "" {
+			return strings.EqualFold(o.Category, r.Category)
+		}
+		return descriptionKey(o.Description) == key
+	}
+	for i := range batch {
+		o := &batch[i]
+		if o.Type == r.Type && o.Currency == r.Currency && o.Amount.Equal(r.Amount) &&
+			absDuration(o.CreatedAt.Sub(r.CreatedAt)) <= duplicateWindow && same(o) {
+			return o, nil
+		}
+	}
+
+	var candidates []models.FinanceRecord
+	err := tx.Where("type = ? AND currency = ? AND amount = ? AND created_at BETWEEN ? AND ? AND id NOT IN ?",
+		r.Type, r.Currency, r.Amount, r.CreatedAt.Add(-duplicateWindow), r.CreatedAt.Add(duplicateWindow), ids).
+		Order("created_at desc").
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range candidates {
+		if same(&candidates[i]) {
+			return &candidates[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// spikeScore compares r with the category's expenses in the same currency
+// in the anomalyHistoryDays before it. ok is false when there is too little
+// history to judge.
+func spikeScore(tx *gorm.DB, r *models.FinanceRecord, ids []uuid.UUID) (z, ratio float64, mean decimal.Decimal, n int, ok bool, err error) {
+	var amounts []decimal.Decimal
+	err = tx.Model(&models.FinanceRecord{}).
+		Where("type = ? AND currency = ? AND lower(category) = ? AND created_at >= ? AND created_at < ? AND id NOT IN ?",
+			"expense", r.Currency, categoryKey(r.Category), r.CreatedAt.AddDate(0, 0, -anomalyHistoryDays), r.CreatedAt, ids).
+		Pluck("amount", &amounts).Error
+	if err != nil || len(amounts) < anomalyMinHistory {
+		return 0, 0, decimal.Zero, len(amounts), false, err
+	}
+
+	total := decimal.Zero
+	for _, a := range amounts {
+		total = total.Add(a)
+	}
+	mean = total.Div(decimal.NewFromInt(int64(len(amounts))))
+	if !mean.IsPositive() {
+		return 0, 0, mean, len(amounts), false, nil
+	}
+	var variance float64
+	for _, a := range amounts {
+		d := a.Sub(mean).InexactFloat64()
+		variance += d * d
+	}
+	sd := math.Sqrt(variance / float64(len(amounts)))
+	ratio = r.Amount.Div(mean).InexactFloat64()
+	if sd > 0 {
+		z = r.Amount.Sub(mean).InexactFloat64() / sd
+	} else if ratio > 1 {
+		z = math.Inf(1)
+	}
+	return z, ratio, mean, len(amounts), true, nil
+}
+
+// merchantHistory is when expenses outside the batch were made, oldest
+// first, overall and per description key.
+type merchantHistory struct {
+	all  []time.Time
+	seen map[string][]time.Time
+}
+
+// knownMerchants loads the expenses outside the batch that fall in the
+// merchantHistoryDays before any of its records.
+func knownMerchants(tx *gorm.DB, records []models.FinanceRecord, ids []uuid.UUID) (*merchantHistory, error) {
+	from, to := records[0].CreatedAt, records[0].CreatedAt
+	for _, r := range records[1:] {
+		if r.CreatedAt.Before(from) {
+			from = r.CreatedAt
+		}
+		if r.CreatedAt.After(to) {
+			to = r.CreatedAt
+		}
+	}
+	var rows []struct {
+		Description string
+		CreatedAt   time.Time
+	}
+	err := tx.Model(&models.FinanceRecord{}).
+		Select("description, created_at").
+		Where("type = ? AND created_at >= ? AND created_at < ? AND id NOT IN ?", "expense", from.AddDate(0, 0, -merchantHistoryDays), to, ids).
+		Order("created_at asc").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	h := &merchantHistory{seen: map[string][]time.Time{}}
+	for _, row := range rows {
+		h.all = append(h.all, row.CreatedAt)
+		key := descriptionKey(row.Description)
+		h.seen[key] = append(h.seen[key], row.CreatedAt)
+	}
+	return h, nil
+}
+
+// known reports whether key was spent at in the merchantHistoryDays before
+// at. ok is false while that window holds too few expenses to say what is
+// new.
+func (h *merchantHistory) known(key string, at time.Time) (known, ok bool) {
+	from := at.AddDate(0, 0, -merchantHistoryDays)
+	within := func(times []time.Time) int {
+		lo := sort.Search(len(times), func(i int) bool { return !times[i].Before(from) })
+		hi := sort.Search(len(times), func(i int) bool { return !times[i].Before(at) })
+		return hi - lo
+	}
+	if within(h.all) < anomalyMinHistory {
+		return false, false
+	}
+	return within(h.seen[key]) > 0, true
+}
+
+// detectAnomalies checks newly created records against the history
+// visible to tx. Income is not checked.
+func detectAnomalies(tx *gorm.DB, records []models.FinanceRecord) ([]Anomaly, error) {
+	if len(records) == 0 {
+		return nil, nil
+	}
+	ids := make([]uuid.UUID, len(records))
+	for i, r := range records {
+		ids[i] = r.ID
+	}
+	history, err := knownMerchants(tx, records, ids)
+	if err != nil {
+		return nil, err
+	}
+	inBatch := map[string]bool{}
+
+	var found []Anomaly
+	for i := range records {
+		r := &records[i]
+		if r.Type != "expense" {
+			continue
+		}
+		base := Anomaly{RecordID: r.ID, Category: r.Category, Description: r.Description, Amount: r.Amount, Currency: r.Currency}
+		amount := FormatMoney(r.Amount, r.Currency)
+
+		dup, err := findDuplicate(tx, r, records[:i], ids)
+		if err != nil {
+			return nil, err
+		}
+		if dup != nil {
+			a := base
+			a.Kind = AnomalyDuplicate
+			a.Explanation = fmt.Sprintf("%s of %s on %s matches one on %s; possible duplicate charge.",
+				a.label(), amount, r.CreatedAt.Format("2006-01-02"), dup.CreatedAt.Format("2006-01-02"))
+			found = append(found, a)
+		}
+
+		z, ratio, mean, n, ok, err := spikeScore(tx, r, ids)
+		if err != nil {
+			return nil, err
+		}
+		if ok && z >= anomalyZ && ratio >= spikeRatio {
+			a := base
+			a.Kind = AnomalySpike
+			a.Explanation = fmt.Sprintf("%s of %s in %s is %.1fx the usual %s", a.label(), amount, r.Category, ratio, FormatMoney(mean, r.Currency))
+			if math.IsInf(z, 1) {
+				a.Explanation += fmt.Sprintf(" (all %d expenses in the last %d days were the same).", n, anomalyHistoryDays)
+			} else {
+				a.Score = math.Round(z*100) / 100
+				a.Explanation += fmt.Sprintf(" (z-score %.1f over %d expenses in the last %d days).", z, n, anomalyHistoryDays)
+			}
+			found = append(found, a)
+		}
+
+		if key := descriptionKey(r.Description); key != "" {
+			if known, ok := history.known(key, r.CreatedAt); ok && !known && !inBatch[key] {
+				a := base
+				a.Kind = AnomalyNewMerchant
+				a.Explanation = fmt.Sprintf("First expense at %s (%s) in %d days.", r.Description, amount, merchantHistoryDays)
+				found = append(found, a)
+			}
+			inBatch[key] = true // later rows of the batch are no longer new
+		}
+	}
+	return found, nil
+}
+
+// recordAnomalies files duplicates and spikes in the Action Center with a
+// warning event, and the batch's new merchants in one security audit entry.
+func recordAnomalies(tx *gorm.DB, found []Anomaly) error {
+	var merchants []string
+	for _, a := range found {
+		switch a.Kind {
+		case AnomalyNewMerchant:
+			merchants = append(merchants, fmt.Sprintf("%s (%s)", a.Description, FormatMoney(a.Amount, a.Currency)))
+		default:
+			title := "Unusual spending: " + a.Category
+			if a.Kind == AnomalyDuplicate {
+				title = "Possible duplicate: " + a.label()
+			}
+			EmitEvent(tx.Statement.Context, "ANOMALY", a.Explanation, "WARN")
+			if err := mirrorToActionCenter(tx, anomalyActionType, a.RecordID.String(), title, a.Explanation); err != nil {
+				return err
+			}
+		}
+	}
+	if len(merchants) > 0 {
+		issue := fmt.Sprintf("First expense at %s in %d days.", merchants[0], merchantHistoryDays)
+		if len(merchants) > 1 {
+			issue = fmt.Sprintf("%d merchants not seen in %d days: %s.", len(merchants), merchantHistoryDays, strings.Join(merchants, "; "))
+		}
+		audit := models.SecurityAudit{Issue: issue, Severity: "Low", Status: "Open"}
+		if err := tx.Create(&audit).Error; err != nil {
+			return fmt.Errorf("log new merchants: %w", err)
+		}
+	}
+	if len(found) > 0 {
+		log.Printf("[ANOMALY] %d finding(s)", len(found))
+	}
+	return nil
+}
+
+// checkSpendingAnomalies detects and files anomalies in newly created
+// records.
+func checkSpendingAnomalies(tx *gorm.DB, records []models.FinanceRecord) ([]Anomaly, error) {
+	found, err := detectAnomalies(tx, records)
+	if err != nil {
+		return nil, err
+	}
+	return found, recordAnomalies(tx, found)
+}
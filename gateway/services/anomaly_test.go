@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"gateway/db"
+	"gateway/models"
+	"math"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// anomalyTx is a recording transaction whose finance_records queries are
+// answered by rows.
+func anomalyTx(t *testing.T, cols []string, rows [][]driver.Value) (*gorm.DB, *recordingDB) {
+	t.Helper()
+	rec := useRecordingDB(t, func(query string, _ []driver.Value) ([]string, [][]driver.Value) {
+		if !strings.Contains(query, `"finance_records"`) {
+			return nil, nil
+		}
+		return cols, rows
+	})
+	return db.For(db.WithOwner(context.Background(), uuid.New())), rec
+}
+
+func expenseAt(at time.Time, amount, category, description string) models.FinanceRecord {
+	r := models.FinanceRecord{Type: "expense", Currency: "USD", Amount: dec(amount), Category: category, Description: description}
+	r.ID, r.CreatedAt = uuid.New(), at
+	return r
+}
+
+func TestSpikeScore(t *testing.T) {
+	tests := []struct {
+		name    string
+		history []string
+		amount  string
+		ok      bool
+		z       float64
+		ratio   float64
+		mean    string
+	}{
+		{"too little history", []string{"10", "20", "10", "20"}, "100", false, 0, 0, "0"},
+		{"spread history", []string{"10", "20", "10", "20", "10", "20"}, "40", true, 5, 40.0 / 15, "15"},
+		{"at the mean", []string{"10", "20", "10", "20", "10", "20"}, "15", true, 0, 1, "15"},
+		{"below the mean", []string{"10", "20", "10", "20", "10", "20"}, "5", true, -2, 1.0 / 3, "15"},
+		{"identical history", []string{"12", "12", "12", "12", "12"}, "36", true, math.Inf(1), 3, "12"},
+		{"identical history, not above", []string{"12", "12", "12", "12", "12"}, "12", true, 0, 1, "12"},
+		{"refunds leave no positive mean", []string{"0", "0", "0", "0", "0"}, "10", false, 0, 0, "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vals := make([][]driver.Value, len(tt.history))
+			for i, a := range tt.history {
+				vals[i] = []driver.Value{a}
+			}
+			tx, rec := anomalyTx(t, []string{"amount"}, vals)
+			r := expenseAt(day("2026-06-01"), tt.amount, " Groceries ", "Market")
+
+			z, ratio, mean, n, ok, err := spikeScore(tx, &r, []uuid.UUID{r.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.ok || n != len(tt.history) {
+				t.Fatalf("ok = %v over %d, want %v over %d", ok, n, tt.ok, len(tt.history))
+			}
+			if ok && (math.Abs(z-tt.z) > 1e-9 && !(math.IsInf(z, 1) && math.IsInf(tt.z, 1)) ||
+				math.Abs(ratio-tt.ratio) > 1e-9 || !mean.Equal(dec(tt.mean))) {
+				t.Errorf("z %v, ratio %v, mean %s, want %v, %v, %s", z, ratio, mean, tt.z, tt.ratio, tt.mean)
+			}
+
+			// History is the category's, whatever its case and spacing.
+			q := rec.touching("finance_records")
+			if len(q) != 1 || !hasArg(q[0].Args, "groceries") {
+				t.Errorf("history query = %+v, want one for category groceries", q)
+			}
+		})
+	}
+}
+
+func TestFindDuplicate(t *testing.T) {
+	at := day("2026-06-10")
+	tests := []struct {
+		name    string
+		r       models.FinanceRecord
+		batch   []models.FinanceRecord
+		history []models.FinanceRecord
+		want    string // description of the duplicate found, "" for none
+	}{
+		{"earlier in the batch", expenseAt(at, "9.99", "Fun", "STREAMCO 0610"),
+			[]models.FinanceRecord{expenseAt(at.Add(-time.Hour), "9.99", "Other", "Streamco 0609")}, nil, "Streamco 0609"},
+		{"batch outside the window", expenseAt(at, "9.99", "Fun", "STREAMCO"),
+			[]models.FinanceRecord{expenseAt(at.Add(-4*24*time.Hour), "9.99", "Fun", "STREAMCO")}, nil, ""},
+		{"batch in another currency", expenseAt(at, "9.99", "Fun", "STREAMCO"),
+			[]models.FinanceRecord{func() models.FinanceRecord {
+				o := expenseAt(at, "9.99", "Fun", "STREAMCO")
+				o.Currency = "EUR"
+				return o
+			}()}, nil, ""},
+		{"batch with another amount", expenseAt(at, "9.99", "Fun", "STREAMCO"),
+			[]models.FinanceRecord{expenseAt(at, "10.99", "Fun", "STREAMCO")}, nil, ""},
+		{"no description falls back to the category", expenseAt(at, "20", "Fuel", "1234"),
+			[]models.FinanceRecord{expenseAt(at, "20", "fuel", "#5678")}, nil, "#5678"},
+		{"in the history", expenseAt(at, "9.99", "Fun", "STREAMCO"), nil,
+			[]models.FinanceRecord{expenseAt(at.Add(-time.Hour), "9.99", "Fun", "Gym"), expenseAt(at.Add(-2*time.Hour), "9.99", "Fun", "streamco")}, "streamco"},
+		{"history at another merchant", expenseAt(at, "9.99", "Fun", "STREAMCO"), nil,
+			[]models.FinanceRecord{expenseAt(at.Add(-time.Hour), "9.99", "Fun", "Gym")}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cols := []string{"id", "created_at", "amount", "currency", "category", "description", "type"}
+			var vals [][]driver.Value
+			for _, h := range tt.history {
+				vals = append(vals, []driver.Value{h.ID.String(), h.CreatedAt, h.Amount.String(), h.Currency, h.Category, h.Description, h.Type})
+			}
+			tx, _ := anomalyTx(t, cols, vals)
+
+			got, err := findDuplicate(tx, &tt.r, tt.batch, []uuid.UUID{tt.r.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got == nil && tt.want != "" || got != nil && got.Description != tt.want {
+				t.Fatalf("duplicate = %+v, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerchantHistoryKnown(t *testing.T) {
+	at := day("2026-06-01")
+	// history has five expenses a month apart before at, one at "market"
+	// and the rest at "gym", plus the extra ones.
+	history := func(extra map[string]time.Time) *merchantHistory {
+		h := &merchantHistory{seen: map[string][]time.Time{}}
+		add := func(key string, t time.Time) {
+			h.all = append(h.all, t)
+			h.seen[key] = append(h.seen[key], t)
+		}
+		for i := 5; i >= 1; i-- {
+			key := "gym"
+			if i == 3 {
+				key = "market"
+			}
+			add(key, at.AddDate(0, -i, 0))
+		}
+		for key, t := range extra {
+			add(key, t)
+		}
+		byTime := func(ts []time.Time) {
+			sort.Slice(ts, func(i, j int) bool { return ts[i].Before(ts[j]) })
+		}
+		byTime(h.all)
+		for _, ts := range h.seen {
+			byTime(ts)
+		}
+		return h
+	}
+
+	tests := []struct {
+		name      string
+		h         *merchantHistory
+		key       string
+		at        time.Time
+		known, ok bool
+	}{
+		{"seen", history(nil), "market", at, true, true},
+		{"never seen", history(nil), "bakery", at, false, true},
+		{"seen only longer ago than the window", history(map[string]time.Time{"bakery": at.AddDate(-2, 0, 0)}), "bakery", at, false, true},
+		{"seen only afterwards", history(map[string]time.Time{"bakery": at.AddDate(0, 0, 1)}), "bakery", at, false, true},
+		{"too little history before at", history(nil), "market", at.AddDate(0, -2, 0), false, false},
+		{"history aged out of the window", history(nil), "gym", at.AddDate(1, 0, 0), false, false},
+		{"empty", &merchantHistory{seen: map[string][]time.Time{}}, "market", at, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			known, ok := tt.h.known(tt.key, tt.at)
+			if known != tt.known || ok != tt.ok {
+				t.Fatalf("known, ok = %v, %v, want %v, %v", known, ok, tt.known, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gateway/db"
+	"gateway/models"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	apiKeyPrefix  = "sqk_"
+	sessionPrefix = "sqs_"
+	sessionTTL    = 30 * 24 * time.Hour
+)
+
+var (
+	ErrUnauthorized      = errors.New("unauthorized")
+	ErrSignupClosed      = errors.New("signup is closed")
+	ErrEmailTaken        = errors.New("email already registered")
+	ErrInvalidCredential = errors.New("invalid email or password")
+)
+
+func newToken(prefix string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// registerLock serializes sign-ups so only one can be the first user.
+const registerLock = 7340101
+
+// Register creates a user. The first user becomes admin and inherits every
+// row created before authentication existed. Later users need
+// ALLOW_SIGNUP=true.
+func Register(email, name, password string) (*models.User, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" || len(password) < 8 {
+		return nil, errors.New("email and a password of at least 8 characters are required")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user := models.User{
+		Email:        email,
+		Name:         name,
+		PasswordHash: string(hash),
+		Role:         models.RoleMember,
+	}
+
+	err = db.Instance.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", registerLock).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.User{}).Count(&count).Error; err != nil {
+			return err
+		}
+		first := count == 0
+		if !first && os.Getenv("ALLOW_SIGNUP") != "true" {
+			return ErrSignupClosed
+		}
+
+		var existing int64
+		tx.Model(&models.User{}).Where("email = ?", email).Count(&existing)
+		if existing > 0 {
+			return ErrEmailTaken
+		}
+
+		if first {
+			user.Role = models.RoleAdmin
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		if first {
+			if err := db.ClaimUnowned(tx, user.ID); err != nil {
+				return fmt.Errorf("claim legacy data: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Login checks a password and issues a session token.
+func Login(email, password string) (string, *models.UserSession, error) {
+	var user models.User
+	if err := db.Instance.Where("email = ?", strings.ToLower(strings.TrimSpace(email))).First(&user).Error; err != nil {
+		return "", nil, ErrInvalidCredential
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return "", nil, ErrInvalidCredential
+	}
+
+	token, err := newToken(sessionPrefix)
+	if err != nil {
+		return "", nil, err
+	}
+	session := models.UserSession{
+		UserID:    user.ID,
+		Hash:      hashToken(token),
+		ExpiresAt: time.Now().Add(sessionTTL),
+	}
+	if err := db.Instance.Create(&session).Error; err != nil {
+		return "", nil, err
+	}
+	return token, &session, nil
+}
+
+func Logout(token string) {
+	db.Instance.Where("hash = ?", hashToken(token)).Delete(&models.UserSession{})
+}
+
+// CreateAPIKey returns the plaintext key once; only its hash is kept.
+func CreateAPIKey(userID uuid.UUID, name string) (string, *models.APIKey, error) {
+	token, err := newToken(apiKeyPrefix)
+	if err != nil {
+		return "", nil, err
+	}
+	key := models.APIKey{
+		UserID: userID,
+		Name:   name,
+		Prefix: token[:len(apiKeyPrefix)+6],
+		Hash:   hashToken(token),
+	}
+	if err := db.Instance.Create(&key).Error; err != nil {
+		return "", nil, err
+	}
+	return token, &key, nil
+}
+
+// Authenticate resolves a session token or API key to its user.
+func Authenticate(token string) (*models.User, error) {
+	var userID uuid.UUID
+	hash := hashToken(token)
+
+	switch {
+	case strings.HasPrefix(token, sessionPrefix):
+		var session models.UserSession
+		if err := db.Instance.Where("hash = ? AND expires_at > ?", hash, time.Now()).First(&session).Error; err != nil {
+			return nil, ErrUnauthorized
+		}
+		userID = session.UserID
+
+	case strings.HasPrefix(token, apiKeyPrefix):
+		var key models.APIKey
+		if err := db.Instance.Where("hash = ?", hash).First(&key).Error; err != nil {
+			return nil, ErrUnauthorized
+		}
+		db.Instance.Model(&key).Update("last_used_at", time.Now())
+		userID = key.UserID
+
+	default:
+		return nil, ErrUnauthorized
+	}
+
+	var user models.User
+	if err := db.Instance.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, ErrUnauthorized
+	}
+	return &user, nil
+}
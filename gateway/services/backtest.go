@@ -0,0 +1,389 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gateway/db"
+	"gateway/models"
+	"log"
+	"math"
+	"sort"
+	"time"
+)
+
+// Backtesting replays a strategy over stored candles. The strategy is either
+// the owner's stored TradingSignals for the pair or a rule from the request.
+// Simulation is long-only: BUY opens a position with PositionSize of equity
+// at the next candle's open, SELL closes it. Results are statistics on
+// hypothetical money, so the simulation reads candle prices as floats.
+
+var ErrNoCandles = errors.New("no candles stored for that range")
+
+// BacktestRule is a request-defined strategy.
+//
+//	{"type": "sma_cross", "fast": 10, "slow": 30}
+//	{"type": "rsi", "period": 14, "buy_below": 30, "sell_above": 70}
+type BacktestRule struct {
+	Type      string  `json:"type"`
+	Fast      int     `json:"fast"`
+	Slow      int     `json:"slow"`
+	Period    int     `json:"period"`
+	BuyBelow  float64 `json:"buy_below"`
+	SellAbove float64 `json:"sell_above"`
+}
+
+type BacktestRequest struct {
+	Pair         string        `json:"pair"`
+	Interval     int           `json:"interval"` // minutes
+	From         time.Time     `json:"from"`
+	To           time.Time     `json:"to"`
+	StartingCash float64       `json:"starting_cash"`
+	PositionSize float64       `json:"position_size"` // fraction of equity per entry, 0-1
+	FeePct       float64       `json:"fee_pct"`       // per fill, in percent
+	Source       string        `json:"source"`        // "signals" or "rule"
+	Rule         *BacktestRule `json:"rule"`
+}
+
+type BacktestTrade struct {
+	EntryTime  time.Time `json:"entry_time"`
+	EntryPrice float64   `json:"entry_price"`
+	ExitTime   time.Time `json:"exit_time"`
+	ExitPrice  float64   `json:"exit_price"`
+	Quantity   float64   `json:"quantity"`
+	Fees       float64   `json:"fees"`
+	PnL        float64   `json:"pnl"`
+	ReturnPct  float64   `json:"return_pct"`
+	Open       bool      `json:"open"` // still held at the end; marked at the last close
+}
+
+type EquityPoint struct {
+	Time   time.Time `json:"time"`
+	Equity float64   `json:"equity"`
+}
+
+type BacktestResult struct {
+	Pair           string          `json:"pair"`
+	Interval       int             `json:"interval"`
+	From           time.Time       `json:"from"`
+	To             time.Time       `json:"to"`
+	Candles        int             `json:"candles"`
+	StartingCash   float64         `json:"starting_cash"`
+	FinalEquity    float64         `json:"final_equity"`
+	TotalReturnPct float64         `json:"total_return_pct"`
+	BuyHoldPct     float64         `json:"buy_and_hold_pct"`
+	MaxDrawdownPct float64         `json:"max_drawdown_pct"`
+	Sharpe         float64         `json:"sharpe"`
+	WinRate        float64         `json:"win_rate"`
+	Trades         []BacktestTrade `json:"trades"`
+	EquityCurve    []EquityPoint   `json:"equity_curve"`
+}
+
+func (r *BacktestRequest) normalize() error {
+	if r.Pair == "" {
+		return errors.New("pair is required")
+	}
+	if r.Interval == 0 {
+		r.Interval = 60
+	}
+	if !ValidInterval(r.Interval) {
+		return fmt.Errorf("unsupported interval %d", r.Interval)
+	}
+	if r.StartingCash <= 0 {
+		r.StartingCash = 10000
+	}
+	if r.PositionSize <= 0 || r.PositionSize > 1 {
+		r.PositionSize = 1
+	}
+	if r.Source == "" {
+		r.Source = "signals"
+		if r.Rule != nil {
+			r.Source = "rule"
+		}
+	}
+	switch r.Source {
+	case "signals":
+	case "rule":
+		if r.Rule == nil {
+			return errors.New("rule is required when source is rule")
+		}
+		return r.Rule.normalize()
+	default:
+		return fmt.Errorf("unknown source %q", r.Source)
+	}
+	return nil
+}
+
+func (r *BacktestRule) normalize() error {
+	switch r.Type {
+	case "sma_cross":
+		if r.Fast <= 0 {
+			r.Fast = 10
+		}
+		if r.Slow <= 0 {
+			r.Slow = 30
+		}
+		if r.Fast >= r.Slow {
+			return errors.New("sma_cross needs fast < slow")
+		}
+	case "rsi":
+		if r.Period <= 0 {
+			r.Period = 14
+		}
+		if r.BuyBelow <= 0 {
+			r.BuyBelow = 30
+		}
+		if r.SellAbove <= 0 {
+			r.SellAbove = 70
+		}
+	default:
+		return fmt.Errorf("unknown rule type %q", r.Type)
+	}
+	return nil
+}
+
+// ruleSignals returns the action decided at the close of each candle, keyed
+// by candle index. It only ever looks at candles up to that index.
+func ruleSignals(rule *BacktestRule, candles []models.Candle) map[int]string {
+	closes := make([]float64, len(candles))
+	for i, c := range candles {
+		closes[i] = c.Close.InexactFloat64()
+	}
+
+	out := map[int]string{}
+	switch rule.Type {
+	case "sma_cross":
+		fast, slow := sma(closes, rule.Fast), sma(closes, rule.Slow)
+		for i := rule.Slow; i < len(closes); i++ {
+			above, wasAbove := fast[i] > slow[i], fast[i-1] > slow[i-1]
+			switch {
+			case above && !wasAbove:
+				out[i] = "BUY"
+			case !above && wasAbove:
+				out[i] = "SELL"
+			}
+		}
+	case "rsi":
+		values := rsi(closes, rule.Period)
+		for i := rule.Period; i < len(closes); i++ {
+			switch {
+			case values[i] < rule.BuyBelow:
+				out[i] = "BUY"
+			case values[i] > rule.SellAbove:
+				out[i] = "SELL"
+			}
+		}
+	}
+	return out
+}
+
+func sma(xs []float64, n int) []float64 {
+	out := make([]float64, len(xs))
+	var sum float64
+	for i, x := range xs {
+		sum += x
+		if i >= n {
+			sum -= xs[i-n]
+		}
+		if i >= n-1 {
+			out[i] = sum / float64(n)
+		}
+	}
+	return out
+}
+
+// rsi uses Wilder's smoothing.
+func rsi(xs []float64, n int) []float64 {
+	out := make([]float64, len(xs))
+	if len(xs) <= n {
+		return out
+	}
+	var gain, loss float64
+	for i := 1; i <= n; i++ {
+		if d := xs[i] - xs[i-1]; d > 0 {
+			gain += d
+		} else {
+			loss -= d
+		}
+	}
+	gain, loss = gain/float64(n), loss/float64(n)
+	for i := n; i < len(xs); i++ {
+		if i > n {
+			d := xs[i] - xs[i-1]
+			gain = (gain*float64(n-1) + math.Max(d, 0)) / float64(n)
+			loss = (loss*float64(n-1) + math.Max(-d, 0)) / float64(n)
+		}
+		if loss == 0 {
+			out[i] = 100
+		} else {
+			out[i] = 100 - 100/(1+gain/loss)
+		}
+	}
+	return out
+}
+
+// storedSignals maps the owner's signals for the pair onto the candle in
+// which each was created.
+func storedSignals(ctx context.Context, pair string, candles []models.Candle) map[int]string {
+	var signals []models.TradingSignal
+	end := candles[len(candles)-1].Time.Add(time.Duration(candles[0].Interval) * time.Minute)
+	db.For(ctx).Where("asset = ? AND action IN ? AND created_at >= ? AND created_at < ?",
+		pair, []string{"BUY", "SELL"}, candles[0].Time, end).
+		Order("created_at asc").Find(&signals)
+
+	out := map[int]string{}
+	for _, s := range signals {
+		i := sort.Search(len(candles), func(i int) bool { return candles[i].Time.After(s.CreatedAt) }) - 1
+		if i >= 0 {
+			out[i] = s.Action
+		}
+	}
+	return out
+}
+
+// RunBacktest ingests any missing candles for the pair, then simulates the
+// requested strategy over the stored range.
+func RunBacktest(ctx context.Context, req BacktestRequest) (*BacktestResult, error) {
+	if err := req.normalize(); err != nil {
+		return nil, err
+	}
+	if _, err := IngestCandles(req.Pair, req.Interval); err != nil {
+		// Stored history may still cover the range.
+		log.Printf("[BACKTEST] Ingest %s/%d: %v", req.Pair, req.Interval, err)
+	}
+
+	candles, err := LoadCandles(req.Pair, req.Interval, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	if len(candles) < 2 {
+		return nil, ErrNoCandles
+	}
+
+	var decisions map[int]string
+	if req.Source == "rule" {
+		decisions = ruleSignals(req.Rule, candles)
+	} else {
+		decisions = storedSignals(ctx, req.Pair, candles)
+	}
+	return simulate(req, candles, decisions), nil
+}
+
+// simulate fills a decision made during candle i at the open of candle i+1,
+// so no strategy trades on a price it could not have seen.
+func simulate(req BacktestRequest, candles []models.Candle, decisions map[int]string) *BacktestResult {
+	fee := req.FeePct / 100
+	cash := req.StartingCash
+	var qty float64
+	var open *BacktestTrade
+
+	res := &BacktestResult{
+		Pair:         req.Pair,
+		Interval:     req.Interval,
+		From:         candles[0].Time,
+		To:           candles[len(candles)-1].Time,
+		Candles:      len(candles),
+		StartingCash: req.StartingCash,
+		Trades:       []BacktestTrade{},
+		EquityCurve:  make([]EquityPoint, 0, len(candles)),
+	}
+
+	for i, c := range candles {
+		price := c.Open.InexactFloat64()
+		switch decisions[i-1] {
+		case "BUY":
+			if open == nil {
+				spend := cash * req.PositionSize
+				cost := spend * fee
+				qty = (spend - cost) / price
+				cash -= spend
+				open = &BacktestTrade{EntryTime: c.Time, EntryPrice: price, Quantity: qty, Fees: cost}
+			}
+		case "SELL":
+			if open != nil {
+				cash += closeTrade(open, c.Time, price, fee)
+				res.Trades = append(res.Trades, *open)
+				open, qty = nil, 0
+			}
+		}
+		res.EquityCurve = append(res.EquityCurve, EquityPoint{Time: c.Time, Equity: cash + qty*c.Close.InexactFloat64()})
+	}
+
+	last := candles[len(candles)-1]
+	lastClose, firstOpen := last.Close.InexactFloat64(), candles[0].Open.InexactFloat64()
+	if open != nil {
+		open.Open = true
+		cash += closeTrade(open, last.Time, lastClose, 0)
+		res.Trades = append(res.Trades, *open)
+	}
+
+	res.FinalEquity = cash
+	res.TotalReturnPct = (cash - req.StartingCash) / req.StartingCash * 100
+	res.BuyHoldPct = (lastClose - firstOpen) / firstOpen * 100
+	res.MaxDrawdownPct = maxDrawdown(res.EquityCurve)
+	res.Sharpe = sharpe(res.EquityCurve, req.Interval)
+
+	wins := 0
+	for _, t := range res.Trades {
+		if t.PnL > 0 {
+			wins++
+		}
+	}
+	if len(res.Trades) > 0 {
+		res.WinRate = float64(wins) / float64(len(res.Trades))
+	}
+	return res
+}
+
+// closeTrade exits t at price and returns the cash received.
+func closeTrade(t *BacktestTrade, at time.Time, price, fee float64) float64 {
+	spent := t.Quantity*t.EntryPrice + t.Fees
+	gross := t.Quantity * price
+	cost := gross * fee
+	t.ExitTime, t.ExitPrice = at, price
+	t.Fees += cost
+	t.PnL = gross - cost - spent
+	if spent > 0 {
+		t.ReturnPct = t.PnL / spent * 100
+	}
+	return gross - cost
+}
+
+func maxDrawdown(curve []EquityPoint) float64 {
+	var peak, worst float64
+	for _, p := range curve {
+		peak = math.Max(peak, p.Equity)
+		if peak > 0 {
+			worst = math.Max(worst, (peak-p.Equity)/peak*100)
+		}
+	}
+	return worst
+}
+
+// sharpe annualises per-candle returns with a zero risk-free rate.
+func sharpe(curve []EquityPoint, interval int) float64 {
+	if len(curve) < 3 {
+		return 0
+	}
+	returns := make([]float64, 0, len(curve)-1)
+	for i := 1; i < len(curve); i++ {
+		if prev := curve[i-1].Equity; prev > 0 {
+			returns = append(returns, curve[i].Equity/prev-1)
+		}
+	}
+	var mean float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	std := math.Sqrt(variance / float64(len(returns)-1))
+	if std == 0 {
+		return 0
+	}
+	periodsPerYear := 365 * 24 * 60 / float64(interval)
+	return mean / std * math.Sqrt(periodsPerYear)
+}
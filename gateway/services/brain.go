@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"gateway/models"
@@ -11,13 +12,28 @@ import (
 	"time"
 )
 
+// BrainResponse carries either a single Action/Data pair or an ordered list
+// of Steps. When Steps is present, Action and Data are ignored.
 type BrainResponse struct {
 	Message  string                 `json:"message"`
 	Action   string                 `json:"action"`
 	Data     map[string]interface{} `json:"data"`
+	Steps    []ToolCall             `json:"steps,omitempty"`
+	Results  []StepResult           `json:"results,omitempty"`
 	AudioURL string                 `json:"audio_url"`
 }
 
+// Plan normalises the response into an ordered list of tool calls.
+func (r *BrainResponse) Plan() []ToolCall {
+	if len(r.Steps) > 0 {
+		return r.Steps
+	}
+	if r.Action != "" {
+		return []ToolCall{{Action: r.Action, Data: r.Data}}
+	}
+	return nil
+}
+
 var brainHTTPClient = &http.Client{Timeout: 60 * time.Second}
 
 func brainURL() string {
@@ -31,31 +47,61 @@ func RequestIntent(
 	userID, sessionID, query, filePath string,
 	history []models.ChatHistory,
 ) (*BrainResponse, error) {
+	return postIntent(context.Background(), intentBody(userID, sessionID, query, filePath, history))
+}
+
+func intentBody(
+	userID, sessionID, query, filePath string,
+	history []models.ChatHistory,
+) map[string]interface{} {
 	msgs := make([]map[string]string, len(history))
 
 	for i, h := range history {
 		msgs[i] = map[string]string{"role": h.Role, "text": h.Text}
 	}
 
-	payload, err := json.Marshal(map[string]interface{}{
+	return map[string]interface{}{
 		"user_id":    userID,
 		"session_id": sessionID,
 		"query":      query,
 		"file_path":  filePath,
 		"history":    msgs,
+	}
+}
+
+// RequestAgentIntent sends a history-less query routed to a specific agent
+// slug. An empty agent lets the Brain's router decide.
+func RequestAgentIntent(agent, userID, sessionID, query string) (*BrainResponse, error) {
+	return postIntent(context.Background(), map[string]interface{}{
+		"user_id":    userID,
+		"session_id": sessionID,
+		"query":      query,
+		"agent":      agent,
+		"history":    []map[string]string{},
 	})
+}
+
+// postIntent calls the blocking endpoint. Cancelling ctx aborts the
+// request.
+func postIntent(ctx context.Context, body map[string]interface{}) (*BrainResponse, error) {
+	payload, err := json.Marshal(body)
 
 	if err != nil {
 		return nil, fmt.Errorf("marshal payload: %w", err)
 	}
 
-	log.Printf("[BRAIN] Intent session=%s file=%s", sessionID, filePath)
+	log.Printf("[BRAIN] Intent session=%v agent=%v", body["session_id"], body["agent"])
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		brainURL()+"/brain/v1/process_intent", bytes.NewBuffer(payload))
+
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := brainHTTPClient.Post(
-		brainURL()+"/brain/v1/process_intent",
-		"application/json",
-		bytes.NewBuffer(payload),
-	)
+	resp, err := brainHTTPClient.Do(req)
 
 	if err != nil {
 		return nil, fmt.Errorf("brain request: %w", err)
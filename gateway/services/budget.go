@@ -0,0 +1,306 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gateway/db"
+	"gateway/models"
+	"gateway/utils"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Budgets are evaluated per calendar month in server local time. Rollover
+// is replayed month by month from the budget's StartsOn, so editing a past
+// expense changes what carries forward. Limits are in DefaultCurrency, and
+// expenses in other currencies count at the rate of the day they were made.
+// Expenses in a currency with no rate at all are left out and counted as
+// unconverted, so a missing rate never blocks recording spending.
+
+const budgetActionType = "Budget_Alert"
+
+const (
+	BudgetOK     = "ok"
+	BudgetAtRisk = "at_risk" // on pace to exceed by month end
+	BudgetOver   = "over"
+)
+
+type BudgetStatus struct {
+	models.Budget
+	Month       string          `json:"month"`
+	Carried     decimal.Decimal `json:"carried"`   // rollover into this month
+	Available   decimal.Decimal `json:"available"` // limit plus carried
+	Spent       decimal.Decimal `json:"spent"`
+	Remaining   decimal.Decimal `json:"remaining"`
+	Projected   decimal.Decimal `json:"projected"` // month-end spend at the current daily rate
+	PercentUsed float64         `json:"percent_used"`
+	State       string          `json:"state"`
+}
+
+type BudgetReport struct {
+	Month          string          `json:"month"`
+	Currency       string          `json:"currency"` // of every amount, DefaultCurrency
+	Budgets        []BudgetStatus  `json:"budgets"`
+	TotalAvailable decimal.Decimal `json:"total_available"`
+	TotalSpent     decimal.Decimal `json:"total_spent"`
+	TotalRemaining decimal.Decimal `json:"total_remaining"`
+	Unbudgeted     decimal.Decimal `json:"unbudgeted"`  // expenses this month in categories without a budget
+	Unconverted    int             `json:"unconverted"` // expenses left out for want of an FX rate
+}
+
+func ValidRollover(r string) bool {
+	return r == models.RolloverNone || r == models.RolloverSurplus || r == models.RolloverFull
+}
+
+func monthStart(t time.Time) time.Time {
+	y, m, _ := t.In(time.Local).Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.Local)
+}
+
+// ParseMonth accepts "2006-01"; empty means the current month.
+func ParseMonth(s string) (time.Time, error) {
+	if s == "" {
+		return monthStart(time.Now()), nil
+	}
+	t, err := time.ParseInLocation("2006-01", s, time.Local)
+	if err != nil {
+		return time.Time{}, errors.New("month must look like 2006-01")
+	}
+	return t, nil
+}
+
+// ValidateBudget normalizes the budget and checks it is complete.
+func ValidateBudget(b *models.Budget) error {
+	b.Category = strings.TrimSpace(b.Category)
+	if b.Category == "" {
+		return errors.New("category is required")
+	}
+	if !b.MonthlyLimit.IsPositive() {
+		return errors.New("monthly_limit must be positive")
+	}
+	if b.Rollover == "" {
+		b.Rollover = models.RolloverNone
+	}
+	if !ValidRollover(b.Rollover) {
+		return fmt.Errorf("unknown rollover %q: use none, surplus or full", b.Rollover)
+	}
+	if b.MaxRollover.IsNegative() {
+		return errors.New("max_rollover cannot be negative")
+	}
+	if b.StartsOn.IsZero() {
+		b.StartsOn = time.Now()
+	}
+	b.StartsOn = monthStart(b.StartsOn)
+	currency := DefaultCurrency()
+	b.MonthlyLimit = utils.RoundMoney(b.MonthlyLimit, currency)
+	b.MaxRollover = utils.RoundMoney(b.MaxRollover, currency)
+	return nil
+}
+
+func categoryKey(c string) string {
+	return strings.ToLower(strings.TrimSpace(c))
+}
+
+// monthlySpend sums expenses per category key and month start from `from`
+// up to (not including) `to`, in DefaultCurrency and rounded to its minor
+// unit. Expenses that cannot be converted are skipped and counted.
+func monthlySpend(tx *gorm.DB, from, to time.Time) (map[string]map[time.Time]decimal.Decimal, int, error) {
+	var rows []struct {
+		Category  string
+		Amount    decimal.Decimal
+		Currency  string
+		CreatedAt time.Time
+	}
+	err := tx.Model(&models.FinanceRecord{}).
+		Select("category, amount, currency, created_at").
+		Where("type = ? AND created_at >= ? AND created_at < ?", "expense", from, to).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	fx, err := loadFXTable(tx)
+	if err != nil {
+		return nil, 0, err
+	}
+	currency := DefaultCurrency()
+	spend := map[string]map[time.Time]decimal.Decimal{}
+	skipped := 0
+	for _, r := range rows {
+		amount, err := fx.Convert(r.Amount, r.Currency, currency, r.CreatedAt)
+		if errors.Is(err, ErrNoFXRate) {
+			skipped++
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		key := categoryKey(r.Category)
+		if spend[key] == nil {
+			spend[key] = map[time.Time]decimal.Decimal{}
+		}
+		month := monthStart(r.CreatedAt)
+		spend[key][month] = spend[key][month].Add(amount)
+	}
+	for _, months := range spend {
+		for m, v := range months {
+			months[m] = utils.RoundMoney(v, currency)
+		}
+	}
+	return spend, skipped, nil
+}
+
+// carryInto replays rollover from the budget's first month up to month.
+func carryInto(b *models.Budget, month time.Time, spent map[time.Time]decimal.Decimal) decimal.Decimal {
+	carry := decimal.Zero
+	for m := b.StartsOn; m.Before(month); m = m.AddDate(0, 1, 0) {
+		left := b.MonthlyLimit.Add(carry).Sub(spent[m])
+		switch b.Rollover {
+		case models.RolloverSurplus:
+			carry = decimal.Max(left, decimal.Zero)
+		case models.RolloverFull:
+			carry = left
+		default:
+			carry = decimal.Zero
+		}
+		if b.MaxRollover.IsPositive() && carry.GreaterThan(b.MaxRollover) {
+			carry = b.MaxRollover
+		}
+	}
+	return carry
+}
+
+// monthElapsed is how far through month now is, from 0 to 1.
+func monthElapsed(month, now time.Time) float64 {
+	end := month.AddDate(0, 1, 0)
+	switch {
+	case !now.After(month):
+		return 0
+	case !now.Before(end):
+		return 1
+	}
+	return float64(now.Sub(month)) / float64(end.Sub(month))
+}
+
+func budgetStatus(b *models.Budget, month time.Time, spent map[time.Time]decimal.Decimal) BudgetStatus {
+	s := BudgetStatus{Budget: *b, Month: month.Format("2006-01")}
+	if month.Before(b.StartsOn) {
+		s.State = BudgetOK
+		return s
+	}
+	s.Carried = carryInto(b, month, spent)
+	s.Available = b.MonthlyLimit.Add(s.Carried)
+	s.Spent = spent[month]
+	s.Remaining = s.Available.Sub(s.Spent)
+	if f := monthElapsed(month, time.Now()); f > 0 {
+		s.Projected = utils.RoundMoney(s.Spent.Div(decimal.NewFromFloat(f)), DefaultCurrency())
+	}
+	if s.Available.IsPositive() {
+		s.PercentUsed = s.Spent.Div(s.Available).Mul(decimal.NewFromInt(100)).Round(2).InexactFloat64()
+	}
+	switch {
+	case s.Spent.GreaterThan(s.Available):
+		s.State = BudgetOver
+	case s.Projected.GreaterThan(s.Available):
+		s.State = BudgetAtRisk
+	default:
+		s.State = BudgetOK
+	}
+	return s
+}
+
+func buildBudgetReport(tx *gorm.DB, month time.Time) (*BudgetReport, error) {
+	var budgets []models.Budget
+	if err := tx.Order("category asc").Find(&budgets).Error; err != nil {
+		return nil, err
+	}
+	from := month
+	for _, b := range budgets {
+		if b.StartsOn.Before(from) {
+			from = b.StartsOn
+		}
+	}
+	spend, skipped, err := monthlySpend(tx, from, month.AddDate(0, 1, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &BudgetReport{Month: month.Format("2006-01"), Currency: DefaultCurrency(), Budgets: make([]BudgetStatus, len(budgets)), Unconverted: skipped}
+	budgeted := map[string]bool{}
+	for i := range budgets {
+		key := categoryKey(budgets[i].Category)
+		budgeted[key] = true
+		s := budgetStatus(&budgets[i], month, spend[key])
+		report.Budgets[i] = s
+		report.TotalAvailable = report.TotalAvailable.Add(s.Available)
+		report.TotalSpent = report.TotalSpent.Add(s.Spent)
+		report.TotalRemaining = report.TotalRemaining.Add(s.Remaining)
+	}
+	for key, months := range spend {
+		if !budgeted[key] {
+			report.Unbudgeted = report.Unbudgeted.Add(months[month])
+		}
+	}
+	return report, nil
+}
+
+// BuildBudgetReport reports spent, remaining and projected spend for every
+// budget of the owner in ctx for the month starting at month.
+func BuildBudgetReport(ctx context.Context, month time.Time) (*BudgetReport, error) {
+	return buildBudgetReport(db.For(ctx), monthStart(month))
+}
+
+// checkBudget runs after an expense of amount in currency and category is
+// recorded on tx. If it pushed the category over this month's budget, it
+// raises a warning event and a Budget_Alert in the Action Center, and
+// returns a note for the tool's reply. An expense with no FX rate is not
+// counted against the budget, and the note says so.
+func checkBudget(tx *gorm.DB, category string, amount decimal.Decimal, currency string) (string, error) {
+	var budget models.Budget
+	err := tx.Where("lower(category) = ?", categoryKey(category)).First(&budget).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	fx, err := loadFXTable(tx)
+	if err != nil {
+		return "", err
+	}
+	base := DefaultCurrency()
+	amount, err = fx.Convert(amount, currency, base, time.Now())
+	if errors.Is(err, ErrNoFXRate) {
+		return fmt.Sprintf("No %s/%s rate, so it was not counted against the %s budget.", currency, base, budget.Category), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	month := monthStart(time.Now())
+	spend, _, err := monthlySpend(tx, budget.StartsOn, month.AddDate(0, 1, 0))
+	if err != nil {
+		return "", err
+	}
+	s := budgetStatus(&budget, month, spend[categoryKey(category)])
+	if s.Spent.LessThanOrEqual(s.Available) {
+		return "", nil
+	}
+
+	msg := fmt.Sprintf("%s budget exceeded: %s spent of %s this month.", budget.Category, FormatMoney(s.Spent, base), FormatMoney(s.Available, base))
+	// Only the expense that crosses the line raises an alert.
+	if s.Spent.Sub(amount).LessThanOrEqual(s.Available) {
+		EmitEvent(tx.Statement.Context, "BUDGET", msg, "WARN")
+		content := fmt.Sprintf("%s\n\nLimit %s, carried %s, over by %s.", msg,
+			FormatMoney(budget.MonthlyLimit, base), FormatMoney(s.Carried, base), FormatMoney(s.Remaining.Neg(), base))
+		if err := mirrorToActionCenter(tx, budgetActionType, fmt.Sprint(budget.ID), "Over budget: "+budget.Category, content); err != nil {
+			return "", err
+		}
+	}
+	return msg, nil
+}
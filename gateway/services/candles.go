@@ -0,0 +1,164 @@
+package services
+
+import (
+	"fmt"
+	"gateway/db"
+	"gateway/models"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// krakenIntervals are the OHLC intervals Kraken serves, in minutes.
+var krakenIntervals = map[int]bool{1: true, 5: true, 15: true, 30: true, 60: true, 240: true, 1440: true, 10080: true, 21600: true}
+
+func ValidInterval(minutes int) bool {
+	return krakenIntervals[minutes]
+}
+
+var intervalNames = map[string]int{
+	"1m": 1, "5m": 5, "15m": 15, "30m": 30,
+	"1h": 60, "4h": 240, "1d": 1440, "1w": 10080, "15d": 21600,
+}
+
+// ParseInterval accepts a name such as "15m" or "1d", or minutes ("60").
+func ParseInterval(s string) (int, error) {
+	if m, ok := intervalNames[strings.ToLower(s)]; ok {
+		return m, nil
+	}
+	if m, err := strconv.Atoi(s); err == nil && ValidInterval(m) {
+		return m, nil
+	}
+	return 0, fmt.Errorf("unsupported interval %q: use 1m, 5m, 15m, 30m, 1h, 4h, 1d, 1w or 15d", s)
+}
+
+// IngestCandles backfills pair/interval from the newest stored candle
+// onwards. The newest candle is refetched since it was still forming.
+func IngestCandles(pair string, interval int) (int, error) {
+	if !ValidInterval(interval) {
+		return 0, fmt.Errorf("unsupported interval %d", interval)
+	}
+
+	var latest models.Candle
+	var since int64
+	if db.Instance.Where("pair = ? AND interval_min = ?", pair, interval).
+		Order("time desc").Limit(1).Find(&latest).RowsAffected > 0 {
+		since = latest.Time.Unix() - 1
+	}
+
+	candles, _, err := ActiveExchange().OHLC(pair, interval, since)
+	if err != nil {
+		return 0, err
+	}
+	if err := storeCandles(candles); err != nil {
+		return 0, err
+	}
+	return len(candles), nil
+}
+
+// storeCandles upserts candles; a stored candle that was still forming is
+// overwritten.
+func storeCandles(candles []models.Candle) error {
+	if len(candles) == 0 {
+		return nil
+	}
+	err := db.Instance.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "pair"}, {Name: "interval_min"}, {Name: "time"}},
+		DoUpdates: clause.AssignmentColumns([]string{"open", "high", "low", "close", "vwap", "volume", "trades"}),
+	}).CreateInBatches(candles, 500).Error
+	if err != nil {
+		return fmt.Errorf("store candles: %w", err)
+	}
+	return nil
+}
+
+// LoadCandles reads stored candles in [from, to], oldest first. Zero times
+// leave that side open.
+func LoadCandles(pair string, interval int, from, to time.Time) ([]models.Candle, error) {
+	q := db.Instance.Where("pair = ? AND interval_min = ?", pair, interval)
+	if !from.IsZero() {
+		q = q.Where("time >= ?", from)
+	}
+	if !to.IsZero() {
+		q = q.Where("time <= ?", to)
+	}
+	var candles []models.Candle
+	err := q.Order("time asc").Find(&candles).Error
+	return candles, err
+}
+
+// candleTTL is how long stored candles are served before Kraken is asked
+// for newer ones. Override with OHLC_TTL_SECONDS.
+func candleTTL() time.Duration {
+	return time.Duration(envFloat("OHLC_TTL_SECONDS", 60)) * time.Second
+}
+
+var (
+	refreshMu     sync.Mutex
+	lastRefreshed = map[string]time.Time{}
+)
+
+// refreshCandles ingests pair/interval unless it was refreshed within the
+// TTL. Callers are serialised so a burst of chart loads costs one request.
+func refreshCandles(pair string, interval int) error {
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+
+	key := fmt.Sprintf("%s/%d", pair, interval)
+	if time.Since(lastRefreshed[key]) < candleTTL() {
+		return nil
+	}
+	if _, err := IngestCandles(pair, interval); err != nil {
+		return err
+	}
+	lastRefreshed[key] = time.Now()
+	return nil
+}
+
+// chartWindow is how many candles are served when no since is given,
+// matching what Kraken itself returns.
+const chartWindow = 720
+
+// CachedCandles serves candles from the local store, topping it up from
+// Kraken once the TTL has lapsed. Only candles opening after since (unix
+// seconds) are returned, so a chart can poll with its newest time; without
+// since the latest chartWindow are returned. If Kraken is unreachable,
+// whatever is stored is returned.
+func CachedCandles(pair string, interval int, since int64) ([]Candle, error) {
+	refreshErr := refreshCandles(pair, interval)
+	if refreshErr != nil {
+		log.Printf("[OHLC] Refresh %s/%d: %v", pair, interval, refreshErr)
+	}
+
+	var from time.Time
+	if since > 0 {
+		from = time.Unix(since+1, 0)
+	}
+	stored, err := LoadCandles(pair, interval, from, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	if len(stored) == 0 && refreshErr != nil {
+		return nil, refreshErr
+	}
+	if since <= 0 && len(stored) > chartWindow {
+		stored = stored[len(stored)-chartWindow:]
+	}
+
+	candles := make([]Candle, len(stored))
+	for i, c := range stored {
+		candles[i] = Candle{
+			Time:   c.Time.Unix(),
+			Open:   c.Open,
+			High:   c.High,
+			Low:    c.Low,
+			Close:  c.Close,
+			Volume: c.Volume,
+		}
+	}
+	return candles, nil
+}
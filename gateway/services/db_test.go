@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"gateway/db"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// recordingDB stands in for Postgres in tests that only need to see which
+// statements a service issues. Every statement is kept with its arguments;
+// queries are answered by rows, and otherwise find nothing (a lone max() or
+// count() gives one NULL), and writes report one affected row.
+type recordingDB struct {
+	rows func(query string, args []driver.Value) ([]string, [][]driver.Value)
+
+	mu    sync.Mutex
+	stmts []recordedStmt
+}
+
+type recordedStmt struct {
+	SQL  string
+	Args []driver.Value
+}
+
+// useRecordingDB makes a recordingDB db.Instance for the rest of the test.
+func useRecordingDB(t *testing.T, rows func(query string, args []driver.Value) ([]string, [][]driver.Value)) *recordingDB {
+	t.Helper()
+	r := &recordingDB{rows: rows}
+	g, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(r)}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := db.Instance
+	if err := db.Use(g); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Instance = prev })
+	return r
+}
+
+// touching returns the recorded statements naming table.
+func (r *recordingDB) touching(table string) []recordedStmt {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []recordedStmt
+	for _, s := range r.stmts {
+		if strings.Contains(s.SQL, `"`+table+`"`) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func (r *recordingDB) record(query string, named []driver.NamedValue) []driver.Value {
+	args := make([]driver.Value, len(named))
+	for i, a := range named {
+		args[i] = a.Value
+	}
+	r.mu.Lock()
+	r.stmts = append(r.stmts, recordedStmt{SQL: query, Args: args})
+	r.mu.Unlock()
+	return args
+}
+
+func (r *recordingDB) Connect(context.Context) (driver.Conn, error) { return recordingConn{r}, nil }
+func (r *recordingDB) Driver() driver.Driver                        { return nil }
+
+type recordingConn struct{ db *recordingDB }
+
+func (c recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return recordingStmt{c, query}, nil
+}
+func (c recordingConn) Close() error              { return nil }
+func (c recordingConn) Begin() (driver.Tx, error) { return recordingTx{}, nil }
+
+func (c recordingConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.record(query, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (c recordingConn) QueryContext(_ context.Context, query string, named []driver.NamedValue) (driver.Rows, error) {
+	args := c.db.record(query, named)
+	rows := &recordingRows{}
+	if c.db.rows != nil {
+		rows.cols, rows.vals = c.db.rows(query, args)
+	}
+	if rows.cols == nil && (strings.HasPrefix(query, "SELECT max(") || strings.HasPrefix(query, "SELECT count(")) {
+		rows.cols, rows.vals = []string{"aggregate"}, [][]driver.Value{{nil}}
+	}
+	return rows, nil
+}
+
+type recordingStmt struct {
+	conn  recordingConn
+	query string
+}
+
+func (s recordingStmt) Close() error  { return nil }
+func (s recordingStmt) NumInput() int { return -1 }
+
+func (s recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, namedValues(args))
+}
+
+func (s recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, namedValues(args))
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+type recordingTx struct{}
+
+func (recordingTx) Commit() error   { return nil }
+func (recordingTx) Rollback() error { return nil }
+
+type recordingRows struct {
+	cols []string
+	vals [][]driver.Value
+}
+
+func (r *recordingRows) Columns() []string { return r.cols }
+func (r *recordingRows) Close() error      { return nil }
+
+func (r *recordingRows) Next(dest []driver.Value) error {
+	if len(r.vals) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.vals[0])
+	r.vals = r.vals[1:]
+	return nil
+}
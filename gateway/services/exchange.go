@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gateway/db"
+	"gateway/models"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Exchange is the market and account API the finance services work
+// through. Assets are normalized tickers ("BTC", "USD"); pairs are in the
+// exchange's own notation, as stored on signals and candles. Adding an
+// exchange means writing an adapter and registering it.
+type Exchange interface {
+	Name() string
+	// NormalizeAsset maps one of the exchange's asset codes to its common
+	// ticker.
+	NormalizeAsset(code string) string
+	// USDBase returns the normalized base asset of one of the exchange's
+	// USD-quoted pairs; ok is false for any other pair.
+	USDBase(pair string) (asset string, ok bool)
+	// Balances returns the amount held per normalized asset.
+	Balances() (map[string]decimal.Decimal, error)
+	// USDPair names the asset's USD market, for OHLC.
+	USDPair(asset string) string
+	// USDPrice returns the last traded USD price of an asset.
+	USDPrice(asset string) (decimal.Decimal, error)
+	// OHLC returns candles for pair at interval (minutes) opening after
+	// since (unix seconds, 0 for the default window) and the cursor for the
+	// next call.
+	OHLC(pair string, interval int, since int64) ([]models.Candle, int64, error)
+	PlaceOrder(o OrderRequest) (*OrderResult, error)
+	QueryOrder(txid string) (*OrderFill, error)
+	// Trades and Ledger page through account history after start (zero for
+	// all), oldest first.
+	Trades(start time.Time) ([]models.ExchangeTrade, error)
+	Ledger(start time.Time) ([]models.LedgerEntry, error)
+}
+
+// OrderRequest is an order to place on the exchange.
+type OrderRequest struct {
+	Pair      string
+	Side      string // "buy" or "sell"
+	OrderType string // "market" or "limit"
+	Volume    decimal.Decimal
+	Price     decimal.Decimal // limit price; ignored for market orders
+	Validate  bool            // ask the exchange to check the order without placing it
+}
+
+type OrderResult struct {
+	TxIDs       []string `json:"txid"`
+	Description string   `json:"description"`
+}
+
+// OrderFill is the execution state of a placed order.
+type OrderFill struct {
+	Status       string
+	FilledVolume decimal.Decimal
+	AvgPrice     decimal.Decimal
+	Cost         decimal.Decimal
+	Fee          decimal.Decimal
+}
+
+var (
+	exchanges    = map[string]func() Exchange{}
+	exchangeOnce sync.Once
+	exchange     Exchange
+)
+
+// RegisterExchange makes an adapter selectable through EXCHANGE.
+func RegisterExchange(name string, factory func() Exchange) {
+	exchanges[name] = factory
+}
+
+// ExchangeNames lists the registered adapters.
+func ExchangeNames() []string {
+	names := make([]string, 0, len(exchanges))
+	for n := range exchanges {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetupExchange selects the adapter named by EXCHANGE (default "kraken").
+// main calls it at startup so a bad name fails fast.
+func SetupExchange() error {
+	name := os.Getenv("EXCHANGE")
+	if name == "" {
+		name = "kraken"
+	}
+	factory, ok := exchanges[name]
+	if !ok {
+		return fmt.Errorf("unknown EXCHANGE %q, have %v", name, ExchangeNames())
+	}
+	exchange = factory()
+	return nil
+}
+
+// ActiveExchange returns the configured adapter, defaulting to Kraken when
+// SetupExchange was never called.
+func ActiveExchange() Exchange {
+	exchangeOnce.Do(func() {
+		if exchange == nil {
+			exchange = exchanges["kraken"]()
+		}
+	})
+	return exchange
+}
+
+var (
+	// ErrNotExchangeOwner refuses a sync or import for anyone but the user
+	// the exchange credentials belong to.
+	ErrNotExchangeOwner = errors.New("the exchange account belongs to another user")
+	ErrNoExchangeOwner  = errors.New("no user is registered to own the exchange account")
+)
+
+// ExchangeOwner returns the user the exchange credentials belong to: the
+// one whose email is EXCHANGE_OWNER or, when that is unset, the only user.
+func ExchangeOwner() (*models.User, error) {
+	if email := os.Getenv("EXCHANGE_OWNER"); email != "" {
+		var u models.User
+		if err := db.Instance.Where("email = ?", email).First(&u).Error; err != nil {
+			return nil, fmt.Errorf("EXCHANGE_OWNER %q: %w", email, err)
+		}
+		return &u, nil
+	}
+	var users []models.User
+	if err := db.Instance.Limit(2).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	switch len(users) {
+	case 0:
+		return nil, ErrNoExchangeOwner
+	case 1:
+		return &users[0], nil
+	}
+	return nil, errors.New("several users are registered; set EXCHANGE_OWNER to the email of the exchange account's owner")
+}
+
+// exchangeContext scopes ctx to the exchange owner, so account history and
+// balances are only ever stored as that user's rows. A ctx scoped to anyone
+// else is refused; an unscoped one, such as the kraken_sync job's, takes the
+// owner on.
+func exchangeContext(ctx context.Context) (context.Context, error) {
+	owner, err := ExchangeOwner()
+	if err != nil {
+		return ctx, err
+	}
+	if id, scoped := db.OwnerFrom(ctx); scoped && id != owner.ID {
+		return ctx, ErrNotExchangeOwner
+	}
+	return db.WithOwner(ctx, owner.ID), nil
+}
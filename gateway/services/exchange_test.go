@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"gateway/db"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// twoUsers answers user lookups with alice and bob, filtered by email when
+// the query asks for one.
+func twoUsers(alice, bob uuid.UUID) func(string, []driver.Value) ([]string, [][]driver.Value) {
+	users := [][]driver.Value{
+		{alice.String(), time.Now(), time.Now(), "alice@example.com", "admin"},
+		{bob.String(), time.Now(), time.Now(), "bob@example.com", "member"},
+	}
+	return func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		if !strings.Contains(query, `FROM "users"`) {
+			return nil, nil
+		}
+		cols := []string{"id", "created_at", "updated_at", "email", "role"}
+		if !strings.Contains(query, "email =") {
+			return cols, users
+		}
+		for _, u := range users {
+			if u[3] == args[0] {
+				return cols, [][]driver.Value{u}
+			}
+		}
+		return cols, nil
+	}
+}
+
+// exchangeTables are the owned tables a sync writes.
+var exchangeTables = []string{"crypto_holdings", "ledger_entries", "exchange_trades", "finance_records"}
+
+func TestSyncKrakenWritesOnlyTheExchangeOwnersRows(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+	rec := useRecordingDB(t, twoUsers(alice, bob))
+	useExchange(t, newFakeKraken(t))
+	t.Setenv("EXCHANGE_OWNER", "bob@example.com")
+
+	// The kraken_sync job is unowned and runs with no owner in ctx.
+	if _, err := syncKraken(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, table := range exchangeTables {
+		for _, s := range rec.touching(table) {
+			if !hasArg(s.Args, bob.String()) {
+				t.Errorf("%s not scoped to the exchange owner:\n%s", table, s.SQL)
+			}
+			if hasArg(s.Args, alice.String()) {
+				t.Errorf("%s touches another user's rows:\n%s", table, s.SQL)
+			}
+		}
+	}
+	var inserts, deletes int
+	for _, s := range rec.touching("crypto_holdings") {
+		switch {
+		case strings.HasPrefix(s.SQL, "INSERT"):
+			inserts++
+		case strings.Contains(s.SQL, "deleted_at") && strings.HasPrefix(s.SQL, "UPDATE"):
+			deletes++
+		}
+	}
+	if inserts == 0 || deletes != 1 {
+		t.Fatalf("%d holdings inserted and %d sold-out deletes, want some and 1", inserts, deletes)
+	}
+}
+
+func TestSyncKrakenRefusesOtherUsers(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+	rec := useRecordingDB(t, twoUsers(alice, bob))
+	useExchange(t, newFakeKraken(t))
+	t.Setenv("EXCHANGE_OWNER", "bob@example.com")
+
+	_, _, err := SyncKraken(db.WithOwner(context.Background(), alice))
+	if !errors.Is(err, ErrNotExchangeOwner) {
+		t.Fatalf("err = %v, want ErrNotExchangeOwner", err)
+	}
+	for _, table := range exchangeTables {
+		if n := len(rec.touching(table)); n > 0 {
+			t.Errorf("%d statement(s) on %s, want none", n, table)
+		}
+	}
+}
+
+func TestExchangeOwner(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+	tests := []struct {
+		name  string
+		env   string
+		users func(string, []driver.Value) ([]string, [][]driver.Value)
+		want  uuid.UUID
+		err   bool
+	}{
+		{"configured", "alice@example.com", twoUsers(alice, bob), alice, false},
+		{"unknown email", "carol@example.com", twoUsers(alice, bob), uuid.Nil, true},
+		{"several users unset", "", twoUsers(alice, bob), uuid.Nil, true},
+		{"only user", "", func(q string, a []driver.Value) ([]string, [][]driver.Value) {
+			cols, rows := twoUsers(alice, bob)(q, a)
+			return cols, rows[:min(len(rows), 1)]
+		}, alice, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useRecordingDB(t, tt.users)
+			t.Setenv("EXCHANGE_OWNER", tt.env)
+			u, err := ExchangeOwner()
+			if tt.err {
+				if err == nil {
+					t.Fatalf("owner = %s, want an error", u.Email)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.ID != tt.want {
+				t.Fatalf("owner = %s, want %s", u.ID, tt.want)
+			}
+		})
+	}
+
+	useRecordingDB(t, nil)
+	t.Setenv("EXCHANGE_OWNER", "")
+	if _, err := syncKraken(context.Background()); err != nil {
+		t.Fatalf("sync with no users: %v, want it skipped", err)
+	}
+}
+
+func hasArg(args []driver.Value, want string) bool {
+	for _, a := range args {
+		if s, ok := a.(string); ok && s == want {
+			return true
+		}
+	}
+	return false
+}
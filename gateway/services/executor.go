@@ -1,290 +1,507 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"gateway/db"
 	"gateway/models"
 	"gateway/utils"
 	"log"
 	"strconv"
-	// "time"
-)
+	"time"
 
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
 
-func mirrorToActionCenter(actionType, title, content string) {
+func mirrorToActionCenter(tx *gorm.DB, actionType, refID, title, content string) error {
 	action := models.PendingAction{
 		Type:     actionType,
 		Title:    title,
 		Content:  content,
-		Status:   "Pending",
+		Status:   models.ActionPending,
 		Priority: "Medium",
+		RefID:    refID,
 	}
-	if err := db.Instance.Create(&action).Error; err != nil {
+	if err := tx.Create(&action).Error; err != nil {
 		log.Printf("[MIRROR ERROR] Failed to send to Action Center: %v", err)
-	} else {
-		log.Printf("[MIRROR SUCCESS] Action '%s' queued for review", title)
+		return fmt.Errorf("mirror to action center: %w", err)
 	}
+	log.Printf("[MIRROR SUCCESS] Action '%s' queued for review", title)
+	return nil
 }
 
-func ExecuteToolCall(action string, data map[string]interface{}) (string, string) {
+// ExecuteToolCall resolves action in the tool registry, decodes data into the
+// tool's argument struct and runs it for the owner in ctx. Bad arguments
+// yield a *ToolValidationError.
+func ExecuteToolCall(ctx context.Context, action string, data map[string]interface{}) (string, string, error) {
 	log.Printf("Executing action: %s with data: %+v\n", action, data)
-	switch action {
-		case "execute_record_expense":
+
+	return runTool(db.For(ctx), action, data)
+}
+
+func runTool(tx *gorm.DB, action string, data map[string]interface{}) (string, string, error) {
+	tool, ok := lookupTool(action)
+	if !ok {
+		return "", "", fmt.Errorf("%w: %s", ErrUnknownTool, action)
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return tool.run(tx, data)
+}
+
+type recordExpenseArgs struct {
+	Amount      decimal.Decimal `json:"amount" tool:"required" desc:"Amount spent"`
+	Currency    string          `json:"currency" desc:"ISO 4217 code, e.g. EUR; defaults to the ledger currency"`
+	Category    string          `json:"category" tool:"required" desc:"Spending category, e.g. Food"`
+	Description string          `json:"description" desc:"What the money was spent on"`
+}
+
+func (a recordExpenseArgs) Validate() []FieldError {
+	if !a.Amount.IsPositive() {
+		return []FieldError{{Field: "amount", Reason: "must be greater than zero"}}
+	}
+	return validateCurrencyArg(a.Currency)
+}
+
+// validateCurrencyArg checks an optional currency code tool argument.
+func validateCurrencyArg(c string) []FieldError {
+	if _, err := NormalizeCurrency(c); err != nil {
+		return []FieldError{{Field: "currency", Reason: "must be a three-letter ISO 4217 code"}}
+	}
+	return nil
+}
+
+type socialDraftArgs struct {
+	Content  string `json:"content" tool:"required" desc:"Post body"`
+	Platform string `json:"platform" tool:"required" desc:"Target platform: x, linkedin, instagram"`
+}
+
+type createTaskArgs struct {
+	Title string `json:"title" tool:"required" desc:"Task title"`
+}
+
+type trackJobArgs struct {
+	Company string `json:"company" tool:"required"`
+	Role    string `json:"role" tool:"required"`
+}
+
+type recordMealArgs struct {
+	FoodItem string `json:"food_item" tool:"required"`
+	Calories int    `json:"calories" tool:"required" desc:"Energy in kcal"`
+	Protein  int    `json:"protein" desc:"Grams of protein"`
+	Carbs    int    `json:"carbs" desc:"Grams of carbohydrate"`
+	Fats     int    `json:"fats" desc:"Grams of fat"`
+}
+
+func (a recordMealArgs) Validate() []FieldError {
+	var errs []FieldError
+	for field, v := range map[string]int{"calories": a.Calories, "protein": a.Protein, "carbs": a.Carbs, "fats": a.Fats} {
+		if v < 0 {
+			errs = append(errs, FieldError{Field: field, Reason: "must not be negative"})
+		}
+	}
+	return errs
+}
+
+type recordWorkoutArgs struct {
+	Exercise string `json:"exercise" tool:"required"`
+	Sets     int    `json:"sets"`
+	Reps     int    `json:"reps"`
+	Weight   int    `json:"weight"`
+	Duration int    `json:"duration" desc:"Duration in minutes"`
+}
+
+type syncPortfolioArgs struct{}
+
+type tradingSignalArgs struct {
+	Asset      string          `json:"asset" tool:"required" desc:"Kraken pair or asset, e.g. XXBTZUSD"`
+	Action     string          `json:"signal_action" tool:"required" enum:"BUY,SELL,HOLD"`
+	Price      decimal.Decimal `json:"price"`
+	Reasoning  string          `json:"reasoning"`
+	Confidence float64         `json:"confidence" desc:"0 to 100"`
+}
+
+type webResearchArgs struct {
+	Query    string `json:"query" tool:"required"`
+	Findings string `json:"findings" desc:"Synthesized report in Markdown"`
+}
+
+type recordIncomeArgs struct {
+	Amount      decimal.Decimal `json:"amount" tool:"required"`
+	Currency    string          `json:"currency" desc:"ISO 4217 code, e.g. EUR; defaults to the ledger currency"`
+	Category    string          `json:"category"`
+	Description string          `json:"description"`
+}
+
+func (a recordIncomeArgs) Validate() []FieldError {
+	if !a.Amount.IsPositive() {
+		return []FieldError{{Field: "amount", Reason: "must be greater than zero"}}
+	}
+	return validateCurrencyArg(a.Currency)
+}
+
+type launchVentureArgs struct {
+	Name         string `json:"name" tool:"required"`
+	Category     string `json:"category" desc:"Affiliate, SaaS, Content"`
+	Strategy     string `json:"strategy"`
+	ProjectedROI string `json:"projected_roi"`
+	Platform     string `json:"platform"`
+}
+
+type recordSavingsArgs struct {
+	Amount      decimal.Decimal `json:"amount" tool:"required"`
+	Currency    string          `json:"currency" desc:"ISO 4217 code, e.g. EUR; defaults to the ledger currency"`
+	Description string          `json:"description" desc:"Venture name the profit came from"`
+}
+
+func (a recordSavingsArgs) Validate() []FieldError {
+	return validateCurrencyArg(a.Currency)
+}
+
+type saveKnowledgeArgs struct {
+	Topic   string `json:"topic" tool:"required"`
+	Content string `json:"content" tool:"required"`
+	Tags    string `json:"tags" desc:"Comma-separated tags"`
+}
+
+type logSecurityArgs struct {
+	Issue    string `json:"issue" tool:"required"`
+	Severity string `json:"severity" enum:"Low,Medium,High,Critical"`
+}
+
+type saveCodeArgs struct {
+	FileName    string `json:"file_name"`
+	Language    string `json:"language"`
+	Code        string `json:"code" tool:"required"`
+	Description string `json:"description"`
+}
+
+type submitForReviewArgs struct {
+	Type       string `json:"type" desc:"Job_App or a manual action type; use execute_create_social_draft or execute_launch_venture for posts and ventures"`
+	ActionType string `json:"action_type" desc:"Legacy spelling of type"`
+	Title      string `json:"title" tool:"required"`
+	Content    string `json:"content"`
+	Priority   string `json:"priority" enum:"Low,Medium,High"`
+}
+
+// linkedActionTools names the tool that creates each action type whose
+// deployer needs a RefID.
+var linkedActionTools = map[string]string{
+	"Social_Post":  "execute_create_social_draft",
+	"Venture_Plan": "execute_launch_venture",
+}
+
+func init() {
+	RegisterTool("execute_record_expense", "Record an expense in the finance ledger.",
+		func(tx *gorm.DB, a recordExpenseArgs) (string, string, error) {
+			currency, _ := recordCurrency(a.Currency)
 			expense := models.FinanceRecord{
-				Amount:      data["amount"].(float64),
-				Category:    data["category"].(string),
-				Description: data["description"].(string),
+				Amount:      utils.RoundMoney(a.Amount, currency),
+				Currency:    currency,
+				Category:    a.Category,
+				Description: a.Description,
+				Type:        "expense",
+			}
+			if err := tx.Create(&expense).Error; err != nil {
+				return "", "", fmt.Errorf("record expense: %w", err)
+			}
+			reply := fmt.Sprintf("Recorded %s in %s.", FormatMoney(expense.Amount, currency), expense.Category)
+			warning, err := checkBudget(tx, expense.Category, expense.Amount, currency)
+			if err != nil {
+				return "", "", fmt.Errorf("check budget: %w", err)
+			}
+			if warning != "" {
+				reply += " " + warning
+			}
+			anomalies, err := checkSpendingAnomalies(tx, []models.FinanceRecord{expense})
+			if err != nil {
+				return "", "", fmt.Errorf("check anomalies: %w", err)
+			}
+			for _, a := range anomalies {
+				reply += " " + a.Explanation
 			}
-			db.Instance.Create(&expense)
-			return fmt.Sprintf("Recorded $%.2f in %s.", expense.Amount, expense.Category), "view_finance"
+			return reply, "view_finance", nil
+		})
 
-		case "execute_create_social_draft":
+	RegisterTool("execute_create_social_draft", "Save a social media post draft and queue it for review.",
+		func(tx *gorm.DB, a socialDraftArgs) (string, string, error) {
 			post := models.SocialPost{
-				Content:  data["content"].(string),
-				Platform: data["platform"].(string),
+				Content:  a.Content,
+				Platform: a.Platform,
 				Status:   "draft",
 			}
-			db.Instance.Create(&post)
-			
-			mirrorToActionCenter("Social_Post", "Post Draft: "+post.Platform, post.Content)
-
-			return "Draft saved to Social Hub.", "view_social"
+			if err := tx.Create(&post).Error; err != nil {
+				return "", "", fmt.Errorf("create social draft: %w", err)
+			}
+			if err := mirrorToActionCenter(tx, "Social_Post", post.ID.String(), "Post Draft: "+post.Platform, post.Content); err != nil {
+				return "", "", err
+			}
+			return "Draft saved to Social Hub.", "view_social", nil
+		})
 
-		case "execute_create_task":
+	RegisterTool("execute_create_task", "Add a task to the to-do list.",
+		func(tx *gorm.DB, a createTaskArgs) (string, string, error) {
 			task := models.TaskRecord{
-				Title:  data["title"].(string),
+				Title:  a.Title,
 				Status: "Pending",
 			}
-			db.Instance.Create(&task)
-			return "Task created.", "view_tasks"
+			if err := tx.Create(&task).Error; err != nil {
+				return "", "", fmt.Errorf("create task: %w", err)
+			}
+			return "Task created.", "view_tasks", nil
+		})
 
-		case "execute_track_job_application":
+	RegisterTool("execute_track_job_application", "Track a submitted job application.",
+		func(tx *gorm.DB, a trackJobArgs) (string, string, error) {
 			job := models.JobApplication{
-				Company: data["company"].(string),
-				Role:    data["role"].(string),
+				Company: a.Company,
+				Role:    a.Role,
 				Status:  "Applied",
 			}
-			db.Instance.Create(&job)
-			mirrorToActionCenter("Job_App", "Track App: "+job.Company, job.Role)
-
-			return "Job application tracked.", "view_jobs"
+			if err := tx.Create(&job).Error; err != nil {
+				return "", "", fmt.Errorf("track job application: %w", err)
+			}
+			if err := mirrorToActionCenter(tx, "Job_App", job.ID.String(), "Track App: "+job.Company, job.Role); err != nil {
+				return "", "", err
+			}
+			return "Job application tracked.", "view_jobs", nil
+		})
 
-		case "execute_record_meal":
+	RegisterTool("execute_record_meal", "Log a meal with its macronutrients.",
+		func(tx *gorm.DB, a recordMealArgs) (string, string, error) {
 			meal := models.DietRecord{
-				FoodItem: data["food_item"].(string),
-				Calories: int(data["calories"].(float64)),
-				Protein:  int(data["protein"].(float64)),
-				Carbs:    int(data["carbs"].(float64)),
-				Fats:     int(data["fats"].(float64)),
+				FoodItem: a.FoodItem,
+				Calories: a.Calories,
+				Protein:  a.Protein,
+				Carbs:    a.Carbs,
+				Fats:     a.Fats,
 			}
-			
-			db.Instance.Create(&meal)
-			return fmt.Sprintf("Logged %s (%d kcal).", meal.FoodItem, meal.Calories), "view_health"
+			if err := tx.Create(&meal).Error; err != nil {
+				return "", "", fmt.Errorf("record meal: %w", err)
+			}
+			return fmt.Sprintf("Logged %s (%d kcal).", meal.FoodItem, meal.Calories), "view_health", nil
+		})
 
-		case "execute_record_workout":
+	RegisterTool("execute_record_workout", "Log a workout session.",
+		func(tx *gorm.DB, a recordWorkoutArgs) (string, string, error) {
 			workout := models.WorkoutRecord{
-				Exercise:     data["exercise"].(string),
-				Sets:         int(data["sets"].(float64)),
-				Reps:         int(data["reps"].(float64)),
-				Weight:       int(data["weight"].(float64)),
-				DurationMins: int(data["duration"].(float64)),
+				Exercise:     a.Exercise,
+				Sets:         a.Sets,
+				Reps:         a.Reps,
+				Weight:       a.Weight,
+				DurationMins: a.Duration,
 			}
-			db.Instance.Create(&workout)
-			return fmt.Sprintf("Workout recorded: %s.", workout.Exercise), "view_health"
-
-		case "execute_sync_portfolio":
-			balances, err := FetchKrakenBalances()
-			if err != nil {
-				log.Printf("Kraken Sync Failed: %v", err)
-				return fmt.Sprintf("Failed to sync with Kraken. Error: %v", err), ""
+			if err := tx.Create(&workout).Error; err != nil {
+				return "", "", fmt.Errorf("record workout: %w", err)
 			}
-
-			count := 0
-			for asset, val := range balances {
-				amount, err := strconv.ParseFloat(val, 64)
+			return fmt.Sprintf("Workout recorded: %s.", workout.Exercise), "view_health", nil
+		})
+
+	RegisterTool("execute_sync_portfolio", "Synchronise crypto balances from the exchange.",
+		func(tx *gorm.DB, _ syncPortfolioArgs) (string, string, error) {
+			name := ActiveExchange().Name()
+			// The exchange is called after the plan commits, not while its
+			// transaction is open.
+			afterCommit(tx.Statement.Context, func(ctx context.Context) {
+				count, err := SyncHoldings(ctx)
 				if err != nil {
-					continue
-				}
-				if amount > 0 {
-					db.Instance.Where(models.CryptoHoldings{Asset: asset}).
-						Assign(models.CryptoHoldings{Balance: amount}).
-						FirstOrCreate(&models.CryptoHoldings{})
-					count++
+					log.Printf("Exchange Sync Failed: %v", err)
+					EmitEvent(ctx, "EXCHANGE", fmt.Sprintf("Sync from %s failed: %v", name, err), "WARN")
+					return
 				}
-			}
+				EmitEvent(ctx, "EXCHANGE", fmt.Sprintf("Synchronized %d assets from %s.", count, name), "SUCCESS")
+			})
+			return fmt.Sprintf("Portfolio sync from %s queued; it runs once the plan commits.", name), "view_finance", nil
+		})
 
-			return fmt.Sprintf("Successfully synchronized %d assets from Kraken.", count), "view_finance"
+	RegisterTool("execute_generate_trading_signal", "Archive an AI trading signal for human review.",
+		func(tx *gorm.DB, a tradingSignalArgs) (string, string, error) {
+			log.Printf("[TRADER] AI generated signal: %v for %v", a.Action, a.Asset)
 
-		// case "execute_get_market_analysis":
-		// 	pair := utils.SafeString(data, "pair")
-		// 	if pair == "" { pair = "XXBTZUSD" }
-
-		// 	candles, err := FetchMarketCandles(pair)
-		// 	if err != nil {
-		// 		return "Error fetching market data", "", nil
-		// 	}
-
-		// 	return "Market data retrieved", "view_finance", candles
-			
-		case "execute_generate_trading_signal":
-			log.Printf("[TRADER] AI generated signal: %v for %v", data["signal_action"], data["asset"])
-			
 			signal := models.TradingSignal{
-				Asset:      utils.SafeString(data, "asset"),
-				Action:     utils.SafeString(data, "signal_action"), // BUY/SELL/HOLD
-				Price:      utils.ParseNumeric(data["price"]),
-				Reasoning:  utils.SafeString(data, "reasoning"),
-				Confidence: utils.ParseNumeric(data["confidence"]),
-				Status:     "Pending",
-			}
-			
-			if err := db.Instance.Create(&signal).Error; err != nil {
+				Asset:      a.Asset,
+				Action:     a.Action,
+				Price:      a.Price,
+				Reasoning:  a.Reasoning,
+				Confidence: a.Confidence,
+				Status:     models.SignalPending,
+			}
+			if err := tx.Create(&signal).Error; err != nil {
 				log.Printf("[DB ERROR] %v", err)
-				return "Internal DB Error", ""
+				return "", "", fmt.Errorf("archive signal: %w", err)
 			}
-			
-			return fmt.Sprintf("Signal Archived: %s %s", signal.Action, signal.Asset), "view_finance"
-	
-		case "execute_web_research":
-			q := utils.SafeString(data, "query")
-			f := utils.SafeString(data, "findings")
 
-			log.Printf("[DEBUG] Research Data Recv -> Query: %s | Findings Len: %d", q, len(f))
+			return fmt.Sprintf("Signal Archived: %s %s", signal.Action, signal.Asset), "view_finance", nil
+		})
+
+	RegisterTool("execute_web_research", "Archive a synthesized research report.",
+		func(tx *gorm.DB, a webResearchArgs) (string, string, error) {
+			log.Printf("[DEBUG] Research Data Recv -> Query: %s | Findings Len: %d", a.Query, len(a.Findings))
 
-			if f == "" {
-				f = "Analysis completed, but no usable data was synthesized by the agent."
+			findings := a.Findings
+			if findings == "" {
+				findings = "Analysis completed, but no usable data was synthesized by the agent."
 			}
 
 			report := models.ResearchReports{
-				Query:    q,
-				Findings: f,
+				Query:    a.Query,
+				Findings: findings,
 				Category: "System Research",
 			}
-			
-			if err := db.Instance.Create(&report).Error; err != nil {
+			if err := tx.Create(&report).Error; err != nil {
 				log.Printf("[DATABASE ERROR]: %v", err)
-				return "Internal DB Error", ""
+				return "", "", fmt.Errorf("archive research: %w", err)
 			}
 
-			return fmt.Sprintf("Intelligence Report for '%s' has been synthesized and archived.", q), "view_research"
+			return fmt.Sprintf("Intelligence Report for '%s' has been synthesized and archived.", a.Query), "view_research", nil
+		})
 
-		case "execute_record_income":
+	RegisterTool("execute_record_income", "Record a cash inflow.",
+		func(tx *gorm.DB, a recordIncomeArgs) (string, string, error) {
+			currency, _ := recordCurrency(a.Currency)
 			income := models.FinanceRecord{
-				Amount:      utils.ParseNumeric(data["amount"]),
-				Category:    utils.SafeString(data, "category"),
-				Description: utils.SafeString(data, "description"),
+				Amount:      utils.RoundMoney(a.Amount, currency),
+				Currency:    currency,
+				Category:    a.Category,
+				Description: a.Description,
 				Type:        "income",
 			}
-			db.Instance.Create(&income)
-			return fmt.Sprintf("Cash inflow of $%.2f recorded.", income.Amount), "view_finance"
-
-		case "execute_launch_venture", "execute_db_launch_venture":
-			log.Println(" DEBUG: VENTURE EXECUTION STARTED ")
-			
-			// Log the raw data map to see what Python sent
-			log.Printf("RAW DATA FROM PYTHON: %+v", data)
+			if err := tx.Create(&income).Error; err != nil {
+				return "", "", fmt.Errorf("record income: %w", err)
+			}
+			return fmt.Sprintf("Cash inflow of %s recorded.", FormatMoney(income.Amount, currency)), "view_finance", nil
+		})
 
+	RegisterTool("execute_launch_venture", "Start a new venture campaign and queue its strategy for review.",
+		func(tx *gorm.DB, a launchVentureArgs) (string, string, error) {
 			venture := models.VentureCampaign{
-				Name:            utils.SafeString(data, "name"),
-				Category:        utils.SafeString(data, "category"),
-				StrategySummary: utils.SafeString(data, "strategy"), // Check if Python sends 'strategy' or 'strategy_summary'
-				ProjectedROI:    utils.SafeString(data, "projected_roi"),
-				Platform:        utils.SafeString(data, "platform"),
-				Status:          "Active",
-				RevenueEarned:   0.0,
+				Name:            a.Name,
+				Category:        a.Category,
+				StrategySummary: a.Strategy,
+				ProjectedROI:    a.ProjectedROI,
+				Platform:        a.Platform,
+				Status:          "Incubating",
+				RevenueEarned:   decimal.Zero,
+				Currency:        DefaultCurrency(),
 			}
-
-			log.Printf("MAPPED STRUCT: %+v", venture)
-
-			// Check for DB errors explicitly
-			result := db.Instance.Create(&venture)
-			if result.Error != nil {
-				log.Printf("!!! DATABASE ERROR: %v", result.Error)
-				return "Internal DB Error", ""
+			if err := tx.Create(&venture).Error; err != nil {
+				log.Printf("!!! DATABASE ERROR: %v", err)
+				return "", "", fmt.Errorf("launch venture: %w", err)
 			}
 
-			mirrorToActionCenter("Venture_Plan", "Review Strategy: "+venture.Name, venture.StrategySummary)
+			if err := mirrorToActionCenter(tx, "Venture_Plan", strconv.FormatUint(uint64(venture.ID), 10), "Review Strategy: "+venture.Name, venture.StrategySummary); err != nil {
+				return "", "", err
+			}
 			log.Printf("SUCCESS: Venture saved with ID: %d", venture.ID)
-			return fmt.Sprintf("Venture '%s' initialized.", venture.Name), "view_finance"
+			return fmt.Sprintf("Venture '%s' initialized.", venture.Name), "view_finance", nil
+		})
+	RegisterToolAlias("execute_db_launch_venture", "execute_launch_venture")
 
-		case "execute_record_savings":
-			amount := utils.ParseNumeric(data["amount"])
-			description := utils.SafeString(data, "description")
-			
+	RegisterTool("execute_record_savings", "Record venture profit and credit it to the matching venture.",
+		func(tx *gorm.DB, a recordSavingsArgs) (string, string, error) {
+			currency, _ := recordCurrency(a.Currency)
 			income := models.FinanceRecord{
-				Amount:      amount,
+				Amount:      utils.RoundMoney(a.Amount, currency),
+				Currency:    currency,
 				Category:    "Venture Profit",
-				Description: description,
+				Description: a.Description,
 				Type:        "income",
 			}
-			db.Instance.Create(&income)
+			if err := tx.Create(&income).Error; err != nil {
+				return "", "", fmt.Errorf("record savings: %w", err)
+			}
 
 			var v models.VentureCampaign
-			db.Instance.Where("name ILIKE ?", "%"+description+"%").First(&v)
+			tx.Where("name ILIKE ?", "%"+a.Description+"%").First(&v)
 			if v.ID != 0 {
-				db.Instance.Model(&v).Update("revenue_earned", v.RevenueEarned + amount)
+				// Revenue is kept in the venture's own currency.
+				fx, err := loadFXTable(tx)
+				if err != nil {
+					return "", "", err
+				}
+				earned, err := fx.Convert(income.Amount, currency, v.Currency, time.Now())
+				if err != nil {
+					return "", "", fmt.Errorf("credit %s: %w", v.Name, err)
+				}
+				tx.Model(&v).Update("revenue_earned", utils.RoundMoney(v.RevenueEarned.Add(earned), v.Currency))
 			}
 
-			return fmt.Sprintf("Profit of $%.2f realized and indexed.", amount), "view_finance"
-
+			return fmt.Sprintf("Profit of %s realized and indexed.", FormatMoney(income.Amount, currency)), "view_finance", nil
+		})
 
-		case "execute_db_save_knowledge":
+	RegisterTool("execute_db_save_knowledge", "Store a knowledge node for the Oracle.",
+		func(tx *gorm.DB, a saveKnowledgeArgs) (string, string, error) {
 			node := models.KnowledgeNode{
-				Topic: utils.SafeString(data, "topic"),
-				Content: utils.SafeString(data, "content"),
-				Tags: utils.SafeString(data, "tags"),
+				Topic:   a.Topic,
+				Content: a.Content,
+				Tags:    a.Tags,
+			}
+			if err := tx.Create(&node).Error; err != nil {
+				return "", "", fmt.Errorf("save knowledge: %w", err)
 			}
-			db.Instance.Create(&node)
-			return "Knowledge node indexed.", "view_overview"
+			return "Knowledge node indexed.", "view_overview", nil
+		})
 
-		case "execute_db_log_security":
+	RegisterTool("execute_db_log_security", "Flag a security issue for the Vanguard audit log.",
+		func(tx *gorm.DB, a logSecurityArgs) (string, string, error) {
 			audit := models.SecurityAudit{
-				Issue: utils.SafeString(data, "issue"),
-				Severity: utils.SafeString(data, "severity"),
-				Status: "Open",
+				Issue:    a.Issue,
+				Severity: a.Severity,
+				Status:   "Open",
 			}
-			db.Instance.Create(&audit)
-			return "Security vulnerability flagged.", "view_overview"
+			if err := tx.Create(&audit).Error; err != nil {
+				return "", "", fmt.Errorf("log security issue: %w", err)
+			}
+			return "Security vulnerability flagged.", "view_overview", nil
+		})
 
-		case "execute_db_save_code":
+	RegisterTool("execute_db_save_code", "Archive a code snippet or automation.",
+		func(tx *gorm.DB, a saveCodeArgs) (string, string, error) {
 			snip := models.CodeSnippet{
-				FileName: utils.SafeString(data, "file_name"),
-				Language: utils.SafeString(data, "language"),
-				Code: utils.SafeString(data, "code"),
-				Description: utils.SafeString(data, "description"),
+				FileName:    a.FileName,
+				Language:    a.Language,
+				Code:        a.Code,
+				Description: a.Description,
+			}
+			if err := tx.Create(&snip).Error; err != nil {
+				return "", "", fmt.Errorf("save code: %w", err)
 			}
-			db.Instance.Create(&snip)
-			return "Automation logic archived by Builder.", "view_overview"
+			return "Automation logic archived by Builder.", "view_overview", nil
+		})
 
-		case "execute_submit_for_review":
-			// Log for debugging
-			log.Printf("[EXECUTOR] Capturing Action: %s", utils.SafeString(data, "title"))
+	RegisterTool("execute_submit_for_review", "Queue a drafted action in the Action Center.",
+		func(tx *gorm.DB, a submitForReviewArgs) (string, string, error) {
+			log.Printf("[EXECUTOR] Capturing Action: %s", a.Title)
 
-			newAction := models.PendingAction{
-				// Check if Python sent 'type' or 'action_type'
-				Type:     utils.SafeString(data, "type"), 
-				Title:    utils.SafeString(data, "title"),
-				Content:  utils.SafeString(data, "content"),
-				Priority: utils.SafeString(data, "priority"),
-				Status:   "Pending",
+			actionType := a.Type
+			if actionType == "" {
+				actionType = a.ActionType
 			}
-
-			// Safety check: if 'type' is empty, try 'action_type'
-			if newAction.Type == "" {
-				newAction.Type = utils.SafeString(data, "action_type")
+			// These deployers act on a linked row, which only their own
+			// tools create.
+			if tool, ok := linkedActionTools[actionType]; ok {
+				return "", "", &ToolValidationError{Tool: "execute_submit_for_review", Fields: []FieldError{
+					{Field: "type", Reason: fmt.Sprintf("%s actions must be created with %s", actionType, tool)},
+				}}
 			}
 
-			if err := db.Instance.Create(&newAction).Error; err != nil {
+			newAction := models.PendingAction{
+				Type:     actionType,
+				Title:    a.Title,
+				Content:  a.Content,
+				Priority: a.Priority,
+				Status:   models.ActionPending,
+			}
+			if err := tx.Create(&newAction).Error; err != nil {
 				log.Printf("[DATABASE ERROR] PendingAction: %v", err)
-				return "Failed to save action to queue.", ""
+				return "", "", fmt.Errorf("queue action: %w", err)
 			}
 
 			// Emit event so the sidebar/overview pulse
-			EmitEvent("KERNEL", "Draft Ready: "+newAction.Title, "SUCCESS")
-
-			return fmt.Sprintf("Action center updated with: %s", newAction.Title), "view_overview"
-
-		}
+			EmitEvent(tx.Statement.Context, "KERNEL", "Draft Ready: "+newAction.Title, "SUCCESS")
 
-	return "", ""
+			return fmt.Sprintf("Action center updated with: %s", newAction.Title), "view_overview", nil
+		})
 }
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"gateway/db"
+	"gateway/models"
+	"gateway/utils"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type FinanceSummary struct {
+	Currency       string                 `json:"currency"` // everything below is in this currency
+	TotalExpenses  decimal.Decimal        `json:"total_expenses"`
+	TotalIncome    decimal.Decimal        `json:"total_income"` // recorded income plus venture revenue
+	VentureRevenue decimal.Decimal        `json:"venture_revenue"`
+	RecentRecords  []models.FinanceRecord `json:"recent_records"` // as recorded, unconverted
+	Unconverted    int                    `json:"unconverted"`    // records and ventures left out for want of an FX rate
+}
+
+// ventureRevenue totals revenue_earned in currency at today's rates, since
+// it is a running balance with no dates of its own. The total is not
+// rounded. Ventures in a currency without a rate are left out and counted
+// in skipped.
+func ventureRevenue(tx *gorm.DB, fx *FXTable, currency string) (total decimal.Decimal, skipped int, err error) {
+	var rows []struct {
+		Currency string
+		Total    decimal.Decimal
+		Count    int
+	}
+	err = tx.Model(&models.VentureCampaign{}).
+		Select("currency, COALESCE(sum(revenue_earned), 0) AS total, count(*) AS count").
+		Group("currency").Scan(&rows).Error
+	if err != nil {
+		return decimal.Zero, 0, err
+	}
+	for _, r := range rows {
+		v, err := fx.Convert(r.Total, r.Currency, currency, time.Now())
+		if errors.Is(err, ErrNoFXRate) {
+			skipped += r.Count
+			continue
+		}
+		if err != nil {
+			return decimal.Zero, 0, err
+		}
+		total = total.Add(v)
+	}
+	return total, skipped, nil
+}
+
+// VentureRevenue totals the revenue of the ventures visible to ctx in
+// currency, leaving out any it has no rate for.
+func VentureRevenue(ctx context.Context, currency string) (decimal.Decimal, error) {
+	fx, err := LoadFXTable()
+	if err != nil {
+		return decimal.Zero, err
+	}
+	total, _, err := ventureRevenue(db.For(ctx), fx, currency)
+	return utils.RoundMoney(total, currency), err
+}
+
+// BuildFinanceSummary totals the finance records and venture revenue of the
+// owner in ctx in currency, converting each day's records at that day's
+// rate. Records in a currency without a rate are left out of the totals
+// and counted in Unconverted rather than failing the summary.
+func BuildFinanceSummary(ctx context.Context, currency string) (*FinanceSummary, error) {
+	tx := db.For(ctx)
+	fx, err := LoadFXTable()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Type     string
+		Currency string
+		Day      time.Time
+		Total    decimal.Decimal
+		Count    int
+	}
+	err = tx.Model(&models.FinanceRecord{}).
+		Select("type, currency, date(created_at) AS day, sum(amount) AS total, count(*) AS count").
+		Where("type IN ?", []string{"expense", "income"}).
+		Group("type, currency, day").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FinanceSummary{Currency: currency}
+	for _, r := range rows {
+		v, err := fx.Convert(r.Total, r.Currency, currency, calendarDay(r.Day))
+		if errors.Is(err, ErrNoFXRate) {
+			s.Unconverted += r.Count
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if r.Type == "expense" {
+			s.TotalExpenses = s.TotalExpenses.Add(v)
+		} else {
+			s.TotalIncome = s.TotalIncome.Add(v)
+		}
+	}
+	venture, skipped, err := ventureRevenue(tx, fx, currency)
+	if err != nil {
+		return nil, err
+	}
+	s.VentureRevenue = venture
+	s.Unconverted += skipped
+	s.TotalIncome = s.TotalIncome.Add(s.VentureRevenue)
+
+	// Rounded once, after summing exactly.
+	s.TotalExpenses = utils.RoundMoney(s.TotalExpenses, currency)
+	s.TotalIncome = utils.RoundMoney(s.TotalIncome, currency)
+	s.VentureRevenue = utils.RoundMoney(s.VentureRevenue, currency)
+
+	if err := tx.Order("created_at desc").Limit(10).Find(&s.RecentRecords).Error; err != nil {
+		return nil, err
+	}
+	return s, nil
+}
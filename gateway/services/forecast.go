@@ -0,0 +1,352 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gateway/db"
+	"gateway/models"
+	"gateway/utils"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// The cash-flow forecast starts from the cash accounts' balance and walks
+// forward a day at a time. Recurring templates and future-dated income
+// records are taken as certain. Everyday spending is the daily average of
+// the last forecastLookbackDays of expenses not posted by a template; its
+// day-to-day spread widens the confidence band by the square root of the
+// days elapsed. Expenses matching an active expense template's name or
+// description, for about its amount, are left out too, so a bill imported
+// from a statement is not counted again as everyday spending. Amounts with
+// no FX rate into the forecast currency are skipped and counted.
+
+const forecastLookbackDays = 90
+
+// forecastZ is the normal quantile for the 80% band.
+const forecastZ = 1.2816
+
+// ForecastHorizons are the lengths a forecast can be asked for, in days.
+var ForecastHorizons = []int{30, 90, 180}
+
+// ValidForecastHorizon reports whether days is one of ForecastHorizons.
+func ValidForecastHorizon(days int) bool {
+	for _, h := range ForecastHorizons {
+		if days == h {
+			return true
+		}
+	}
+	return false
+}
+
+// CashFlowFloor is the balance below which a forecast day is flagged. Set
+// with CASHFLOW_FLOOR, in DefaultCurrency; default 0.
+func CashFlowFloor() decimal.Decimal {
+	return envDecimal("CASHFLOW_FLOOR", decimal.Zero)
+}
+
+// CategorySpend is a category's average everyday spending per day.
+type CategorySpend struct {
+	Category string          `json:"category"`
+	Daily    decimal.Decimal `json:"daily"`
+}
+
+// ForecastDay is the projected end-of-day balance.
+type ForecastDay struct {
+	Day        time.Time       `json:"day"`
+	Income     decimal.Decimal `json:"income"`   // recurring and future-dated income
+	Bills      decimal.Decimal `json:"bills"`    // recurring expenses
+	Spending   decimal.Decimal `json:"spending"` // expected everyday spending
+	Balance    decimal.Decimal `json:"balance"`
+	Low        decimal.Decimal `json:"low"`
+	High       decimal.Decimal `json:"high"`
+	BelowFloor bool            `json:"below_floor"` // Balance under the floor
+	AtRisk     bool            `json:"at_risk"`     // Low under the floor
+	Items      []string        `json:"items,omitempty"`
+}
+
+type CashFlowForecast struct {
+	Currency        string          `json:"currency"`
+	Days            int             `json:"days"`
+	StartingBalance decimal.Decimal `json:"starting_balance"`
+	Floor           decimal.Decimal `json:"floor"`
+	Confidence      float64         `json:"confidence"` // of the Low-High band
+	DailySpending   decimal.Decimal `json:"daily_spending"`
+	Categories      []CategorySpend `json:"categories"`
+	Series          []ForecastDay   `json:"series"`
+	BelowFloor      []time.Time     `json:"below_floor"` // days whose expected balance is under the floor
+	Lowest          ForecastDay     `json:"lowest"`
+	Unconverted     int             `json:"unconverted"` // accounts, scheduled items and past expenses left out for want of an FX rate
+}
+
+// ForecastOptions overrides the defaults of a forecast.
+type ForecastOptions struct {
+	Days            int
+	Currency        string
+	Floor           *decimal.Decimal // default CashFlowFloor
+	StartingBalance *decimal.Decimal // default the cash accounts' total
+}
+
+// cashBalance totals the owner's cash accounts in currency. Accounts with
+// no FX rate are left out and counted in skipped.
+func cashBalance(ctx context.Context, fx *FXTable, currency string) (total decimal.Decimal, skipped int, err error) {
+	var accounts []models.NetWorthAccount
+	if err := db.For(ctx).Where("kind = ?", models.AccountCash).Find(&accounts).Error; err != nil {
+		return decimal.Zero, 0, err
+	}
+	for _, a := range accounts {
+		v, err := fx.Convert(a.Balance, a.Currency, currency, time.Now())
+		if errors.Is(err, ErrNoFXRate) {
+			skipped++
+			continue
+		}
+		if err != nil {
+			return decimal.Zero, 0, fmt.Errorf("%s: %w", a.Name, err)
+		}
+		total = total.Add(v)
+	}
+	return total, skipped, nil
+}
+
+// spendingStats describes a day of everyday spending.
+type spendingStats struct {
+	mean        decimal.Decimal // per day
+	stddev      float64
+	categories  []CategorySpend
+	unconverted int // expenses without an FX rate, left out
+}
+
+// coveredByTemplate reports whether an expense is one an active expense
+// template already schedules: its description matches the template's name
+// or description once dates and reference numbers are stripped, and its
+// amount is within subscriptionAmountTolerance of the template's. A shared
+// category alone is not enough; one meal-kit template must not take all
+// food spending out of the forecast.
+func coveredByTemplate(fx *FXTable, templates []models.RecurringTransaction) func(r *models.FinanceRecord) bool {
+	byKey := map[string][]*models.RecurringTransaction{}
+	for i := range templates {
+		t := &templates[i]
+		if !t.Active || t.Type != "expense" {
+			continue
+		}
+		seen := map[string]bool{}
+		for _, d := range []string{t.Name, t.Description} {
+			if key := descriptionKey(d); key != "" && !seen[key] {
+				seen[key] = true
+				byKey[key] = append(byKey[key], t)
+			}
+		}
+	}
+	return func(r *models.FinanceRecord) bool {
+		for _, t := range byKey[descriptionKey(r.Description)] {
+			amount, err := fx.Convert(t.Amount, t.Currency, r.Currency, r.CreatedAt)
+			if err != nil {
+				continue
+			}
+			if r.Amount.Sub(amount).Abs().LessThanOrEqual(amount.Abs().Mul(decimal.NewFromFloat(subscriptionAmountTolerance))) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// everydaySpending averages expenses neither posted by nor covered by a
+// template over the lookback window, or since the first such expense if
+// that is later: the mean and standard deviation of a day's total and the
+// mean per category.
+func everydaySpending(ctx context.Context, fx *FXTable, currency string, today time.Time, templates []models.RecurringTransaction) (*spendingStats, error) {
+	from := today.AddDate(0, 0, -forecastLookbackDays)
+	var all []models.FinanceRecord
+	err := db.For(ctx).
+		Where("type = ? AND recurring_id IS NULL AND created_at >= ? AND created_at < ?", "expense", from, today).
+		Order("created_at asc").
+		Find(&all).Error
+	if err != nil {
+		return nil, err
+	}
+	covered := coveredByTemplate(fx, templates)
+	var records []models.FinanceRecord
+	for i := range all {
+		if !covered(&all[i]) {
+			records = append(records, all[i])
+		}
+	}
+	stats := &spendingStats{categories: []CategorySpend{}}
+	if len(records) == 0 {
+		return stats, nil
+	}
+
+	start := calendarDay(fxDay(records[0].CreatedAt))
+	days := int(math.Round(today.Sub(start).Hours() / 24))
+	if days < 1 {
+		days = 1
+	}
+	perDay := make([]decimal.Decimal, days)
+	perCategory := map[string]decimal.Decimal{}
+	total := decimal.Zero
+	for _, r := range records {
+		v, err := fx.Convert(r.Amount, r.Currency, currency, r.CreatedAt)
+		if errors.Is(err, ErrNoFXRate) {
+			stats.unconverted++
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		i := int(math.Round(calendarDay(fxDay(r.CreatedAt)).Sub(start).Hours() / 24))
+		if i >= 0 && i < days {
+			perDay[i] = perDay[i].Add(v)
+		}
+		perCategory[r.Category] = perCategory[r.Category].Add(v)
+		total = total.Add(v)
+	}
+
+	n := decimal.NewFromInt(int64(days))
+	stats.mean = total.Div(n)
+	var variance float64
+	for _, d := range perDay {
+		diff := d.Sub(stats.mean).InexactFloat64()
+		variance += diff * diff
+	}
+	stats.stddev = math.Sqrt(variance / float64(days))
+
+	for c, v := range perCategory {
+		stats.categories = append(stats.categories, CategorySpend{Category: c, Daily: utils.RoundMoney(v.Div(n), currency)})
+	}
+	sort.Slice(stats.categories, func(i, j int) bool { return stats.categories[i].Daily.GreaterThan(stats.categories[j].Daily) })
+	return stats, nil
+}
+
+// ForecastCashFlow projects the owner's cash balance for opts.Days days,
+// starting today.
+func ForecastCashFlow(ctx context.Context, opts ForecastOptions) (*CashFlowForecast, error) {
+	if !ValidForecastHorizon(opts.Days) {
+		return nil, fmt.Errorf("days must be one of %v", ForecastHorizons)
+	}
+	currency := opts.Currency
+	if currency == "" {
+		currency = DefaultCurrency()
+	}
+	fx, err := LoadFXTable()
+	if err != nil {
+		return nil, err
+	}
+
+	f := &CashFlowForecast{Currency: currency, Days: opts.Days, Confidence: 0.8, BelowFloor: []time.Time{}}
+	if opts.StartingBalance != nil {
+		f.StartingBalance = *opts.StartingBalance
+	} else if f.StartingBalance, f.Unconverted, err = cashBalance(ctx, fx, currency); err != nil {
+		return nil, err
+	}
+	if opts.Floor != nil {
+		f.Floor = *opts.Floor
+	} else if f.Floor, err = fx.Convert(CashFlowFloor(), DefaultCurrency(), currency, time.Now()); err != nil {
+		return nil, err
+	}
+
+	var templates []models.RecurringTransaction
+	if err := db.For(ctx).Where("active = ?", true).Find(&templates).Error; err != nil {
+		return nil, err
+	}
+
+	today := calendarDay(fxDay(time.Now()))
+	end := today.AddDate(0, 0, opts.Days)
+	spending, err := everydaySpending(ctx, fx, currency, today, templates)
+	if err != nil {
+		return nil, err
+	}
+	f.DailySpending = utils.RoundMoney(spending.mean, currency)
+	f.Categories = spending.categories
+	f.Unconverted += spending.unconverted
+
+	f.Series = make([]ForecastDay, opts.Days)
+	for i := range f.Series {
+		f.Series[i] = ForecastDay{Day: today.AddDate(0, 0, i), Spending: spending.mean}
+	}
+	dayIndex := func(t time.Time) int {
+		return int(math.Round(calendarDay(fxDay(t)).Sub(today).Hours() / 24))
+	}
+
+	for _, o := range occurrencesBetween(templates, today, end) {
+		i := dayIndex(o.DueAt)
+		if i < 0 || i >= opts.Days {
+			continue
+		}
+		v, err := fx.Convert(o.Amount, o.Currency, currency, o.DueAt)
+		if errors.Is(err, ErrNoFXRate) {
+			f.Unconverted++
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		d := &f.Series[i]
+		if o.Type == "income" {
+			d.Income = d.Income.Add(v)
+		} else {
+			d.Bills = d.Bills.Add(v)
+		}
+		d.Items = append(d.Items, fmt.Sprintf("%s %s", o.Name, FormatMoney(v, currency)))
+	}
+
+	var known []models.FinanceRecord
+	err = db.For(ctx).
+		Where("type = ? AND created_at >= ? AND created_at < ?", "income", time.Now(), end).
+		Find(&known).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range known {
+		i := dayIndex(r.CreatedAt)
+		if i < 0 || i >= opts.Days {
+			continue
+		}
+		v, err := fx.Convert(r.Amount, r.Currency, currency, r.CreatedAt)
+		if errors.Is(err, ErrNoFXRate) {
+			f.Unconverted++
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		f.Series[i].Income = f.Series[i].Income.Add(v)
+		f.Series[i].Items = append(f.Series[i].Items, fmt.Sprintf("%s %s", r.Description, FormatMoney(v, currency)))
+	}
+
+	f.project(spending.stddev)
+	return f, nil
+}
+
+// project runs the balance forward through the series, from unrounded
+// StartingBalance and day amounts, and sets each day's band, floor flags
+// and the lowest day. stddev is the daily spending's standard deviation;
+// day i's band is forecastZ of it times the square root of i+1 days.
+func (f *CashFlowForecast) project(stddev float64) {
+	balance := f.StartingBalance
+	for i := range f.Series {
+		d := &f.Series[i]
+		balance = balance.Add(d.Income).Sub(d.Bills).Sub(d.Spending)
+		spread := decimal.NewFromFloat(forecastZ * stddev * math.Sqrt(float64(i+1)))
+
+		d.Income = utils.RoundMoney(d.Income, f.Currency)
+		d.Bills = utils.RoundMoney(d.Bills, f.Currency)
+		d.Spending = utils.RoundMoney(d.Spending, f.Currency)
+		d.Balance = utils.RoundMoney(balance, f.Currency)
+		d.Low = utils.RoundMoney(balance.Sub(spread), f.Currency)
+		d.High = utils.RoundMoney(balance.Add(spread), f.Currency)
+		d.BelowFloor = d.Balance.LessThan(f.Floor)
+		d.AtRisk = d.Low.LessThan(f.Floor)
+		if d.BelowFloor {
+			f.BelowFloor = append(f.BelowFloor, d.Day)
+		}
+		if i == 0 || d.Balance.LessThan(f.Lowest.Balance) {
+			f.Lowest = *d
+		}
+	}
+	f.StartingBalance = utils.RoundMoney(f.StartingBalance, f.Currency)
+	f.Floor = utils.RoundMoney(f.Floor, f.Currency)
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"gateway/models"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestCoveredByTemplate(t *testing.T) {
+	templates := []models.RecurringTransaction{
+		{Name: "Rent", Description: "ACME PROPERTY MGMT", Type: "expense", Category: "Housing",
+			Amount: decimal.NewFromInt(1500), Currency: "USD", Active: true},
+		{Name: "Meal kit", Type: "expense", Category: "Food",
+			Amount: decimal.NewFromInt(60), Currency: "USD", Active: true},
+		{Name: "Gym", Type: "expense", Category: "Health",
+			Amount: decimal.NewFromInt(40), Currency: "USD", Active: false},
+		{Name: "Salary", Type: "income", Category: "Food",
+			Amount: decimal.NewFromInt(3000), Currency: "USD", Active: true},
+	}
+	covered := coveredByTemplate(&FXTable{rates: map[[2]string][]fxQuote{}}, templates)
+
+	tests := []struct {
+		name        string
+		description string
+		category    string
+		amount      int64
+		currency    string
+		want        bool
+	}{
+		{"statement line for rent", "ACME PROPERTY MGMT 10/26 #4411", "Housing", 1500, "USD", true},
+		{"rent within tolerance", "acme property mgmt", "Other", 1440, "USD", true},
+		{"rent far off", "ACME PROPERTY MGMT", "Housing", 900, "USD", false},
+		{"by template name", "Meal Kit", "Food", 62, "USD", true},
+		{"same category only", "Corner grocery", "Food", 60, "USD", false},
+		{"inactive template", "Gym", "Health", 40, "USD", false},
+		{"income template", "Salary", "Food", 3000, "USD", false},
+		{"no rate into the template currency", "Rent", "Housing", 1500, "EUR", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &models.FinanceRecord{
+				Description: tt.description, Category: tt.category,
+				Amount: decimal.NewFromInt(tt.amount), Currency: tt.currency, Type: "expense",
+			}
+			r.CreatedAt = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+			if got := covered(r); got != tt.want {
+				t.Fatalf("covered = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForecastProject(t *testing.T) {
+	first := time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local)
+	// series builds days from "income/bills/spending" strings.
+	series := func(days ...string) []ForecastDay {
+		out := make([]ForecastDay, len(days))
+		for i, d := range days {
+			v := strings.Split(d, "/")
+			out[i] = ForecastDay{Day: first.AddDate(0, 0, i),
+				Income: dec(v[0]), Bills: dec(v[1]), Spending: dec(v[2])}
+		}
+		return out
+	}
+	third := decimal.NewFromInt(10).Div(decimal.NewFromInt(3))
+
+	tests := []struct {
+		name       string
+		start      string
+		floor      string
+		stddev     float64
+		series     []ForecastDay
+		bands      []string // balance low high, per day
+		belowFloor []int
+		atRisk     []int
+		lowest     int
+	}{
+		{"no spread", "100", "75", 0, series("0/0/10", "0/0/10", "0/0/10"),
+			[]string{"90 90 90", "80 80 80", "70 70 70"}, []int{2}, []int{2}, 2},
+		// The band is 1.2816 * 10 * sqrt(n) either side on day n.
+		{"band widens with the square root of the days", "1000", "980", 10, series("0/0/0", "0/0/0", "0/0/0", "0/0/0"),
+			[]string{"1000 987.18 1012.82", "1000 981.88 1018.12", "1000 977.8 1022.2", "1000 974.37 1025.63"},
+			nil, []int{2, 3}, 0},
+		{"bills and income move the balance on their day", "50", "0", 0, series("0/80/5", "100/0/5", "0/0/5"),
+			[]string{"-35 -35 -35", "60 60 60", "55 55 55"}, []int{0}, []int{0}, 0},
+		{"rounding does not accumulate", "0", "-100", 0, []ForecastDay{
+			{Day: first, Spending: third}, {Day: first.AddDate(0, 0, 1), Spending: third}, {Day: first.AddDate(0, 0, 2), Spending: third}},
+			[]string{"-3.33 -3.33 -3.33", "-6.67 -6.67 -6.67", "-10 -10 -10"}, nil, nil, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &CashFlowForecast{Currency: "USD", StartingBalance: dec(tt.start), Floor: dec(tt.floor),
+				Series: tt.series, BelowFloor: []time.Time{}}
+			f.project(tt.stddev)
+
+			var below, risk []int
+			for i, d := range f.Series {
+				got := d.Balance.String() + " " + d.Low.String() + " " + d.High.String()
+				if got != tt.bands[i] {
+					t.Errorf("day %d = %s, want %s", i, got, tt.bands[i])
+				}
+				if d.BelowFloor {
+					below = append(below, i)
+				}
+				if d.AtRisk {
+					risk = append(risk, i)
+				}
+			}
+			if !reflect.DeepEqual(below, tt.belowFloor) || !reflect.DeepEqual(risk, tt.atRisk) {
+				t.Errorf("below floor %v, at risk %v, want %v and %v", below, risk, tt.belowFloor, tt.atRisk)
+			}
+			if len(f.BelowFloor) != len(tt.belowFloor) {
+				t.Errorf("BelowFloor = %v, want %d days", f.BelowFloor, len(tt.belowFloor))
+			}
+			if !f.Lowest.Day.Equal(f.Series[tt.lowest].Day) {
+				t.Errorf("lowest = %s, want day %d", f.Lowest.Day.Format("2006-01-02"), tt.lowest)
+			}
+		})
+	}
+}
@@ -0,0 +1,375 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gateway/db"
+	"gateway/models"
+	"gateway/utils"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FX rates are cached in fx_rates, entered by hand or loaded from a file;
+// nothing is fetched from the network. An amount converts at the latest
+// rate on or before the day it was recorded, or the earliest rate known if
+// it predates them all. Pairs without a rate either way are crossed
+// through USD.
+
+var ErrNoFXRate = errors.New("no FX rate")
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// DefaultCurrency is the currency of amounts given without one, and the
+// default reporting currency. Set with DEFAULT_CURRENCY.
+func DefaultCurrency() string {
+	if c, err := NormalizeCurrency(os.Getenv("DEFAULT_CURRENCY")); err == nil && c != "" {
+		return c
+	}
+	return "USD"
+}
+
+// NormalizeCurrency upper-cases an ISO 4217 code. Empty stays empty.
+func NormalizeCurrency(c string) (string, error) {
+	c = strings.ToUpper(strings.TrimSpace(c))
+	if c != "" && !currencyCode.MatchString(c) {
+		return "", fmt.Errorf("currency %q is not a three-letter code", c)
+	}
+	return c, nil
+}
+
+// recordCurrency normalizes c, defaulting to DefaultCurrency.
+func recordCurrency(c string) (string, error) {
+	c, err := NormalizeCurrency(c)
+	if c == "" && err == nil {
+		c = DefaultCurrency()
+	}
+	return c, err
+}
+
+// FormatMoney renders amount rounded to currency's minor unit with its
+// code, e.g. "12.50 EUR". USD keeps the dollar sign the replies have
+// always used.
+func FormatMoney(amount decimal.Decimal, currency string) string {
+	text := utils.RoundMoney(amount, currency).StringFixed(utils.CurrencyPlaces(currency))
+	if currency == "" || currency == "USD" {
+		return "$" + text
+	}
+	return text + " " + currency
+}
+
+// fxDay is the local calendar day of t, as the date fx_rates stores.
+func fxDay(t time.Time) time.Time {
+	y, m, d := t.In(time.Local).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// calendarDay reads a date column, which arrives as midnight UTC, back
+// into local midnight.
+func calendarDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+// ValidateFXRate normalizes the pair and day and checks the rate is usable.
+func ValidateFXRate(r *models.FXRate) error {
+	var err error
+	if r.Base, err = NormalizeCurrency(r.Base); err != nil {
+		return err
+	}
+	if r.Quote, err = NormalizeCurrency(r.Quote); err != nil {
+		return err
+	}
+	if r.Base == "" || r.Quote == "" {
+		return errors.New("base and quote are required")
+	}
+	if r.Base == r.Quote {
+		return errors.New("base and quote must differ")
+	}
+	if !r.Rate.IsPositive() {
+		return errors.New("rate must be positive")
+	}
+	if r.AsOf.IsZero() {
+		r.AsOf = time.Now()
+	}
+	r.AsOf = fxDay(r.AsOf)
+	if r.Source == "" {
+		r.Source = "manual"
+	}
+	return nil
+}
+
+// SaveFXRates validates and stores rates, replacing any already known for
+// the same pair and day.
+func SaveFXRates(rates []models.FXRate) (int, error) {
+	for i := range rates {
+		if err := ValidateFXRate(&rates[i]); err != nil {
+			return 0, fmt.Errorf("rate %d: %w", i+1, err)
+		}
+		rates[i].UpdatedAt = time.Now()
+	}
+	if len(rates) == 0 {
+		return 0, nil
+	}
+	err := db.Instance.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "base"}, {Name: "quote"}, {Name: "as_of"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "updated_at"}),
+	}).CreateInBatches(rates, 500).Error
+	if err != nil {
+		return 0, fmt.Errorf("store rates: %w", err)
+	}
+	return len(rates), nil
+}
+
+// fxRateFile is the JSON most rate APIs export: every rate against one base
+// on one date.
+type fxRateFile struct {
+	Base  string                     `json:"base"`
+	Date  string                     `json:"date"`
+	Rates map[string]decimal.Decimal `json:"rates"`
+}
+
+func parseFXDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("date %q must look like 2006-01-02", s)
+	}
+	return t, nil
+}
+
+// ParseFXRates reads rates from a .json or .csv file. JSON is either
+// {"base": "USD", "date": "2026-10-01", "rates": {"EUR": 0.92}} or a list
+// of {"base", "quote", "rate", "as_of"}. CSV rows are base,quote,rate and
+// an optional date, with or without a header. A missing date means today.
+func ParseFXRates(name string, r io.Reader) ([]models.FXRate, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return parseFXJSON(r)
+	case ".csv":
+		return parseFXCSV(r)
+	}
+	return nil, errors.New("rate files must be .json or .csv")
+}
+
+func parseFXJSON(r io.Reader) ([]models.FXRate, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+		var list []struct {
+			Base  string          `json:"base"`
+			Quote string          `json:"quote"`
+			Rate  decimal.Decimal `json:"rate"`
+			AsOf  string          `json:"as_of"`
+		}
+		if err := json.Unmarshal(body, &list); err != nil {
+			return nil, err
+		}
+		rates := make([]models.FXRate, len(list))
+		for i, l := range list {
+			day, err := parseFXDate(l.AsOf)
+			if err != nil {
+				return nil, fmt.Errorf("rate %d: %w", i+1, err)
+			}
+			rates[i] = models.FXRate{Base: l.Base, Quote: l.Quote, Rate: l.Rate, AsOf: day}
+		}
+		return rates, nil
+	}
+
+	var file fxRateFile
+	if err := json.Unmarshal(body, &file); err != nil {
+		return nil, err
+	}
+	day, err := parseFXDate(file.Date)
+	if err != nil {
+		return nil, err
+	}
+	quotes := make([]string, 0, len(file.Rates))
+	for q := range file.Rates {
+		quotes = append(quotes, q)
+	}
+	sort.Strings(quotes)
+	var rates []models.FXRate
+	for _, q := range quotes {
+		if strings.EqualFold(q, file.Base) {
+			continue // rate files often list the base at 1
+		}
+		rates = append(rates, models.FXRate{Base: file.Base, Quote: q, Rate: file.Rates[q], AsOf: day})
+	}
+	return rates, nil
+}
+
+func parseFXCSV(r io.Reader) ([]models.FXRate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var rates []models.FXRate
+	for n, rec := range records {
+		if len(rec) < 3 {
+			return nil, fmt.Errorf("line %d: want base,quote,rate[,date]", n+1)
+		}
+		rate, err := decimal.NewFromString(strings.TrimSpace(rec[2]))
+		if err != nil {
+			if n == 0 {
+				continue // header
+			}
+			return nil, fmt.Errorf("line %d: bad rate %q", n+1, rec[2])
+		}
+		var day time.Time
+		if len(rec) > 3 {
+			if day, err = parseFXDate(strings.TrimSpace(rec[3])); err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+		}
+		rates = append(rates, models.FXRate{Base: rec[0], Quote: rec[1], Rate: rate, AsOf: day})
+	}
+	return rates, nil
+}
+
+// LoadFXRatesFile parses and stores the rates in path.
+func LoadFXRatesFile(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	rates, err := ParseFXRates(path, f)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	for i := range rates {
+		rates[i].Source = filepath.Base(path)
+	}
+	return SaveFXRates(rates)
+}
+
+// loadFXRates backs the fx_rates task, reloading FX_RATES_FILE so another
+// process can drop fresh rates there.
+func loadFXRates(ctx context.Context) (string, error) {
+	path := os.Getenv("FX_RATES_FILE")
+	if path == "" {
+		return "FX_RATES_FILE is not set; nothing loaded.", nil
+	}
+	n, err := LoadFXRatesFile(path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d FX rate(s) loaded from %s.", n, filepath.Base(path)), nil
+}
+
+type fxQuote struct {
+	day  time.Time
+	rate decimal.Decimal
+}
+
+// FXTable is a snapshot of every cached rate, oldest first per pair.
+type FXTable struct {
+	rates map[[2]string][]fxQuote
+}
+
+func loadFXTable(tx *gorm.DB) (*FXTable, error) {
+	var rows []models.FXRate
+	if err := tx.Order("as_of asc").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("load FX rates: %w", err)
+	}
+	t := &FXTable{rates: map[[2]string][]fxQuote{}}
+	for _, r := range rows {
+		key := [2]string{r.Base, r.Quote}
+		t.rates[key] = append(t.rates[key], fxQuote{day: fxDay(calendarDay(r.AsOf)), rate: r.Rate})
+	}
+	return t, nil
+}
+
+// LoadFXTable reads the rate cache for a batch of conversions.
+func LoadFXTable() (*FXTable, error) {
+	return loadFXTable(db.Instance)
+}
+
+// pickQuote finds the latest quote on or before day, or failing that the
+// earliest one.
+func pickQuote(quotes []fxQuote, day time.Time) (fxQuote, bool) {
+	i := sort.Search(len(quotes), func(i int) bool { return quotes[i].day.After(day) })
+	if i == 0 {
+		return quotes[0], false
+	}
+	return quotes[i-1], true
+}
+
+// direct looks for a rate between from and to quoted either way round,
+// preferring whichever is the more recent as of day.
+func (t *FXTable) direct(from, to string, day time.Time) (decimal.Decimal, bool) {
+	if from == to {
+		return decimal.NewFromInt(1), true
+	}
+	var best fxQuote
+	var bestBefore, found bool
+	consider := func(quotes []fxQuote, invert bool) {
+		if len(quotes) == 0 {
+			return
+		}
+		q, before := pickQuote(quotes, day)
+		if invert {
+			q.rate = decimal.NewFromInt(1).Div(q.rate)
+		}
+		better := !found ||
+			(before && !bestBefore) ||
+			(before && bestBefore && q.day.After(best.day)) ||
+			(!before && !bestBefore && q.day.Before(best.day))
+		if better {
+			best, bestBefore, found = q, before, true
+		}
+	}
+	consider(t.rates[[2]string{from, to}], false)
+	consider(t.rates[[2]string{to, from}], true)
+	return best.rate, found
+}
+
+// Rate is the price of one unit of from in to on the day of at.
+func (t *FXTable) Rate(from, to string, at time.Time) (decimal.Decimal, error) {
+	day := fxDay(at)
+	if r, ok := t.direct(from, to, day); ok {
+		return r, nil
+	}
+	if a, ok := t.direct(from, "USD", day); ok {
+		if b, ok := t.direct("USD", to, day); ok {
+			return a.Mul(b), nil
+		}
+	}
+	return decimal.Zero, fmt.Errorf("%w for %s/%s", ErrNoFXRate, from, to)
+}
+
+// Convert turns amount in from into to at the rate for at. The result is
+// not rounded, so converted amounts can be summed exactly first.
+func (t *FXTable) Convert(amount decimal.Decimal, from, to string, at time.Time) (decimal.Decimal, error) {
+	if amount.IsZero() || from == to {
+		return amount, nil
+	}
+	r, err := t.Rate(from, to, at)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return amount.Mul(r), nil
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"gateway/db"
+	"gateway/models"
+	"gateway/utils"
+	"log"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// usdPrices fetches the last USD price for each asset. Assets the exchange
+// has no USD market for are left out.
+func usdPrices(assets []string) map[string]decimal.Decimal {
+	prices := map[string]decimal.Decimal{}
+	for _, a := range assets {
+		p, err := ActiveExchange().USDPrice(a)
+		if err != nil {
+			log.Printf("[HOLDINGS] No USD price for %s: %v", a, err)
+			continue
+		}
+		prices[a] = p
+	}
+	return prices
+}
+
+// averageCosts replays USD trades with the average-cost method and returns
+// the USD cost per unit still held for each asset.
+func averageCosts(trades []models.ExchangeTrade) map[string]decimal.Decimal {
+	type book struct{ qty, cost decimal.Decimal }
+	books := map[string]*book{}
+	for _, t := range trades {
+		asset, ok := ActiveExchange().USDBase(t.Pair)
+		if !ok {
+			continue
+		}
+		b := books[asset]
+		if b == nil {
+			b = &book{}
+			books[asset] = b
+		}
+		switch t.Type {
+		case "buy":
+			b.qty = b.qty.Add(t.Volume)
+			b.cost = b.cost.Add(t.Cost).Add(t.Fee)
+		case "sell":
+			left := decimal.Max(b.qty.Sub(t.Volume), decimal.Zero)
+			if b.qty.IsPositive() {
+				b.cost = b.cost.Mul(left).Div(b.qty)
+			}
+			b.qty = left
+		}
+	}
+
+	unit := map[string]decimal.Decimal{}
+	for asset, b := range books {
+		if b.qty.IsPositive() {
+			unit[asset] = b.cost.Div(b.qty)
+		}
+	}
+	return unit
+}
+
+// unknownCostAssets lists the assets some of which were acquired other
+// than by a USD trade: deposited, or bought through a crypto or non-USD
+// fiat pair. averageCosts cannot price those units.
+func unknownCostAssets(tx *gorm.DB, trades []models.ExchangeTrade) (map[string]bool, error) {
+	var other []string
+	for _, t := range trades {
+		if _, ok := ActiveExchange().USDBase(t.Pair); !ok {
+			other = append(other, t.TxID)
+		}
+	}
+	var assets []string
+	err := tx.Model(&models.LedgerEntry{}).
+		Where("amount > 0 AND asset <> ? AND (type = ? OR (type = ? AND ref_id IN ?))", "USD", "deposit", "trade", append(other, "")).
+		Distinct().Pluck("asset", &assets).Error
+	if err != nil {
+		return nil, err
+	}
+	unknown := map[string]bool{}
+	for _, a := range assets {
+		unknown[a] = true
+	}
+	return unknown, nil
+}
+
+// SyncHoldings replaces the exchange owner's CryptoHoldings with the
+// exchange's current balances, valued at ticker prices, with cost basis from
+// the imported trade history. Assets no longer held are removed. Returns the
+// number of assets held.
+func SyncHoldings(ctx context.Context) (int, error) {
+	ctx, err := exchangeContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return syncHoldings(db.For(ctx))
+}
+
+func syncHoldings(tx *gorm.DB) (int, error) {
+	balances, err := ActiveExchange().Balances()
+	if err != nil {
+		return 0, err
+	}
+	assets := make([]string, 0, len(balances))
+	for a, amount := range balances {
+		if amount.IsPositive() {
+			assets = append(assets, a)
+		}
+	}
+
+	prices := usdPrices(assets)
+	var trades []models.ExchangeTrade
+	if err := tx.Order("time asc").Find(&trades).Error; err != nil {
+		return 0, err
+	}
+	unitCost := averageCosts(trades)
+	unknown, err := unknownCostAssets(tx, trades)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	err = tx.Transaction(func(tx *gorm.DB) error {
+		for _, a := range assets {
+			var h models.CryptoHoldings
+			tx.Where(models.CryptoHoldings{Asset: a}).FirstOrInit(&h)
+			balance := utils.RoundCrypto(balances[a])
+			if price, ok := prices[a]; ok {
+				h.USDValue = utils.RoundMoney(balance.Mul(price), "USD")
+				h.PricedAt, h.PriceStale = &now, false
+			} else if h.Balance.IsPositive() {
+				// Keep the last known price rather than dropping the asset to zero.
+				h.USDValue = utils.RoundMoney(h.USDValue.Div(h.Balance).Mul(balance), "USD")
+				h.PriceStale = true
+			} else {
+				h.PriceStale = true
+			}
+			h.Balance = balance
+
+			switch {
+			case a == "USD":
+				basis := h.Balance
+				h.CostBasis = &basis
+			case unknown[a]:
+				h.CostBasis = nil
+			default:
+				basis := utils.RoundMoney(h.Balance.Mul(unitCost[a]), "USD")
+				h.CostBasis = &basis
+			}
+			if err := tx.Save(&h).Error; err != nil {
+				return err
+			}
+		}
+		// Drops sold-out assets and rows stored under raw Kraken codes.
+		return tx.Where("asset NOT IN ?", append(assets, "")).Delete(&models.CryptoHoldings{}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(assets), nil
+}
+
+type HoldingValue struct {
+	models.CryptoHoldings
+	Price         decimal.Decimal `json:"price"`
+	UnrealizedPnL decimal.Decimal `json:"unrealized_pnl"`
+	UnrealizedPct float64         `json:"unrealized_pct"`
+}
+
+// PortfolioValue totals the stored holdings. P&L only counts assets with a
+// known cost basis; the rest are listed in UnknownCostBasis, and those
+// valued at an old price in StalePrices.
+type PortfolioValue struct {
+	Holdings           []HoldingValue  `json:"holdings"`
+	TotalValue         decimal.Decimal `json:"total_value"`
+	TotalCostBasis     decimal.Decimal `json:"total_cost_basis"`
+	TotalUnrealizedPnL decimal.Decimal `json:"total_unrealized_pnl"`
+	UnknownCostBasis   []string        `json:"unknown_cost_basis"`
+	StalePrices        []string        `json:"stale_prices"`
+}
+
+func ValueHoldings(ctx context.Context) (*PortfolioValue, error) {
+	var holdings []models.CryptoHoldings
+	if err := db.For(ctx).Order("usd_value desc").Find(&holdings).Error; err != nil {
+		return nil, err
+	}
+
+	out := &PortfolioValue{Holdings: make([]HoldingValue, len(holdings)), UnknownCostBasis: []string{}, StalePrices: []string{}}
+	for i, h := range holdings {
+		v := HoldingValue{CryptoHoldings: h}
+		if h.Balance.IsPositive() {
+			v.Price = utils.RoundCrypto(h.USDValue.Div(h.Balance))
+		}
+		switch {
+		case h.CostBasis == nil:
+			out.UnknownCostBasis = append(out.UnknownCostBasis, h.Asset)
+		case h.CostBasis.IsPositive():
+			v.UnrealizedPnL = h.USDValue.Sub(*h.CostBasis)
+			v.UnrealizedPct = v.UnrealizedPnL.Div(*h.CostBasis).Mul(decimal.NewFromInt(100)).Round(2).InexactFloat64()
+			out.TotalCostBasis = out.TotalCostBasis.Add(*h.CostBasis)
+			out.TotalUnrealizedPnL = out.TotalUnrealizedPnL.Add(v.UnrealizedPnL)
+		}
+		if h.PriceStale {
+			out.StalePrices = append(out.StalePrices, h.Asset)
+		}
+		out.TotalValue = out.TotalValue.Add(h.USDValue)
+		out.Holdings[i] = v
+	}
+	return out, nil
+}
+
+// SyncKraken imports new ledger entries and trades, then refreshes holdings
+// from them, for the exchange owner. It is what both the kraken_sync job and
+// /finance/sync run.
+func SyncKraken(ctx context.Context) (*ImportSummary, int, error) {
+	ctx, err := exchangeContext(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	summary, err := importKrakenHistory(ctx)
+	if err != nil {
+		// Holdings can still be refreshed from balances and stored trades.
+		log.Printf("[HOLDINGS] History import failed: %v", err)
+	}
+	held, err := syncHoldings(db.For(ctx))
+	if err != nil {
+		return summary, 0, err
+	}
+	return summary, held, nil
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"gateway/models"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// subscriberBuffer is how many events a slow subscriber may lag behind
+// before it starts missing live events. Missed events can be recovered by
+// reconnecting with Last-Event-ID.
+const subscriberBuffer = 64
+
+// EventFilter narrows a subscription. Empty lists match everything;
+// comparisons are case-insensitive. System events (no owner) always pass the
+// owner check.
+type EventFilter struct {
+	Owner   uuid.UUID
+	Sources []string
+	Levels  []string
+}
+
+func (f EventFilter) Match(ev models.SystemEvent) bool {
+	if ev.OwnerID != nil && *ev.OwnerID != f.Owner {
+		return false
+	}
+	return matchAny(f.Sources, ev.Source) && matchAny(f.Levels, ev.Level)
+}
+
+func matchAny(allowed []string, v string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(a, v) {
+			return true
+		}
+	}
+	return false
+}
+
+type Subscription struct {
+	C      chan models.SystemEvent
+	filter EventFilter
+	hub    *EventHub
+}
+
+// Close detaches the subscription from its hub.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	if _, ok := s.hub.subs[s]; ok {
+		delete(s.hub.subs, s)
+		close(s.C)
+	}
+}
+
+// EventHub fans SystemEvents out to in-process subscribers.
+type EventHub struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+func NewEventHub() *EventHub {
+	return &EventHub{subs: map[*Subscription]struct{}{}}
+}
+
+// Events is the hub EmitEvent publishes to.
+var Events = NewEventHub()
+
+func (h *EventHub) Subscribe(filter EventFilter) *Subscription {
+	sub := &Subscription{
+		C:      make(chan models.SystemEvent, subscriberBuffer),
+		filter: filter,
+		hub:    h,
+	}
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+// Publish never blocks; a subscriber with a full buffer drops the event.
+func (h *EventHub) Publish(ev models.SystemEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subs {
+		if !sub.filter.Match(ev) {
+			continue
+		}
+		select {
+		case sub.C <- ev:
+		default:
+			log.Printf("[EVENTS] Subscriber lagging, dropped event %d", ev.ID)
+		}
+	}
+}
@@ -1,29 +1,35 @@
 package services
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"gateway/models"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
-	"gateway/utils"
+
+	"github.com/shopspring/decimal"
 )
 
 type Candle struct {
-	Time   int64   `json:"time"`
-	Open   float64 `json:"open"`
-	High   float64 `json:"high"`
-	Low    float64 `json:"low"`
-	Close  float64 `json:"close"`
-	Volume float64 `json:"volume"`
+	Time   int64           `json:"time"`
+	Open   decimal.Decimal `json:"open"`
+	High   decimal.Decimal `json:"high"`
+	Low    decimal.Decimal `json:"low"`
+	Close  decimal.Decimal `json:"close"`
+	Volume decimal.Decimal `json:"volume"`
 }
 
 func getKrakenSignature(urlPath string, values url.Values, secret string) (string, error) {
@@ -41,117 +47,448 @@ func getKrakenSignature(urlPath string, values url.Values, secret string) (strin
 	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
 }
 
-func FetchKrakenBalances() (map[string]string, error) {
-	apiKey := os.Getenv("KRAKEN_API_KEY")
-	secret := os.Getenv("KRAKEN_SECRET_KEY")
-	urlPath := "/0/private/Balance"
+// Kraken is the Exchange adapter for Kraken's REST API. BaseURL can point
+// at the fake exchange (see gateway/fakeexchange) to run offline.
+type Kraken struct {
+	BaseURL string
+	APIKey  string
+	Secret  string
+	Client  *http.Client
+}
+
+// NewKraken reads KRAKEN_BASE_URL, KRAKEN_API_KEY and KRAKEN_SECRET_KEY.
+func NewKraken() *Kraken {
+	base := os.Getenv("KRAKEN_BASE_URL")
+	if base == "" {
+		base = "https://api.kraken.com"
+	}
+	return &Kraken{
+		BaseURL: strings.TrimRight(base, "/"),
+		APIKey:  os.Getenv("KRAKEN_API_KEY"),
+		Secret:  os.Getenv("KRAKEN_SECRET_KEY"),
+		Client:  &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (k *Kraken) Name() string { return "kraken" }
+
+// krakenLegacyCodes are Kraken's X/Z-prefixed asset codes and the aliases
+// it uses for a few assets.
+var krakenLegacyCodes = map[string]string{
+	"XXBT": "BTC", "XBT": "BTC", "XXDG": "DOGE", "XDG": "DOGE",
+	"XETH": "ETH", "ETH2": "ETH", "XETC": "ETC", "XLTC": "LTC",
+	"XXRP": "XRP", "XXLM": "XLM", "XXMR": "XMR", "XZEC": "ZEC",
+	"XREP": "REP", "XMLN": "MLN",
+	"ZUSD": "USD", "ZEUR": "EUR", "ZGBP": "GBP", "ZCAD": "CAD",
+	"ZJPY": "JPY", "ZAUD": "AUD", "ZCHF": "CHF",
+}
+
+// NormalizeAsset maps a Kraken asset code to its common ticker: XXBT and
+// XBT.M become BTC, ZUSD becomes USD. Staked and earn balances (.S, .M,
+// .F, .B, .HOLD) fold into their base asset.
+func (k *Kraken) NormalizeAsset(code string) string {
+	code = strings.ToUpper(code)
+	if base, _, ok := strings.Cut(code, "."); ok {
+		code = base
+	}
+	if name, ok := krakenLegacyCodes[code]; ok {
+		return name
+	}
+	return code
+}
+
+// krakenAsset is the inverse for building pair names.
+func krakenAsset(asset string) string {
+	switch asset {
+	case "BTC":
+		return "XBT"
+	case "DOGE":
+		return "XDG"
+	}
+	return asset
+}
+
+// USDBase returns the normalized base asset of a USD-quoted Kraken pair
+// such as XXBTZUSD or SOLUSD.
+func (k *Kraken) USDBase(pair string) (string, bool) {
+	for _, quote := range []string{"ZUSD", "USD"} {
+		if base, ok := strings.CutSuffix(pair, quote); ok && base != "" {
+			return k.NormalizeAsset(base), true
+		}
+	}
+	return "", false
+}
 
+// decode unwraps Kraken's {"error": [...], "result": ...} envelope into out.
+func (k *Kraken) decode(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Result json.RawMessage `json:"result"`
+		Error  []string        `json:"error"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("Decode error: %v", err)
+	}
+
+	if len(result.Error) > 0 {
+		return fmt.Errorf("Kraken API Error: %v", result.Error)
+	}
+
+	if err := json.Unmarshal(result.Result, out); err != nil {
+		return fmt.Errorf("Decode error: %v", err)
+	}
+	return nil
+}
+
+func (k *Kraken) public(urlPath string, q url.Values, out interface{}) error {
+	resp, err := k.Client.Get(k.BaseURL + urlPath + "?" + q.Encode())
+	if err != nil {
+		return err
+	}
+	return k.decode(resp, out)
+}
+
+// private signs and POSTs a private API call.
+func (k *Kraken) private(urlPath string, values url.Values, out interface{}) error {
 	// Kraken needs nonce in the POST body
-	nonce := fmt.Sprintf("%d", time.Now().UnixNano())
-	values := url.Values{}
-	values.Set("nonce", nonce)
+	values.Set("nonce", fmt.Sprintf("%d", time.Now().UnixNano()))
 
-	sig, err := getKrakenSignature(urlPath, values, secret)
+	sig, err := getKrakenSignature(urlPath, values, k.Secret)
 	if err != nil {
-		return nil, fmt.Errorf("Signature error: %v", err)
+		return fmt.Errorf("Signature error: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://api.kraken.com"+urlPath, strings.NewReader(values.Encode()))
+	req, err := http.NewRequest("POST", k.BaseURL+urlPath, strings.NewReader(values.Encode()))
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	req.Header.Set("API-Key", apiKey)
+	req.Header.Set("API-Key", k.APIKey)
 	req.Header.Set("API-Sign", sig)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{Timeout: 15 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := k.Client.Do(req)
 	if err != nil {
+		return err
+	}
+	return k.decode(resp, out)
+}
+
+func (k *Kraken) Balances() (map[string]decimal.Decimal, error) {
+	var raw map[string]string
+	if err := k.private("/0/private/Balance", url.Values{}, &raw); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
+	// Parsed as decimals: balances go up to 10 places, more than a float
+	// keeps for large holdings.
+	balances := map[string]decimal.Decimal{}
+	for code, val := range raw {
+		amount, err := decimal.NewFromString(val)
+		if err != nil {
+			return nil, fmt.Errorf("balance %s: %w", code, err)
+		}
+		asset := k.NormalizeAsset(code)
+		balances[asset] = balances[asset].Add(amount)
+	}
+	return balances, nil
+}
 
-	var result struct {
-		Result map[string]string `json:"result"`
-		Error  []string          `json:"error"`
+func (k *Kraken) USDPair(asset string) string {
+	return krakenAsset(asset) + "USD"
+}
+
+func (k *Kraken) USDPrice(asset string) (decimal.Decimal, error) {
+	if asset == "USD" {
+		return decimal.NewFromInt(1), nil
 	}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("Decode error: %v", err)
+	q := url.Values{}
+	q.Set("pair", k.USDPair(asset))
+
+	var result map[string]struct {
+		Last []string `json:"c"`
+	}
+	if err := k.public("/0/public/Ticker", q, &result); err != nil {
+		return decimal.Zero, err
+	}
+	for _, t := range result {
+		if len(t.Last) == 0 {
+			break
+		}
+		return decimal.NewFromString(t.Last[0])
 	}
+	return decimal.Zero, fmt.Errorf("no ticker for %s", asset)
+}
 
-	if len(result.Error) > 0 {
-		return nil, fmt.Errorf("Kraken API Error: %v", result.Error)
+// OHLC only reaches back 720 candles per interval; Kraken keeps no more.
+func (k *Kraken) OHLC(pair string, interval int, since int64) ([]models.Candle, int64, error) {
+	q := url.Values{}
+	q.Set("pair", pair)
+	q.Set("interval", strconv.Itoa(interval))
+	if since > 0 {
+		q.Set("since", strconv.FormatInt(since, 10))
+	}
+
+	var result map[string]json.RawMessage
+	if err := k.public("/0/public/OHLC", q, &result); err != nil {
+		return nil, 0, err
 	}
 
-	return result.Result, nil
+	var last int64
+	var candles []models.Candle
+	for key, body := range result {
+		if key == "last" {
+			if err := json.Unmarshal(body, &last); err != nil {
+				return nil, 0, fmt.Errorf("decode OHLC cursor: %w", err)
+			}
+			continue
+		}
+
+		// Kraken OHLC Format: [time, open, high, low, close, vwap, volume, count]
+		var rows [][]json.Number
+		if err := json.Unmarshal(body, &rows); err != nil {
+			return nil, 0, fmt.Errorf("decode OHLC rows: %w", err)
+		}
+		for _, r := range rows {
+			c, err := parseOHLCRow(r)
+			if err != nil {
+				return nil, 0, err
+			}
+			c.Pair = pair
+			c.Interval = interval
+			candles = append(candles, c)
+		}
+	}
+	return candles, last, nil
 }
 
-func FetchOHLC(pair string) (interface{}, error) {
-	// urlPath := fmt.Sprintf("/0/public/OHLC?pair=%s&interval=60", pair)
-	// resp, err := http.Get(KrakenBaseURL + urlPath)
-	resp, err := http.Get(fmt.Sprintf("https://api.kraken.com/0/public/OHLC?pair=%s&interval=60", pair))
-	if err != nil {
-		return nil, err
+func parseOHLCRow(r []json.Number) (models.Candle, error) {
+	if len(r) < 8 {
+		return models.Candle{}, fmt.Errorf("OHLC row has %d fields, want 8", len(r))
 	}
-	defer resp.Body.Close()
+	var f [8]decimal.Decimal
+	for i, n := range r[:8] {
+		v, err := decimal.NewFromString(n.String())
+		if err != nil {
+			return models.Candle{}, fmt.Errorf("OHLC field %d: %w", i, err)
+		}
+		f[i] = v
+	}
+	return models.Candle{
+		Time:   time.Unix(f[0].IntPart(), 0).UTC(),
+		Open:   f[1],
+		High:   f[2],
+		Low:    f[3],
+		Close:  f[4],
+		VWAP:   f[5],
+		Volume: f[6],
+		Trades: int(f[7].IntPart()),
+	}, nil
+}
 
-	var result struct {
-		Result map[string]interface{} `json:"result"`
-		Error  []string               `json:"error"`
+func (k *Kraken) PlaceOrder(o OrderRequest) (*OrderResult, error) {
+	values := url.Values{}
+	values.Set("pair", o.Pair)
+	values.Set("type", o.Side)
+	values.Set("ordertype", o.OrderType)
+	values.Set("volume", o.Volume.String())
+	if o.OrderType == "limit" {
+		values.Set("price", o.Price.String())
 	}
-	json.NewDecoder(resp.Body).Decode(&result)
-	
-	if len(result.Error) > 0 {
-		return nil, fmt.Errorf("kraken error: %v", result.Error)
+	if o.Validate {
+		values.Set("validate", "true")
 	}
 
-	return result.Result, nil
+	var res struct {
+		Descr struct {
+			Order string `json:"order"`
+		} `json:"descr"`
+		TxID []string `json:"txid"`
+	}
+	if err := k.private("/0/private/AddOrder", values, &res); err != nil {
+		return nil, err
+	}
+	return &OrderResult{TxIDs: res.TxID, Description: res.Descr.Order}, nil
 }
 
-func FetchMarketCandles(pair string) ([]Candle, error) {
-	// interval=60 is 1-hour candles.
-	// url := fmt.Sprintf("%s/0/public/OHLC?pair=%s&interval=60", KrakenBaseURL, pair)
-	url := fmt.Sprintf("https://api.kraken.com/0/public/OHLC?pair=%s&interval=60", pair)
-	
-	resp, err := http.Get(url)
-	if err != nil {
+func (k *Kraken) QueryOrder(txid string) (*OrderFill, error) {
+	values := url.Values{}
+	values.Set("txid", txid)
+
+	var res map[string]struct {
+		Status  string `json:"status"`
+		VolExec string `json:"vol_exec"`
+		Cost    string `json:"cost"`
+		Fee     string `json:"fee"`
+		Price   string `json:"price"`
+	}
+	if err := k.private("/0/private/QueryOrders", values, &res); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	o, ok := res[txid]
+	if !ok {
+		return nil, fmt.Errorf("order %s not returned by Kraken", txid)
+	}
+	fill := &OrderFill{Status: o.Status}
+	if err := parseDecimals(txid, []decimalField{
+		{o.VolExec, &fill.FilledVolume}, {o.Price, &fill.AvgPrice}, {o.Cost, &fill.Cost}, {o.Fee, &fill.Fee},
+	}); err != nil {
+		return nil, err
+	}
+	return fill, nil
+}
 
-	var raw struct {
-		Result map[string]interface{} `json:"result"`
-		Error  []string               `json:"error"`
+func (k *Kraken) Trades(start time.Time) ([]models.ExchangeTrade, error) {
+	var trades []models.ExchangeTrade
+	for {
+		values := url.Values{}
+		values.Set("ofs", strconv.Itoa(len(trades)))
+		if !start.IsZero() {
+			values.Set("start", strconv.FormatInt(start.Unix(), 10))
+		}
+
+		var page struct {
+			Trades map[string]struct {
+				OrderTxID string  `json:"ordertxid"`
+				Pair      string  `json:"pair"`
+				Time      float64 `json:"time"`
+				Type      string  `json:"type"`
+				OrderType string  `json:"ordertype"`
+				Price     string  `json:"price"`
+				Cost      string  `json:"cost"`
+				Fee       string  `json:"fee"`
+				Vol       string  `json:"vol"`
+			} `json:"trades"`
+			Count int `json:"count"`
+		}
+		if err := k.private("/0/private/TradesHistory", values, &page); err != nil {
+			return nil, err
+		}
+
+		for id, t := range page.Trades {
+			trade := models.ExchangeTrade{
+				TxID:      id,
+				OrderTxID: t.OrderTxID,
+				Pair:      t.Pair,
+				Time:      krakenTime(t.Time),
+				Type:      t.Type,
+				OrderType: t.OrderType,
+			}
+			if err := parseDecimals(id, []decimalField{
+				{t.Price, &trade.Price}, {t.Cost, &trade.Cost}, {t.Fee, &trade.Fee}, {t.Vol, &trade.Volume},
+			}); err != nil {
+				return nil, err
+			}
+			trades = append(trades, trade)
+		}
+
+		if len(page.Trades) == 0 || len(trades) >= page.Count {
+			break
+		}
 	}
-	json.NewDecoder(resp.Body).Decode(&raw)
 
-	if len(raw.Error) > 0 {
-		return nil, fmt.Errorf("kraken error: %v", raw.Error)
+	sort.Slice(trades, func(i, j int) bool { return trades[i].Time.Before(trades[j].Time) })
+	return trades, nil
+}
+
+func (k *Kraken) Ledger(start time.Time) ([]models.LedgerEntry, error) {
+	var entries []models.LedgerEntry
+	for {
+		values := url.Values{}
+		values.Set("ofs", strconv.Itoa(len(entries)))
+		if !start.IsZero() {
+			values.Set("start", strconv.FormatInt(start.Unix(), 10))
+		}
+
+		var page struct {
+			Ledger map[string]struct {
+				RefID   string  `json:"refid"`
+				Time    float64 `json:"time"`
+				Type    string  `json:"type"`
+				Subtype string  `json:"subtype"`
+				Asset   string  `json:"asset"`
+				Amount  string  `json:"amount"`
+				Fee     string  `json:"fee"`
+				Balance string  `json:"balance"`
+			} `json:"ledger"`
+			Count int `json:"count"`
+		}
+		if err := k.private("/0/private/Ledgers", values, &page); err != nil {
+			return nil, err
+		}
+
+		for id, l := range page.Ledger {
+			entry := models.LedgerEntry{
+				LedgerID: id,
+				RefID:    l.RefID,
+				Time:     krakenTime(l.Time),
+				Type:     l.Type,
+				Subtype:  l.Subtype,
+				Asset:    k.NormalizeAsset(l.Asset),
+				RawAsset: l.Asset,
+			}
+			if err := parseDecimals(id, []decimalField{
+				{l.Amount, &entry.Amount}, {l.Fee, &entry.Fee}, {l.Balance, &entry.Balance},
+			}); err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+
+		if len(page.Ledger) == 0 || len(entries) >= page.Count {
+			break
+		}
 	}
 
-	// Kraken returns the pair name as the key (e.g., "XXBTZUSD") iterate to find the slice of data
-	var candles []Candle
-	for k, v := range raw.Result {
-		if k == "last" { continue }
-		
-		rawSlice := v.([]interface{})
-		for _, item := range rawSlice {
-			c := item.([]interface{})
-			log.Printf("Raw candle data: %v", c)
-			// Kraken OHLC Format: [time, open, high, low, close, vwap, volume, count]
-			candles = append(candles, Candle{
-				Time:  int64(utils.ParseNumeric(c[0])),
-				Open:  utils.ParseNumeric(c[1]),
-				High:  utils.ParseNumeric(c[2]),
-				Low:   utils.ParseNumeric(c[3]),
-				Close: utils.ParseNumeric(c[4]),
-				Volume: utils.ParseNumeric(c[6]),
-			})
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	return entries, nil
+}
+
+type decimalField struct {
+	src string
+	dst *decimal.Decimal
+}
+
+// parseDecimals decodes Kraken's string-encoded numbers for record id.
+func parseDecimals(id string, fields []decimalField) error {
+	for _, f := range fields {
+		v, err := decimal.NewFromString(f.src)
+		if err != nil {
+			return fmt.Errorf("%s: %w", id, err)
 		}
+		*f.dst = v
 	}
+	return nil
+}
+
+// krakenTime converts Kraken's fractional unix seconds.
+func krakenTime(t float64) time.Time {
+	return time.Unix(0, int64(t*float64(time.Second)))
+}
 
-	return candles, nil
-}
\ No newline at end of file
+func init() {
+	RegisterExchange("kraken", func() Exchange { return NewKraken() })
+}
+
+// syncKraken imports history and refreshes CryptoHoldings for the exchange
+// owner. It backs the kraken_sync scheduled task.
+func syncKraken(ctx context.Context) (string, error) {
+	summary, n, err := SyncKraken(ctx)
+	if errors.Is(err, ErrNoExchangeOwner) {
+		return "Skipped: no user registered yet.", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("kraken sync: %w", err)
+	}
+	log.Println("[WORKER] Kraken sync complete")
+	out := fmt.Sprintf("Synchronized %d assets.", n)
+	if summary != nil {
+		out += fmt.Sprintf(" Imported %d ledger entries and %d trades.", summary.Ledger, summary.Trades)
+	}
+	return out, nil
+}
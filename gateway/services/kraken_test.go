@@ -0,0 +1,168 @@
+package services
+
+import (
+	"gateway/fakeexchange"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// newFakeKraken points a Kraken adapter at the fixture exchange.
+func newFakeKraken(t *testing.T) *Kraken {
+	t.Helper()
+	fake, err := fakeexchange.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(fake.Handler())
+	t.Cleanup(srv.Close)
+	return &Kraken{BaseURL: srv.URL, Client: srv.Client()}
+}
+
+// useExchange makes e the active exchange for the rest of the test.
+func useExchange(t *testing.T, e Exchange) {
+	t.Helper()
+	prev := ActiveExchange()
+	exchange = e
+	t.Cleanup(func() { exchange = prev })
+}
+
+func TestKrakenBalances(t *testing.T) {
+	k := newFakeKraken(t)
+	balances, err := k.Balances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"USD": "6714.6896", "BTC": "0.08", "ETH": "2.0231", "SOL": "12.328"}
+	if len(balances) != len(want) {
+		t.Fatalf("balances = %v, want assets %v", balances, want)
+	}
+	for asset, amount := range want {
+		if !balances[asset].Equal(decimal.RequireFromString(amount)) {
+			t.Errorf("%s = %s, want %s", asset, balances[asset], amount)
+		}
+	}
+}
+
+func TestKrakenUSDPrice(t *testing.T) {
+	k := newFakeKraken(t)
+	for asset, want := range map[string]string{"BTC": "75071.60000", "ETH": "3455.67000", "USD": "1"} {
+		got, err := k.USDPrice(asset)
+		if err != nil {
+			t.Fatalf("%s: %v", asset, err)
+		}
+		// Exact, as Kraken wrote it: no detour through float64.
+		if got.String() != decimal.RequireFromString(want).String() {
+			t.Errorf("%s = %v, want %v", asset, got, want)
+		}
+	}
+	if _, err := k.USDPrice("NOPE"); err == nil {
+		t.Error("unknown asset priced")
+	}
+}
+
+func TestKrakenHistory(t *testing.T) {
+	k := newFakeKraken(t)
+	entries, err := k.Ledger(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 39 {
+		t.Fatalf("%d ledger entries, want 39", len(entries))
+	}
+	rewards := 0
+	for i, e := range entries {
+		if i > 0 && e.Time.Before(entries[i-1].Time) {
+			t.Fatalf("ledger not oldest first at %s", e.LedgerID)
+		}
+		switch e.Asset {
+		case "USD", "BTC", "ETH", "SOL":
+		default:
+			t.Errorf("%s: asset %q not normalized from %q", e.LedgerID, e.Asset, e.RawAsset)
+		}
+		if isReward(&e) {
+			rewards++
+		}
+	}
+	if rewards == 0 {
+		t.Error("no staking or earn rewards imported")
+	}
+
+	later, err := k.Ledger(entries[len(entries)-1].Time.Add(-time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(later) == 0 || len(later) >= len(entries) {
+		t.Errorf("resuming returned %d entries of %d", len(later), len(entries))
+	}
+
+	trades, err := k.Trades(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 9 {
+		t.Fatalf("%d trades, want 9", len(trades))
+	}
+	for _, tr := range trades {
+		if _, ok := k.USDBase(tr.Pair); !ok {
+			t.Errorf("%s: %s is not a USD pair", tr.TxID, tr.Pair)
+		}
+	}
+}
+
+func TestKrakenPlaceOrder(t *testing.T) {
+	k := newFakeKraken(t)
+
+	res, err := k.PlaceOrder(OrderRequest{Pair: "XBTUSD", Side: "buy", OrderType: "market", Volume: decimal.RequireFromString("0.001"), Validate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.TxIDs) != 0 {
+		t.Fatalf("validated order placed as %v", res.TxIDs)
+	}
+
+	res, err = k.PlaceOrder(OrderRequest{Pair: "XBTUSD", Side: "buy", OrderType: "market", Volume: decimal.RequireFromString("0.001")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.TxIDs) != 1 {
+		t.Fatalf("txids = %v, want one", res.TxIDs)
+	}
+	fill, err := k.QueryOrder(res.TxIDs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fill.Status != "closed" || !fill.FilledVolume.Equal(decimal.RequireFromString("0.001")) ||
+		!fill.AvgPrice.Equal(decimal.RequireFromString("75071.6")) || !fill.Fee.IsPositive() {
+		t.Errorf("market fill = %+v", fill)
+	}
+
+	res, err = k.PlaceOrder(OrderRequest{Pair: "XBTUSD", Side: "buy", OrderType: "limit", Volume: decimal.RequireFromString("0.001"), Price: decimal.RequireFromString("1000")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fill, err = k.QueryOrder(res.TxIDs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if fill.Status != "open" || !fill.FilledVolume.IsZero() {
+		t.Errorf("limit below market filled: %+v", fill)
+	}
+}
+
+func TestAverageCostsFromHistory(t *testing.T) {
+	k := newFakeKraken(t)
+	useExchange(t, k)
+
+	trades, err := k.Trades(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unit := averageCosts(trades)
+	for _, asset := range []string{"BTC", "ETH", "SOL"} {
+		if !unit[asset].IsPositive() {
+			t.Errorf("%s unit cost = %v, want positive", asset, unit[asset])
+		}
+	}
+}
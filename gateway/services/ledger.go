@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gateway/db"
+	"gateway/models"
+	"gateway/utils"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ImportSummary counts what one ImportKrakenHistory call added.
+type ImportSummary struct {
+	Ledger         int `json:"ledger"`
+	Trades         int `json:"trades"`
+	FinanceRecords int `json:"finance_records"`
+}
+
+// isReward reports whether a ledger entry is staking or earn income.
+func isReward(e *models.LedgerEntry) bool {
+	if !e.Amount.IsPositive() {
+		return false
+	}
+	return e.Type == "staking" || e.Type == "dividend" || (e.Type == "earn" && e.Subtype == "reward")
+}
+
+// resumeFrom returns the time to restart an import from: a second before
+// the newest stored row, so entries sharing its timestamp are not missed.
+// Duplicates are dropped by ID.
+func resumeFrom(tx *gorm.DB, model interface{}) (time.Time, error) {
+	var latest sql.NullTime
+	if err := tx.Model(model).Select("max(time)").Row().Scan(&latest); err != nil {
+		return time.Time{}, err
+	}
+	if !latest.Valid {
+		return time.Time{}, nil
+	}
+	return latest.Time.Add(-time.Second), nil
+}
+
+// knownIDs returns which of ids already exist in column of model.
+func knownIDs(tx *gorm.DB, model interface{}, column string, ids []string) (map[string]bool, error) {
+	known := map[string]bool{}
+	for len(ids) > 0 {
+		n := min(len(ids), 1000)
+		var have []string
+		if err := tx.Model(model).Where(column+" IN ?", ids[:n]).Pluck(column, &have).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range have {
+			known[id] = true
+		}
+		ids = ids[n:]
+	}
+	return known, nil
+}
+
+// ImportKrakenHistory pulls new Kraken ledger entries and trades for the
+// exchange owner. Fees and staking rewards become FinanceRecords, valued in
+// USD at the close of the day they happened.
+func ImportKrakenHistory(ctx context.Context) (*ImportSummary, error) {
+	ctx, err := exchangeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return importKrakenHistory(ctx)
+}
+
+func importKrakenHistory(ctx context.Context) (*ImportSummary, error) {
+	tx := db.For(ctx)
+	summary := &ImportSummary{}
+
+	start, err := resumeFrom(tx, &models.LedgerEntry{})
+	if err != nil {
+		return nil, fmt.Errorf("ledger: %w", err)
+	}
+	entries, err := ActiveExchange().Ledger(start)
+	if err != nil {
+		return nil, fmt.Errorf("ledger: %w", err)
+	}
+	if err := importLedger(tx, entries, summary); err != nil {
+		return nil, err
+	}
+
+	if start, err = resumeFrom(tx, &models.ExchangeTrade{}); err != nil {
+		return nil, fmt.Errorf("trades: %w", err)
+	}
+	trades, err := ActiveExchange().Trades(start)
+	if err != nil {
+		return nil, fmt.Errorf("trades: %w", err)
+	}
+	if err := importTrades(tx, trades, summary); err != nil {
+		return nil, err
+	}
+
+	if summary.Ledger > 0 || summary.Trades > 0 {
+		log.Printf("[LEDGER] Imported %d ledger entries, %d trades", summary.Ledger, summary.Trades)
+	}
+	return summary, nil
+}
+
+func importLedger(tx *gorm.DB, entries []models.LedgerEntry, summary *ImportSummary) error {
+	ids := make([]string, len(entries))
+	for i, e := range entries {
+		ids[i] = e.LedgerID
+	}
+	known, err := knownIDs(tx, &models.LedgerEntry{}, "ledger_id", ids)
+	if err != nil {
+		return err
+	}
+
+	var fresh []models.LedgerEntry
+	for _, e := range entries {
+		if known[e.LedgerID] {
+			continue
+		}
+		known[e.LedgerID] = true
+		fresh = append(fresh, e)
+	}
+	if len(fresh) == 0 {
+		return nil
+	}
+	prices := ledgerPrices(fresh)
+
+	return tx.Transaction(func(tx *gorm.DB) error {
+		for i := range fresh {
+			e := &fresh[i]
+			if record := ledgerFinanceRecord(e, prices); record != nil {
+				if err := tx.Create(record).Error; err != nil {
+					return err
+				}
+				e.FinanceRecordID = &record.ID
+				summary.FinanceRecords++
+			}
+			if err := tx.Create(e).Error; err != nil {
+				return fmt.Errorf("ledger %s: %w", e.LedgerID, err)
+			}
+			summary.Ledger++
+		}
+		return nil
+	})
+}
+
+// ledgerPriceInterval is the candle fees and rewards are valued with:
+// daily, the finest the exchange serves far enough back for old entries.
+const ledgerPriceInterval = 1440
+
+// historicalPrices holds each asset's daily USD candles, oldest first.
+type historicalPrices map[string][]models.Candle
+
+// at returns the close of asset's last candle opening at or before t.
+func (h historicalPrices) at(asset string, t time.Time) (decimal.Decimal, bool) {
+	if asset == "USD" {
+		return decimal.NewFromInt(1), true
+	}
+	candles := h[asset]
+	i := sort.Search(len(candles), func(i int) bool { return candles[i].Time.After(t) })
+	if i == 0 {
+		return decimal.Zero, false
+	}
+	return candles[i-1].Close, true
+}
+
+// ledgerPrices loads the daily USD candles covering the fees and rewards
+// in entries, fetching from the exchange what the store lacks. Assets
+// without a USD market are left out.
+func ledgerPrices(entries []models.LedgerEntry) historicalPrices {
+	from := map[string]time.Time{}
+	for i := range entries {
+		e := &entries[i]
+		if e.Asset == "USD" || (!e.Fee.IsPositive() && !isReward(e)) {
+			continue
+		}
+		if t, ok := from[e.Asset]; !ok || e.Time.Before(t) {
+			from[e.Asset] = e.Time
+		}
+	}
+
+	return usdCandles(from)
+}
+
+// usdCandles loads each asset's daily USD candles from its start time on,
+// fetching from the exchange what the store lacks.
+func usdCandles(from map[string]time.Time) historicalPrices {
+	prices := historicalPrices{}
+	for asset, start := range from {
+		pair := ActiveExchange().USDPair(asset)
+		start = start.Add(-ledgerPriceInterval * time.Minute)
+		candles, _, err := ActiveExchange().OHLC(pair, ledgerPriceInterval, start.Unix())
+		if err == nil {
+			err = storeCandles(candles)
+		}
+		if err != nil {
+			log.Printf("[LEDGER] No USD candles for %s: %v", asset, err)
+		}
+		if prices[asset], err = LoadCandles(pair, ledgerPriceInterval, start, time.Time{}); err != nil {
+			log.Printf("[LEDGER] Load %s candles: %v", pair, err)
+		}
+	}
+	return prices
+}
+
+// ledgerFinanceRecord mirrors a fee or reward into the cash-flow ledger,
+// at the asset's USD close when it happened. Entries without a price are
+// skipped.
+func ledgerFinanceRecord(e *models.LedgerEntry, prices historicalPrices) *models.FinanceRecord {
+	price, ok := prices.at(e.Asset, e.Time)
+	if !ok {
+		return nil
+	}
+	record := &models.FinanceRecord{Base: models.Base{CreatedAt: e.Time}, Currency: "USD"}
+
+	switch {
+	case isReward(e):
+		record.Type = "income"
+		record.Category = "Staking Rewards"
+		record.Amount = utils.RoundMoney(e.Amount.Mul(price), "USD")
+		record.Description = fmt.Sprintf("Kraken %s reward: %s %s", e.Type, e.Amount, e.Asset)
+	case e.Fee.IsPositive():
+		record.Type = "expense"
+		record.Category = "Exchange Fees"
+		record.Amount = utils.RoundMoney(e.Fee.Mul(price), "USD")
+		record.Description = fmt.Sprintf("Kraken %s fee: %s %s", e.Type, e.Fee, e.Asset)
+	default:
+		return nil
+	}
+	if e.RefID != "" {
+		record.Description += " (" + e.RefID + ")"
+	}
+	return record
+}
+
+func importTrades(tx *gorm.DB, trades []models.ExchangeTrade, summary *ImportSummary) error {
+	ids := make([]string, len(trades))
+	for i, t := range trades {
+		ids[i] = t.TxID
+	}
+	known, err := knownIDs(tx, &models.ExchangeTrade{}, "tx_id", ids)
+	if err != nil {
+		return err
+	}
+
+	var fresh []models.ExchangeTrade
+	for _, t := range trades {
+		if !known[t.TxID] {
+			known[t.TxID] = true
+			fresh = append(fresh, t)
+		}
+	}
+	if len(fresh) == 0 {
+		return nil
+	}
+	if err := tx.CreateInBatches(fresh, 500).Error; err != nil {
+		return fmt.Errorf("store trades: %w", err)
+	}
+	summary.Trades += len(fresh)
+	return nil
+}
@@ -1,15 +1,30 @@
 package services
 
 import (
+	"context"
 	"gateway/db"
 	"gateway/models"
+	"log"
 )
 
-func EmitEvent(source, message, level string) {
+// EmitEvent records an event. If ctx carries an owner the event is private to
+// that user; otherwise it is a system event every user sees. Inside a plan
+// or import transaction the event waits for the commit.
+func EmitEvent(ctx context.Context, source, message, level string) {
+	afterCommit(ctx, func(ctx context.Context) {
+		emitEvent(ctx, source, message, level)
+	})
+}
+
+func emitEvent(ctx context.Context, source, message, level string) {
 	event := models.SystemEvent{
 		Source:  source,
 		Message: message,
 		Level:   level,
 	}
-	db.Instance.Create(&event)
-}
\ No newline at end of file
+	if err := db.For(ctx).Create(&event).Error; err != nil {
+		log.Printf("[EVENTS] Failed to persist event: %v", err)
+		return
+	}
+	Events.Publish(event)
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gateway/db"
+	"gateway/models"
+	"gateway/utils"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Net worth is crypto holdings at their last synced USD value plus the
+// manual cash and asset accounts, less liabilities. The net_worth_snapshots
+// job records it once a day in DefaultCurrency; a second snapshot on the
+// same day replaces the first.
+
+// ValidAccountKind reports whether kind is a known NetWorthAccount kind.
+func ValidAccountKind(kind string) bool {
+	switch kind {
+	case models.AccountCash, models.AccountAsset, models.AccountLiability:
+		return true
+	}
+	return false
+}
+
+// ValidateNetWorthAccount normalizes an account before it is saved.
+func ValidateNetWorthAccount(a *models.NetWorthAccount) error {
+	a.Name = strings.TrimSpace(a.Name)
+	if a.Name == "" {
+		return errors.New("name is required")
+	}
+	a.Kind = strings.ToLower(strings.TrimSpace(a.Kind))
+	if !ValidAccountKind(a.Kind) {
+		return fmt.Errorf("unknown kind %q: use cash, asset or liability", a.Kind)
+	}
+	if a.Balance.IsNegative() {
+		return errors.New("balance cannot be negative; record debts as a liability")
+	}
+	var err error
+	if a.Currency, err = recordCurrency(a.Currency); err != nil {
+		return err
+	}
+	a.Balance = utils.RoundMoney(a.Balance, a.Currency)
+	return nil
+}
+
+// measureNetWorth values everything visible to tx in currency as of now.
+// The result is not saved.
+func measureNetWorth(tx *gorm.DB, fx *FXTable, currency string) (*models.NetWorthSnapshot, error) {
+	now := time.Now()
+	s := &models.NetWorthSnapshot{Day: fxDay(now), Currency: currency}
+
+	var crypto decimal.Decimal
+	err := tx.Model(&models.CryptoHoldings{}).Select("COALESCE(sum(usd_value), 0)").Row().Scan(&crypto)
+	if err != nil {
+		return nil, err
+	}
+	if s.Crypto, err = fx.Convert(crypto, "USD", currency, now); err != nil {
+		return nil, err
+	}
+
+	var accounts []models.NetWorthAccount
+	if err := tx.Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	for _, a := range accounts {
+		v, err := fx.Convert(a.Balance, a.Currency, currency, now)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", a.Name, err)
+		}
+		switch a.Kind {
+		case models.AccountCash:
+			s.Cash = s.Cash.Add(v)
+		case models.AccountAsset:
+			s.Assets = s.Assets.Add(v)
+		case models.AccountLiability:
+			s.Liabilities = s.Liabilities.Add(v)
+		}
+	}
+
+	s.Crypto = utils.RoundMoney(s.Crypto, currency)
+	s.Cash = utils.RoundMoney(s.Cash, currency)
+	s.Assets = utils.RoundMoney(s.Assets, currency)
+	s.Liabilities = utils.RoundMoney(s.Liabilities, currency)
+	s.TotalAssets = s.Crypto.Add(s.Cash).Add(s.Assets)
+	s.NetWorth = s.TotalAssets.Sub(s.Liabilities)
+	return s, nil
+}
+
+// MeasureNetWorth values the owner's net worth in currency right now,
+// without recording a snapshot.
+func MeasureNetWorth(ctx context.Context, currency string) (*models.NetWorthSnapshot, error) {
+	fx, err := LoadFXTable()
+	if err != nil {
+		return nil, err
+	}
+	return measureNetWorth(db.For(ctx), fx, currency)
+}
+
+// SnapshotNetWorth records today's net worth for the owner in ctx,
+// replacing any snapshot already taken today.
+func SnapshotNetWorth(ctx context.Context) (*models.NetWorthSnapshot, error) {
+	fx, err := LoadFXTable()
+	if err != nil {
+		return nil, err
+	}
+	tx := db.For(ctx)
+	s, err := measureNetWorth(tx, fx, DefaultCurrency())
+	if err != nil {
+		return nil, err
+	}
+	// Concurrent runs on the same day meet on the unique (owner_id, day)
+	// index and the later one wins.
+	err = tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "owner_id"}, {Name: "day"}},
+		DoUpdates: clause.AssignmentColumns([]string{"updated_at", "deleted_at", "currency", "crypto", "cash",
+			"assets", "liabilities", "total_assets", "net_worth"}),
+	}).Create(s).Error
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// snapshotNetWorth backs the net_worth_snapshots task. Run unowned it
+// snapshots every user.
+func snapshotNetWorth(ctx context.Context) (string, error) {
+	if _, ok := db.OwnerFrom(ctx); ok {
+		s, err := SnapshotNetWorth(ctx)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Net worth %s.", FormatMoney(s.NetWorth, s.Currency)), nil
+	}
+
+	var users []models.User
+	if err := db.Instance.Find(&users).Error; err != nil {
+		return "", err
+	}
+	taken := 0
+	for _, u := range users {
+		if _, err := SnapshotNetWorth(db.WithOwner(ctx, u.ID)); err != nil {
+			log.Printf("[NETWORTH] %s: %v", u.Email, err)
+			continue
+		}
+		taken++
+	}
+	return fmt.Sprintf("Recorded %d net worth snapshot(s).", taken), nil
+}
+
+// NetWorthPoint is the last snapshot in a day, week or month, converted to
+// the reporting currency, with the change since the point before it.
+type NetWorthPoint struct {
+	Period      time.Time       `json:"period"` // first day of the day, week or month
+	Day         time.Time       `json:"day"`    // of the snapshot standing for the period
+	Crypto      decimal.Decimal `json:"crypto"`
+	Cash        decimal.Decimal `json:"cash"`
+	Assets      decimal.Decimal `json:"assets"`
+	Liabilities decimal.Decimal `json:"liabilities"`
+	TotalAssets decimal.Decimal `json:"total_assets"`
+	NetWorth    decimal.Decimal `json:"net_worth"`
+	Change      decimal.Decimal `json:"change"`
+	ChangePct   *float64        `json:"change_pct"` // nil for the first point or after a zero net worth
+}
+
+// periodStart maps day to the start of its period: "day", "week" (weeks
+// start on Monday) or "month".
+func periodStart(day time.Time, period string) (time.Time, error) {
+	switch period {
+	case "", "day":
+		return day, nil
+	case "week":
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7)), nil
+	case "month":
+		return monthStart(day), nil
+	}
+	return time.Time{}, fmt.Errorf("unknown period %q: use day, week or month", period)
+}
+
+// ValidNetWorthPeriod reports whether period is one NetWorthHistory groups
+// by.
+func ValidNetWorthPeriod(period string) bool {
+	_, err := periodStart(time.Now(), period)
+	return err == nil
+}
+
+// NetWorthHistory builds the series for charting from the owner's
+// snapshots on or after since, one point per period in currency. Each
+// snapshot converts at the rate of its own day.
+func NetWorthHistory(ctx context.Context, period string, since time.Time, currency string) ([]NetWorthPoint, error) {
+	if _, err := periodStart(time.Now(), period); err != nil {
+		return nil, err
+	}
+	fx, err := LoadFXTable()
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []models.NetWorthSnapshot
+	query := db.For(ctx).Order("day asc")
+	if !since.IsZero() {
+		query = query.Where("day >= ?", fxDay(since))
+	}
+	if err := query.Find(&snapshots).Error; err != nil {
+		return nil, err
+	}
+
+	points := []NetWorthPoint{}
+	for _, s := range snapshots {
+		day := calendarDay(s.Day)
+		start, _ := periodStart(day, period)
+		p := NetWorthPoint{Period: start, Day: day}
+		for _, f := range []struct {
+			dst *decimal.Decimal
+			v   decimal.Decimal
+		}{
+			{&p.Crypto, s.Crypto}, {&p.Cash, s.Cash}, {&p.Assets, s.Assets},
+			{&p.Liabilities, s.Liabilities},
+		} {
+			v, err := fx.Convert(f.v, s.Currency, currency, day)
+			if err != nil {
+				return nil, err
+			}
+			*f.dst = utils.RoundMoney(v, currency)
+		}
+		p.TotalAssets = p.Crypto.Add(p.Cash).Add(p.Assets)
+		p.NetWorth = p.TotalAssets.Sub(p.Liabilities)
+
+		// Later snapshots in the same period replace earlier ones.
+		if n := len(points); n > 0 && points[n-1].Period.Equal(start) {
+			points[n-1] = p
+		} else {
+			points = append(points, p)
+		}
+	}
+
+	for i := 1; i < len(points); i++ {
+		prev := points[i-1].NetWorth
+		points[i].Change = points[i].NetWorth.Sub(prev)
+		if !prev.IsZero() {
+			pct := points[i].Change.Div(prev.Abs()).Mul(decimal.NewFromInt(100)).Round(2).InexactFloat64()
+			points[i].ChangePct = &pct
+		}
+	}
+	return points, nil
+}
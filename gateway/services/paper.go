@@ -0,0 +1,384 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gateway/db"
+	"gateway/models"
+	"gateway/utils"
+	"log"
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Paper trading: every BUY/SELL signal created after the portfolio is
+// "executed" against a simulated account, at the signal's Price or, when it
+// has none, at the open of the next hourly candle. The paper_trading task
+// fills new signals and records a mark-to-market snapshot.
+
+// paperCandles prices the simulation from the hourly candle store.
+func paperCandles(pair string) ([]Candle, error) {
+	return CachedCandles(pair, 60, 0)
+}
+
+// PositionValue is a position marked to the latest candle close.
+type PositionValue struct {
+	models.PaperPosition
+	MarkPrice     decimal.Decimal `json:"mark_price"`
+	MarketValue   decimal.Decimal `json:"market_value"`
+	UnrealizedPnL decimal.Decimal `json:"unrealized_pnl"`
+}
+
+type PaperValuation struct {
+	Portfolio     models.PaperPortfolio `json:"portfolio"`
+	Positions     []PositionValue       `json:"positions"`
+	MarketValue   decimal.Decimal       `json:"market_value"`
+	Equity        decimal.Decimal       `json:"equity"`
+	UnrealizedPnL decimal.Decimal       `json:"unrealized_pnl"`
+	ReturnPct     float64               `json:"return_pct"`
+}
+
+type ConfidenceBucket struct {
+	Bucket    string          `json:"bucket"`
+	Signals   int             `json:"signals"`
+	Hits      int             `json:"hits"`
+	HitRate   float64         `json:"hit_rate"`
+	AvgReturn float64         `json:"avg_return"`
+	PnL       decimal.Decimal `json:"pnl"`
+}
+
+// PaperStats scores executed signals against the current price: a BUY hits
+// if the price has risen since, a SELL if it has fallen.
+type PaperStats struct {
+	Signals   int                `json:"signals"`
+	Hits      int                `json:"hits"`
+	HitRate   float64            `json:"hit_rate"`
+	AvgReturn float64            `json:"avg_return"`
+	PnL       decimal.Decimal    `json:"pnl"`
+	Buckets   []ConfidenceBucket `json:"buckets"`
+}
+
+// confidenceBuckets split the agent's 0-100 confidence.
+var confidenceBuckets = []struct {
+	label    string
+	min, max float64
+}{
+	{"0-20", 0, 20}, {"20-40", 20, 40}, {"40-60", 40, 60}, {"60-80", 60, 80}, {"80-100", 80, math.Inf(1)},
+}
+
+// priceBook caches candles per pair for the length of one run or request.
+type priceBook map[string][]Candle
+
+func (b priceBook) candles(pair string) []Candle {
+	if c, ok := b[pair]; ok {
+		return c
+	}
+	c, err := paperCandles(pair)
+	if err != nil {
+		log.Printf("[PAPER] No candles for %s: %v", pair, err)
+	}
+	b[pair] = c
+	return c
+}
+
+func (b priceBook) last(pair string) (decimal.Decimal, bool) {
+	c := b.candles(pair)
+	if len(c) == 0 {
+		return decimal.Zero, false
+	}
+	return c[len(c)-1].Close, true
+}
+
+// after returns the open of the first candle starting after t.
+func (b priceBook) after(pair string, t time.Time) (decimal.Decimal, bool) {
+	for _, c := range b.candles(pair) {
+		if c.Time > t.Unix() {
+			return c.Open, true
+		}
+	}
+	return decimal.Zero, false
+}
+
+// paperPortfolio loads the owner's portfolio, opening one from
+// PAPER_STARTING_CASH and PAPER_TRADE_USD on first use.
+func paperPortfolio(tx *gorm.DB) (*models.PaperPortfolio, error) {
+	var p models.PaperPortfolio
+	err := tx.Order("id desc").First(&p).Error
+	if err == nil {
+		return &p, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	cash := envDecimal("PAPER_STARTING_CASH", decimal.NewFromInt(10000))
+	p = models.PaperPortfolio{StartingCash: cash, Cash: cash, TradeSize: envDecimal("PAPER_TRADE_USD", decimal.NewFromInt(1000))}
+	if err := tx.Create(&p).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ResetPaperPortfolio starts a fresh simulation; zero values fall back to
+// the environment defaults. Only signals created afterwards are traded.
+func ResetPaperPortfolio(ctx context.Context, startingCash, tradeSize decimal.Decimal) (*models.PaperPortfolio, error) {
+	var p *models.PaperPortfolio
+	err := db.For(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, m := range []interface{}{&models.PaperSnapshot{}, &models.PaperTrade{}, &models.PaperPosition{}, &models.PaperPortfolio{}} {
+			if err := tx.Where("1 = 1").Delete(m).Error; err != nil {
+				return err
+			}
+		}
+		var err error
+		if p, err = paperPortfolio(tx); err != nil {
+			return err
+		}
+		if startingCash.IsPositive() {
+			p.StartingCash, p.Cash = startingCash, startingCash
+		}
+		if tradeSize.IsPositive() {
+			p.TradeSize = tradeSize
+		}
+		return tx.Save(p).Error
+	})
+	return p, err
+}
+
+// RunPaperTrading fills every untraded signal it has a price for and
+// snapshots the portfolio of the owner in ctx. The paper_trading scheduled
+// task runs unowned and does that for each user in turn.
+func RunPaperTrading(ctx context.Context) (string, error) {
+	book := priceBook{}
+	if _, ok := db.OwnerFrom(ctx); ok {
+		filled, equity, err := runPaperTrading(ctx, book)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Paper traded %d signal(s); equity %s.", filled, FormatMoney(equity, "USD")), nil
+	}
+
+	var users []models.User
+	if err := db.Instance.Find(&users).Error; err != nil {
+		return "", err
+	}
+	total, ran := 0, 0
+	for _, u := range users {
+		filled, _, err := runPaperTrading(db.WithOwner(ctx, u.ID), book)
+		if err != nil {
+			log.Printf("[PAPER] %s: %v", u.Email, err)
+			continue
+		}
+		total += filled
+		ran++
+	}
+	return fmt.Sprintf("Paper traded %d signal(s) across %d portfolio(s).", total, ran), nil
+}
+
+// runPaperTrading trades one owner's portfolio. book is shared across
+// owners so each pair's candles are loaded once per run.
+func runPaperTrading(ctx context.Context, book priceBook) (int, decimal.Decimal, error) {
+	tx := db.For(ctx)
+	p, err := paperPortfolio(tx)
+	if err != nil {
+		return 0, decimal.Zero, fmt.Errorf("paper portfolio: %w", err)
+	}
+
+	var signals []models.TradingSignal
+	err = tx.Where("action IN ? AND created_at >= ?", []string{"BUY", "SELL"}, p.CreatedAt).
+		Where("id NOT IN (?)", db.For(ctx).Model(&models.PaperTrade{}).
+			Select("signal_id").Where("portfolio_id = ?", p.ID)).
+		Order("created_at asc").
+		Find(&signals).Error
+	if err != nil {
+		return 0, decimal.Zero, fmt.Errorf("paper signals: %w", err)
+	}
+
+	filled := 0
+	for i := range signals {
+		s := &signals[i]
+		price, source := s.Price, "signal"
+		if !price.IsPositive() {
+			var ok bool
+			if price, ok = book.after(s.Asset, s.CreatedAt); !ok {
+				continue // next candle not out yet
+			}
+			source = "candle"
+		}
+		if err := tx.Transaction(func(tx *gorm.DB) error {
+			return executePaperTrade(tx, p, s, price, source)
+		}); err != nil {
+			return filled, decimal.Zero, fmt.Errorf("paper trade signal %d: %w", s.ID, err)
+		}
+		filled++
+	}
+
+	val, err := valuePaperPortfolio(tx, p, book)
+	if err != nil {
+		return filled, decimal.Zero, err
+	}
+	tx.Create(&models.PaperSnapshot{
+		PortfolioID:   p.ID,
+		TakenAt:       time.Now(),
+		Cash:          p.Cash,
+		MarketValue:   val.MarketValue,
+		Equity:        val.Equity,
+		RealizedPnL:   p.RealizedPnL,
+		UnrealizedPnL: val.UnrealizedPnL,
+	})
+	return filled, val.Equity, nil
+}
+
+func executePaperTrade(tx *gorm.DB, p *models.PaperPortfolio, s *models.TradingSignal, price decimal.Decimal, source string) error {
+	var pos models.PaperPosition
+	tx.Where(models.PaperPosition{PortfolioID: p.ID, Asset: s.Asset}).FirstOrInit(&pos)
+
+	trade := models.PaperTrade{
+		PortfolioID: p.ID,
+		SignalID:    s.ID,
+		Asset:       s.Asset,
+		Side:        s.Action,
+		Price:       price,
+		PriceSource: source,
+		SignalQty:   utils.RoundCrypto(p.TradeSize.Div(price)),
+		Confidence:  s.Confidence,
+		ExecutedAt:  time.Now(),
+	}
+
+	if s.Action == "BUY" {
+		spend := decimal.Min(p.TradeSize, p.Cash)
+		trade.Quantity = utils.RoundCrypto(spend.Div(price))
+		if trade.Quantity.IsPositive() {
+			held := pos.Quantity.Add(trade.Quantity)
+			pos.AvgCost = utils.RoundCrypto(pos.AvgCost.Mul(pos.Quantity).Add(spend).Div(held))
+			pos.Quantity = held
+			p.Cash = p.Cash.Sub(spend)
+		}
+	} else {
+		trade.Quantity = decimal.Min(pos.Quantity, trade.SignalQty)
+		if trade.Quantity.IsPositive() {
+			trade.RealizedPnL = utils.RoundMoney(price.Sub(pos.AvgCost).Mul(trade.Quantity), "USD")
+			pos.Quantity = pos.Quantity.Sub(trade.Quantity)
+			p.Cash = p.Cash.Add(utils.RoundMoney(trade.Quantity.Mul(price), "USD"))
+			p.RealizedPnL = p.RealizedPnL.Add(trade.RealizedPnL)
+		}
+	}
+
+	if err := tx.Create(&trade).Error; err != nil {
+		return err
+	}
+	if pos.Quantity.IsPositive() || pos.ID != 0 {
+		if err := tx.Save(&pos).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Save(p).Error
+}
+
+func valuePaperPortfolio(tx *gorm.DB, p *models.PaperPortfolio, book priceBook) (*PaperValuation, error) {
+	var positions []models.PaperPosition
+	if err := tx.Where("portfolio_id = ? AND quantity > 0", p.ID).Find(&positions).Error; err != nil {
+		return nil, err
+	}
+
+	val := &PaperValuation{Portfolio: *p, Positions: []PositionValue{}}
+	for _, pos := range positions {
+		mark, ok := book.last(pos.Asset)
+		if !ok {
+			mark = pos.AvgCost
+		}
+		pv := PositionValue{
+			PaperPosition: pos,
+			MarkPrice:     mark,
+			MarketValue:   utils.RoundMoney(pos.Quantity.Mul(mark), "USD"),
+			UnrealizedPnL: utils.RoundMoney(mark.Sub(pos.AvgCost).Mul(pos.Quantity), "USD"),
+		}
+		val.Positions = append(val.Positions, pv)
+		val.MarketValue = val.MarketValue.Add(pv.MarketValue)
+		val.UnrealizedPnL = val.UnrealizedPnL.Add(pv.UnrealizedPnL)
+	}
+	val.Equity = p.Cash.Add(val.MarketValue)
+	if p.StartingCash.IsPositive() {
+		val.ReturnPct = val.Equity.Sub(p.StartingCash).Div(p.StartingCash).Mul(decimal.NewFromInt(100)).Round(2).InexactFloat64()
+	}
+	return val, nil
+}
+
+// ValuePaperPortfolio marks the owner's portfolio to current prices.
+func ValuePaperPortfolio(ctx context.Context) (*PaperValuation, error) {
+	p, err := paperPortfolio(db.For(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return valuePaperPortfolio(db.For(ctx), p, priceBook{})
+}
+
+// ScorePaperSignals computes hit rate, average return and P&L for every
+// paper-traded signal, overall and per confidence bucket. Returns are in
+// percent; P&L is on the signal's nominal size.
+func ScorePaperSignals(ctx context.Context) (*PaperStats, error) {
+	p, err := paperPortfolio(db.For(ctx))
+	if err != nil {
+		return nil, err
+	}
+	var trades []models.PaperTrade
+	if err := db.For(ctx).Where("portfolio_id = ?", p.ID).Find(&trades).Error; err != nil {
+		return nil, err
+	}
+
+	stats := &PaperStats{Buckets: make([]ConfidenceBucket, len(confidenceBuckets))}
+	for i, b := range confidenceBuckets {
+		stats.Buckets[i].Bucket = b.label
+	}
+
+	book := priceBook{}
+	var totalReturn float64
+	sums := make([]float64, len(confidenceBuckets))
+	for _, t := range trades {
+		mark, ok := book.last(t.Asset)
+		if !ok || !t.Price.IsPositive() {
+			continue
+		}
+		move := mark.Sub(t.Price)
+		if t.Side == "SELL" {
+			move = move.Neg()
+		}
+		// Returns are statistics and stay floats; P&L is money.
+		ret := move.Div(t.Price).Mul(decimal.NewFromInt(100)).InexactFloat64()
+		pnl := utils.RoundMoney(move.Mul(t.SignalQty), "USD")
+		hit := ret > 0
+
+		stats.Signals++
+		stats.PnL = stats.PnL.Add(pnl)
+		totalReturn += ret
+		if hit {
+			stats.Hits++
+		}
+
+		for i, b := range confidenceBuckets {
+			if t.Confidence >= b.min && t.Confidence < b.max {
+				stats.Buckets[i].Signals++
+				stats.Buckets[i].PnL = stats.Buckets[i].PnL.Add(pnl)
+				sums[i] += ret
+				if hit {
+					stats.Buckets[i].Hits++
+				}
+				break
+			}
+		}
+	}
+
+	if stats.Signals > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(stats.Signals)
+		stats.AvgReturn = totalReturn / float64(stats.Signals)
+	}
+	for i := range stats.Buckets {
+		if n := stats.Buckets[i].Signals; n > 0 {
+			stats.Buckets[i].HitRate = float64(stats.Buckets[i].Hits) / float64(n)
+			stats.Buckets[i].AvgReturn = sums[i] / float64(n)
+		}
+	}
+	return stats, nil
+}
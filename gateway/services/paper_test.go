@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRunPaperTradingKeepsOwnersApart(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+	rec := useRecordingDB(t, twoUsers(alice, bob))
+
+	// The paper_trading job is unowned and runs with no owner in ctx.
+	if _, err := RunPaperTrading(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]int{}
+	for _, table := range []string{"paper_portfolios", "trading_signals", "paper_snapshots"} {
+		for _, s := range rec.touching(table) {
+			switch {
+			case hasArg(s.Args, alice.String()) && !hasArg(s.Args, bob.String()):
+				seen[alice.String()]++
+			case hasArg(s.Args, bob.String()) && !hasArg(s.Args, alice.String()):
+				seen[bob.String()]++
+			default:
+				t.Errorf("%s not scoped to exactly one owner:\n%s", table, s.SQL)
+			}
+		}
+	}
+	if seen[alice.String()] == 0 || seen[bob.String()] == 0 {
+		t.Fatalf("statements per owner = %v, want both users traded", seen)
+	}
+}
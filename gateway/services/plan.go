@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gateway/db"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+type ToolCall struct {
+	Action string                 `json:"action"`
+	Data   map[string]interface{} `json:"data"`
+}
+
+const (
+	StepExecuted   = "executed"
+	StepFailed     = "failed"
+	StepRolledBack = "rolled_back"
+	StepSkipped    = "skipped"
+)
+
+type StepResult struct {
+	Step    int          `json:"step"`
+	Action  string       `json:"action"`
+	Status  string       `json:"status"`
+	Message string       `json:"message,omitempty"`
+	Nav     string       `json:"nav,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+// commitHooks holds work that must wait for a transaction to commit:
+// events pushed to clients and calls to the outside world. A rolled-back
+// transaction drops them.
+type commitHooks struct {
+	fns []func(ctx context.Context)
+}
+
+type commitHooksKey struct{}
+
+// withCommitHooks returns a context whose afterCommit calls are held until
+// run.
+func withCommitHooks(ctx context.Context) (context.Context, *commitHooks) {
+	h := &commitHooks{}
+	return context.WithValue(ctx, commitHooksKey{}, h), h
+}
+
+// afterCommit runs fn once the transaction ctx belongs to commits, or right
+// away outside one. fn gets a context without the hooks.
+func afterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	if h, ok := ctx.Value(commitHooksKey{}).(*commitHooks); ok {
+		h.fns = append(h.fns, fn)
+		return
+	}
+	fn(ctx)
+}
+
+// run calls the held work in order, with the context the transaction was
+// started from.
+func (h *commitHooks) run(ctx context.Context) {
+	for _, fn := range h.fns {
+		fn(ctx)
+	}
+	h.fns = nil
+}
+
+// ErrPlanFailed is returned by ExecutePlan when a step failed and the whole
+// plan was rolled back. The step results describe which one.
+var ErrPlanFailed = errors.New("plan rolled back")
+
+// ExecutePlan runs every step inside one transaction, on behalf of the owner
+// in ctx. If any step fails,
+// nothing is committed: earlier steps are reported as rolled back and later
+// ones as skipped. Steps without a gateway handler are skipped and do not
+// abort the plan.
+func ExecutePlan(ctx context.Context, steps []ToolCall) ([]StepResult, error) {
+	results := make([]StepResult, len(steps))
+	for i, s := range steps {
+		results[i] = StepResult{Step: i + 1, Action: s.Action, Status: StepSkipped}
+	}
+
+	failed := -1
+	txCtx, hooks := withCommitHooks(ctx)
+	err := db.For(txCtx).Transaction(func(tx *gorm.DB) error {
+		for i, s := range steps {
+			log.Printf("[PLAN] Step %d/%d: %s", i+1, len(steps), s.Action)
+			msg, nav, err := runTool(tx, s.Action, s.Data)
+
+			if errors.Is(err, ErrUnknownTool) {
+				results[i].Message = "No gateway handler; nothing to execute."
+				continue
+			}
+			if err != nil {
+				failed = i
+				results[i].Status = StepFailed
+				results[i].Error = err.Error()
+				var verr *ToolValidationError
+				if errors.As(err, &verr) {
+					results[i].Fields = verr.Fields
+				}
+				return err
+			}
+
+			results[i].Status = StepExecuted
+			results[i].Message = msg
+			results[i].Nav = nav
+		}
+		return nil
+	})
+
+	if err == nil {
+		hooks.run(ctx)
+		return results, nil
+	}
+
+	for i := 0; i < failed; i++ {
+		if results[i].Status == StepExecuted {
+			results[i].Status = StepRolledBack
+		}
+	}
+	if failed < 0 {
+		// Commit itself failed; every executed step is lost.
+		for i := range results {
+			if results[i].Status == StepExecuted {
+				results[i].Status = StepRolledBack
+			}
+		}
+		return results, fmt.Errorf("%w: %v", ErrPlanFailed, err)
+	}
+	return results, fmt.Errorf("%w: step %d (%s): %v", ErrPlanFailed, failed+1, steps[failed].Action, err)
+}
@@ -0,0 +1,423 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gateway/db"
+	"gateway/models"
+	"gateway/utils"
+	"log"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Recurring transactions are posted by the recurring_transactions task.
+// Each template keeps its next due time; a run posts every occurrence due
+// by now, so a gateway that was down catches up on restart.
+
+// nextOccurrence returns the template's first occurrence strictly after t.
+func nextOccurrence(r *models.RecurringTransaction, t time.Time) (time.Time, error) {
+	t = t.In(time.Local)
+	switch r.Frequency {
+	case models.FrequencyWeekly:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+		ahead := (r.Weekday - int(day.Weekday()) + 7) % 7
+		next := day.AddDate(0, 0, ahead)
+		if !next.After(t) {
+			next = next.AddDate(0, 0, 7)
+		}
+		return next, nil
+
+	case models.FrequencyMonthly:
+		for m := monthStart(t); ; m = m.AddDate(0, 1, 0) {
+			last := m.AddDate(0, 1, -1).Day()
+			next := m.AddDate(0, 0, min(r.DayOfMonth, last)-1)
+			if next.After(t) {
+				return next, nil
+			}
+		}
+
+	case models.FrequencyCron:
+		sched, err := cronParser.Parse(r.Cron)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return sched.Next(t), nil
+	}
+	return time.Time{}, fmt.Errorf("unknown frequency %q", r.Frequency)
+}
+
+// ValidateRecurring normalizes the template, checks its schedule and sets
+// its first due time.
+func ValidateRecurring(r *models.RecurringTransaction) error {
+	r.Type = strings.ToLower(r.Type)
+	if r.Type != "expense" && r.Type != "income" {
+		return errors.New(`type must be "expense" or "income"`)
+	}
+	if !r.Amount.IsPositive() {
+		return errors.New("amount must be positive")
+	}
+	var err error
+	if r.Currency, err = recordCurrency(r.Currency); err != nil {
+		return err
+	}
+	r.Amount = utils.RoundMoney(r.Amount, r.Currency)
+	switch r.Frequency {
+	case models.FrequencyWeekly:
+		if r.Weekday < 0 || r.Weekday > 6 {
+			return errors.New("weekday must be 0 (Sunday) to 6")
+		}
+	case models.FrequencyMonthly:
+		if r.DayOfMonth < 1 || r.DayOfMonth > 31 {
+			return errors.New("day_of_month must be 1 to 31")
+		}
+	case models.FrequencyCron:
+		if _, err := cronParser.Parse(r.Cron); err != nil {
+			return fmt.Errorf("invalid cron %q: %w", r.Cron, err)
+		}
+	default:
+		return fmt.Errorf("unknown frequency %q: use weekly, monthly or cron", r.Frequency)
+	}
+	if r.Name == "" {
+		r.Name = r.Description
+	}
+	if r.Name == "" {
+		return errors.New("name or description is required")
+	}
+	if r.StartsOn.IsZero() {
+		r.StartsOn = time.Now()
+	}
+
+	// Re-arm from the later of the start date and the last posting so an
+	// edit never posts an occurrence twice.
+	from := r.StartsOn.Add(-time.Nanosecond)
+	if r.LastPostedAt != nil && r.LastPostedAt.After(from) {
+		from = *r.LastPostedAt
+	}
+	next, err := nextOccurrence(r, from)
+	if err != nil {
+		return err
+	}
+	r.NextDueAt = &next
+	return nil
+}
+
+func recurringRecord(r *models.RecurringTransaction, due time.Time) *models.FinanceRecord {
+	description := r.Description
+	if description == "" {
+		description = r.Name
+	}
+	return &models.FinanceRecord{
+		Base:        models.Base{CreatedAt: due},
+		Amount:      r.Amount,
+		Currency:    r.Currency,
+		Category:    r.Category,
+		Description: description,
+		Type:        r.Type,
+		RecurringID: &r.ID,
+	}
+}
+
+// postRecurring posts every occurrence of r due by now and advances it.
+// The next_due_at check makes a concurrent run skip instead of doubling.
+func postRecurring(tx *gorm.DB, r *models.RecurringTransaction, now time.Time) (int, error) {
+	claimed := *r.NextDueAt
+	posted := 0
+	for r.NextDueAt != nil && !r.NextDueAt.After(now) {
+		due := *r.NextDueAt
+		if r.EndsOn != nil && due.After(*r.EndsOn) {
+			r.NextDueAt = nil
+			r.Active = false
+			break
+		}
+		if err := tx.Create(recurringRecord(r, due)).Error; err != nil {
+			return 0, err
+		}
+		posted++
+		r.LastPostedAt = &due
+		next, err := nextOccurrence(r, due)
+		if err != nil {
+			return 0, err
+		}
+		r.NextDueAt = &next
+	}
+
+	res := tx.Model(r).Where("next_due_at = ?", claimed).Updates(map[string]interface{}{
+		"next_due_at":    r.NextDueAt,
+		"last_posted_at": r.LastPostedAt,
+		"active":         r.Active,
+	})
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return 0, errors.New("claimed by another run")
+	}
+	return posted, nil
+}
+
+// PostRecurringTransactions materializes every due template visible to
+// ctx. Run unowned it posts for every owner.
+func PostRecurringTransactions(ctx context.Context) (string, error) {
+	var due []models.RecurringTransaction
+	now := time.Now()
+	if err := db.For(ctx).Where("active = ? AND next_due_at <= ?", true, now).Find(&due).Error; err != nil {
+		return "", err
+	}
+
+	total := 0
+	for i := range due {
+		r := &due[i]
+		rctx := ctx
+		if r.OwnerID != nil {
+			rctx = db.WithOwner(ctx, *r.OwnerID)
+		}
+		var posted int
+		err := db.For(rctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			posted, err = postRecurring(tx, r, now)
+			return err
+		})
+		if err != nil {
+			log.Printf("[RECURRING] %s: %v", r.Name, err)
+			continue
+		}
+		if posted > 0 && r.Type == "expense" {
+			// Posted spending counts against budgets like any other expense.
+			if _, err := checkBudget(db.For(rctx), r.Category, r.Amount.Mul(decimal.NewFromInt(int64(posted))), r.Currency); err != nil {
+				log.Printf("[RECURRING] budget check for %s: %v", r.Name, err)
+			}
+		}
+		total += posted
+	}
+	return fmt.Sprintf("Posted %d recurring transaction(s).", total), nil
+}
+
+// Occurrence is one future posting of a template.
+type Occurrence struct {
+	RecurringID  uint            `json:"recurring_id"`
+	Name         string          `json:"name"`
+	Type         string          `json:"type"`
+	Category     string          `json:"category"`
+	Amount       decimal.Decimal `json:"amount"`
+	Currency     string          `json:"currency"`
+	Subscription bool            `json:"subscription"`
+	DueAt        time.Time       `json:"due_at"`
+}
+
+// occurrencesBetween expands active templates into their postings in
+// [from, to), in date order.
+func occurrencesBetween(templates []models.RecurringTransaction, from, to time.Time) []Occurrence {
+	out := []Occurrence{}
+	for i := range templates {
+		r := &templates[i]
+		if !r.Active || r.NextDueAt == nil {
+			continue
+		}
+		for due := *r.NextDueAt; due.Before(to); {
+			if r.EndsOn != nil && due.After(*r.EndsOn) {
+				break
+			}
+			if !due.Before(from) {
+				out = append(out, Occurrence{
+					RecurringID: r.ID, Name: r.Name, Type: r.Type, Category: r.Category,
+					Amount: r.Amount, Currency: r.Currency, Subscription: r.Subscription, DueAt: due,
+				})
+			}
+			next, err := nextOccurrence(r, due)
+			if err != nil {
+				break
+			}
+			due = next
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].DueAt.Before(out[j].DueAt) })
+	return out
+}
+
+type UpcomingBills struct {
+	Days        int             `json:"days"`
+	Bills       []Occurrence    `json:"bills"`
+	Total       decimal.Decimal `json:"total"`
+	Currency    string          `json:"currency"`    // of Total
+	Unconverted int             `json:"unconverted"` // bills listed but left out of Total for want of an FX rate
+}
+
+// ListUpcomingBills returns recurring expenses falling due in the next days.
+// Bills with no FX rate into DefaultCurrency are still listed.
+func ListUpcomingBills(ctx context.Context, days int) (*UpcomingBills, error) {
+	var templates []models.RecurringTransaction
+	if err := db.For(ctx).Where("active = ? AND type = ?", true, "expense").Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	fx, err := LoadFXTable()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	out := &UpcomingBills{Days: days, Bills: occurrencesBetween(templates, now, now.AddDate(0, 0, days)), Currency: DefaultCurrency()}
+	for _, b := range out.Bills {
+		amount, err := fx.Convert(b.Amount, b.Currency, out.Currency, b.DueAt)
+		if errors.Is(err, ErrNoFXRate) {
+			out.Unconverted++
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out.Total = out.Total.Add(amount)
+	}
+	out.Total = utils.RoundMoney(out.Total, out.Currency)
+	return out, nil
+}
+
+// SubscriptionCandidate is a run of similar expenses that looks recurring
+// but has no template yet.
+type SubscriptionCandidate struct {
+	Description  string          `json:"description"`
+	Category     string          `json:"category"`
+	Amount       decimal.Decimal `json:"amount"` // median
+	Currency     string          `json:"currency"`
+	Frequency    string          `json:"frequency"`
+	IntervalDays float64         `json:"interval_days"`
+	Occurrences  int             `json:"occurrences"`
+	LastSeen     time.Time       `json:"last_seen"`
+	NextExpected time.Time       `json:"next_expected"`
+	Confidence   float64         `json:"confidence"` // 0-1, from interval and amount regularity
+}
+
+// subscriptionAmountTolerance is how far, as a fraction, amounts of the
+// same bill may differ.
+const subscriptionAmountTolerance = 0.10
+
+var descriptionNoise = regexp.MustCompile(`[0-9#*/\-_.:,()]+`)
+
+// descriptionKey groups descriptions that differ only by dates, invoice
+// numbers or punctuation: "NETFLIX.COM 10/24" and "Netflix.com 11/24".
+func descriptionKey(d string) string {
+	return strings.Join(strings.Fields(descriptionNoise.ReplaceAllString(strings.ToLower(d), " ")), " ")
+}
+
+func median(xs []float64) float64 {
+	s := append([]float64(nil), xs...)
+	sort.Float64s(s)
+	n := len(s)
+	if n%2 == 1 {
+		return s[n/2]
+	}
+	return (s[n/2-1] + s[n/2]) / 2
+}
+
+// subscriptionCadences are the gaps, in days, that count as recurring.
+var subscriptionCadences = []struct {
+	frequency string
+	days      float64
+	tolerance float64
+	step      [3]int // years, months, days to the next posting
+}{
+	{models.FrequencyWeekly, 7, 1.5, [3]int{0, 0, 7}},
+	{models.FrequencyMonthly, 30.4, 4, [3]int{0, 1, 0}},
+	{"yearly", 365, 10, [3]int{1, 0, 0}},
+}
+
+// DetectSubscriptions looks through the last 13 months of expenses for at
+// least three postings with the same description, amounts within 10% of
+// each other and a weekly, monthly or yearly rhythm. Expenses already
+// posted by a template are ignored.
+func DetectSubscriptions(ctx context.Context) ([]SubscriptionCandidate, error) {
+	var records []models.FinanceRecord
+	err := db.For(ctx).
+		Where("type = ? AND recurring_id IS NULL AND created_at >= ?", "expense", time.Now().AddDate(0, -13, 0)).
+		Order("created_at asc").
+		Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	var templates []models.RecurringTransaction
+	if err := db.For(ctx).Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	tracked := map[string]bool{}
+	for _, t := range templates {
+		tracked[descriptionKey(t.Description)] = true
+		tracked[descriptionKey(t.Name)] = true
+	}
+
+	groups := map[string][]models.FinanceRecord{}
+	for _, r := range records {
+		key := descriptionKey(r.Description)
+		if key != "" && !tracked[key] {
+			// A subscription is billed in one currency.
+			groups[key+"|"+r.Currency] = append(groups[key+"|"+r.Currency], r)
+		}
+	}
+
+	out := []SubscriptionCandidate{}
+	for _, group := range groups {
+		if c, ok := detectCadence(group); ok {
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Confidence > out[j].Confidence })
+	return out, nil
+}
+
+func detectCadence(group []models.FinanceRecord) (SubscriptionCandidate, bool) {
+	if len(group) < 3 {
+		return SubscriptionCandidate{}, false
+	}
+	amounts := make([]float64, len(group))
+	for i, r := range group {
+		amounts[i] = r.Amount.InexactFloat64()
+	}
+	amount := median(amounts)
+	var amountDev float64
+	for _, a := range amounts {
+		dev := math.Abs(a-amount) / amount
+		if dev > subscriptionAmountTolerance {
+			return SubscriptionCandidate{}, false
+		}
+		amountDev = math.Max(amountDev, dev)
+	}
+
+	gaps := make([]float64, len(group)-1)
+	for i := 1; i < len(group); i++ {
+		gaps[i-1] = group[i].CreatedAt.Sub(group[i-1].CreatedAt).Hours() / 24
+	}
+	interval := median(gaps)
+	for _, cad := range subscriptionCadences {
+		if math.Abs(interval-cad.days) > cad.tolerance {
+			continue
+		}
+		var gapDev float64
+		for _, g := range gaps {
+			gapDev = math.Max(gapDev, math.Abs(g-cad.days))
+		}
+		if gapDev > 2*cad.tolerance {
+			return SubscriptionCandidate{}, false
+		}
+		last := group[len(group)-1]
+		confidence := (1 - gapDev/(2*cad.tolerance)) * (1 - amountDev/subscriptionAmountTolerance)
+		confidence = 0.5 + 0.5*confidence*math.Min(float64(len(group))/6, 1)
+		return SubscriptionCandidate{
+			Description:  last.Description,
+			Category:     last.Category,
+			Amount:       utils.RoundMoney(decimal.NewFromFloat(amount), last.Currency),
+			Currency:     last.Currency,
+			Frequency:    cad.frequency,
+			IntervalDays: interval,
+			Occurrences:  len(group),
+			LastSeen:     last.CreatedAt,
+			NextExpected: last.CreatedAt.AddDate(cad.step[0], cad.step[1], cad.step[2]),
+			Confidence:   math.Round(confidence*100) / 100,
+		}, true
+	}
+	return SubscriptionCandidate{}, false
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"gateway/models"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestNextOccurrence(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+		if err != nil {
+			panic(err)
+		}
+		return v
+	}
+	weekly := func(wd time.Weekday) *models.RecurringTransaction {
+		return &models.RecurringTransaction{Frequency: models.FrequencyWeekly, Weekday: int(wd)}
+	}
+	monthly := func(day int) *models.RecurringTransaction {
+		return &models.RecurringTransaction{Frequency: models.FrequencyMonthly, DayOfMonth: day}
+	}
+
+	tests := []struct {
+		name string
+		r    *models.RecurringTransaction
+		t    string
+		want string
+		err  bool
+	}{
+		// 2026-03-02 is a Monday.
+		{"weekly later this week", weekly(time.Friday), "2026-03-02 10:00", "2026-03-06 00:00", false},
+		{"weekly on the day moves a week", weekly(time.Monday), "2026-03-02 00:00", "2026-03-09 00:00", false},
+		{"weekly earlier in the week", weekly(time.Sunday), "2026-03-02 10:00", "2026-03-08 00:00", false},
+		{"monthly later this month", monthly(15), "2026-03-02 10:00", "2026-03-15 00:00", false},
+		{"monthly on the day moves a month", monthly(15), "2026-03-15 00:00", "2026-04-15 00:00", false},
+		{"monthly clamped to February", monthly(31), "2026-02-01 00:00", "2026-02-28 00:00", false},
+		{"monthly clamped in a leap year", monthly(30), "2028-02-10 00:00", "2028-02-29 00:00", false},
+		{"monthly back to the full day after a short month", monthly(31), "2026-02-28 00:00", "2026-03-31 00:00", false},
+		{"monthly into next year", monthly(5), "2026-12-20 08:00", "2027-01-05 00:00", false},
+		{"cron", &models.RecurringTransaction{Frequency: models.FrequencyCron, Cron: "30 9 1 * *"},
+			"2026-03-02 10:00", "2026-04-01 09:30", false},
+		{"bad cron", &models.RecurringTransaction{Frequency: models.FrequencyCron, Cron: "every day"},
+			"2026-03-02 10:00", "", true},
+		{"unknown frequency", &models.RecurringTransaction{Frequency: "fortnightly"}, "2026-03-02 10:00", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nextOccurrence(tt.r, at(tt.t))
+			if (err != nil) != tt.err {
+				t.Fatalf("err = %v, want error %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if !got.Equal(at(tt.want)) {
+				t.Fatalf("next = %s, want %s", got.Format("2006-01-02 15:04"), tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectCadence(t *testing.T) {
+	// postings builds a group from "2006-01-02" dates and matching amounts.
+	postings := func(dates []string, amounts ...string) []models.FinanceRecord {
+		group := make([]models.FinanceRecord, len(dates))
+		for i, d := range dates {
+			group[i] = models.FinanceRecord{
+				Description: "STREAMCO " + d, Category: "Entertainment", Currency: "USD",
+				Amount: decimal.RequireFromString(amounts[i%len(amounts)]),
+			}
+			group[i].CreatedAt = day(d)
+		}
+		return group
+	}
+	weeks := []string{"2026-03-02", "2026-03-09", "2026-03-16", "2026-03-23", "2026-03-30"}
+	months := []string{"2026-01-05", "2026-02-05", "2026-03-05", "2026-04-05"}
+
+	tests := []struct {
+		name       string
+		group      []models.FinanceRecord
+		ok         bool
+		frequency  string
+		amount     string
+		next       string
+		confidence float64
+	}{
+		{"weekly", postings(weeks, "9.99"), true, models.FrequencyWeekly, "9.99", "2026-04-06", 0.92},
+		// Gaps of 31, 28 and 31 days are up to 2.4 off a 30.4-day month.
+		{"monthly", postings(months, "15.99"), true, models.FrequencyMonthly, "15.99", "2026-05-05", 0.73},
+		{"monthly with a price change inside the tolerance", postings(months, "10", "10.5"), true,
+			models.FrequencyMonthly, "10.25", "2026-05-05", 0.68},
+		{"yearly", postings([]string{"2024-03-01", "2025-03-01", "2026-03-01"}, "99"), true,
+			"yearly", "99", "2027-03-01", 0.75},
+		{"too few", postings(months[:2], "15.99"), false, "", "", "", 0},
+		{"amount outside the tolerance", postings(months[:3], "10", "10", "12"), false, "", "", "", 0},
+		{"one gap far off the cadence", postings([]string{"2026-01-01", "2026-01-31", "2026-03-02", "2026-06-01"}, "20"),
+			false, "", "", "", 0},
+		{"no cadence", postings([]string{"2026-01-01", "2026-01-16", "2026-01-31"}, "20"), false, "", "", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, ok := detectCadence(tt.group)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v (%+v)", ok, tt.ok, c)
+			}
+			if !ok {
+				return
+			}
+			if c.Frequency != tt.frequency || !c.Amount.Equal(decimal.RequireFromString(tt.amount)) {
+				t.Errorf("cadence = %s at %s, want %s at %s", c.Frequency, c.Amount, tt.frequency, tt.amount)
+			}
+			if got := c.NextExpected.Format("2006-01-02"); got != tt.next {
+				t.Errorf("next expected = %s, want %s", got, tt.next)
+			}
+			if c.Confidence != tt.confidence {
+				t.Errorf("confidence = %v, want %v", c.Confidence, tt.confidence)
+			}
+			if c.Occurrences != len(tt.group) || c.Description != tt.group[len(tt.group)-1].Description {
+				t.Errorf("candidate = %+v, want the last posting's description and %d occurrences", c, len(tt.group))
+			}
+		})
+	}
+}
+
+func TestDescriptionKey(t *testing.T) {
+	tests := []struct{ a, b string }{
+		{"NETFLIX.COM 10/24", "Netflix.com 11/24"},
+		{"Gym #4411 (Main St)", "gym  #5512 (main st)"},
+	}
+	for _, tt := range tests {
+		if descriptionKey(tt.a) != descriptionKey(tt.b) {
+			t.Errorf("descriptionKey(%q) = %q, descriptionKey(%q) = %q, want equal",
+				tt.a, descriptionKey(tt.a), tt.b, descriptionKey(tt.b))
+		}
+	}
+	if descriptionKey("Netflix") == descriptionKey("Hulu") {
+		t.Error("different merchants share a key")
+	}
+}
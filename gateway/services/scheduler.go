@@ -1,37 +1,292 @@
 package services
 
+// One scheduler for every proactive loop. Jobs live in scheduled_jobs; the
+// loop wakes every schedulerTick, claims jobs whose next_run_at has passed and
+// records each execution in job_runs. Because next_run_at is persisted, an
+// occurrence missed while the gateway was down is still due on restart and
+// fires once (if CatchUp is set) instead of being silently dropped.
+
 import (
+	"context"
+	"errors"
+	"fmt"
+	"gateway/db"
+	"gateway/models"
 	"log"
+	"strings"
 	"time"
+
+	"github.com/robfig/cron/v3"
 )
 
-func StartExecutiveScheduler() {
-	// every 30 mins
-	ticker := time.NewTicker(30 * time.Minute)
-
-	go func() {
-		for range ticker.C {
-			now := time.Now()
-			
-			// morning 8am 
-			if now.Hour() == 8 && now.Minute() < 30 {
-				triggerProactiveQuery("Generate a morning briefing and ask for today's goals.")
-			}
-
-			// evening 9pm
-			if now.Hour() == 21 && now.Minute() < 30 {
-				triggerProactiveQuery("Review today's completed tasks and ask for a reflection.")
-			}
-            
-            // random
-            if now.Hour() == 14 {
-                triggerProactiveQuery("Check if I've been productive today and suggest a task to focus on.")
-            }
-		}
-	}()
-}
-
-func triggerProactiveQuery(prompt string) {
-	log.Printf("[EXECUTIVE] Triggering: %s", prompt)
-	RequestIntent("SYSTEM_MANAGER", prompt, "", "", nil)
-}
\ No newline at end of file
+const schedulerTick = 30 * time.Second
+
+// ScheduledTask is a built-in job body. ctx carries the job's owner; the
+// returned string is stored as the run's output.
+type ScheduledTask func(ctx context.Context) (string, error)
+
+var scheduledTasks = map[string]ScheduledTask{}
+
+// RegisterScheduledTask exposes a Go function to scheduled_jobs.task.
+func RegisterScheduledTask(name string, fn ScheduledTask) {
+	scheduledTasks[name] = fn
+}
+
+// agentSlugs are the specialists the Brain's make_agent accepts; keep in step
+// with brain/agents/specialists.py.
+var agentSlugs = map[string]bool{
+	"arbiter": true, "researcher": true, "finance": true, "jobs": true, "health": true,
+	"tasks": true, "manager": true, "vanguard": true, "ghost": true, "oracle": true, "builder": true,
+}
+
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// ValidateJob checks the cron expression, timezone, agent and job body.
+func ValidateJob(job *models.ScheduledJob) error {
+	if _, err := cronParser.Parse(job.Cron); err != nil {
+		return fmt.Errorf("invalid cron %q: %w", job.Cron, err)
+	}
+	if _, err := jobLocation(job); err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", job.Timezone, err)
+	}
+	if job.Agent != "" && !agentSlugs[job.Agent] {
+		return fmt.Errorf("unknown agent %q", job.Agent)
+	}
+	if job.Task == "" && strings.TrimSpace(job.Prompt) == "" {
+		return errors.New("either prompt or task is required")
+	}
+	if job.Task != "" {
+		if _, ok := scheduledTasks[job.Task]; !ok {
+			return fmt.Errorf("unknown task %q", job.Task)
+		}
+	}
+	return nil
+}
+
+func jobLocation(job *models.ScheduledJob) (*time.Location, error) {
+	if job.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(job.Timezone)
+}
+
+// NextRun returns the first occurrence of job strictly after `after`.
+func NextRun(job *models.ScheduledJob, after time.Time) (time.Time, error) {
+	sched, err := cronParser.Parse(job.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	loc, err := jobLocation(job)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return sched.Next(after.In(loc)), nil
+}
+
+// defaultJobs mirrors the loops that used to be hard-coded in worker.go,
+// heartbeat.go and the executive scheduler. Only the ones that were actually
+// running are enabled.
+var defaultJobs = []models.ScheduledJob{
+	{Name: "kraken_sync", Cron: "0 * * * *", Task: "kraken_sync", Enabled: true, CatchUp: true},
+	{Name: "paper_trading", Cron: "*/15 * * * *", Task: "paper_trading", Enabled: true},
+	{Name: "price_alerts", Cron: "*/5 * * * *", Task: "price_alerts", Enabled: true},
+	{Name: "recurring_transactions", Cron: "5 * * * *", Task: "recurring_transactions", Enabled: true, CatchUp: true},
+	{Name: "fx_rates", Cron: "0 6 * * *", Task: "fx_rates", Enabled: true, CatchUp: true},
+	{Name: "net_worth_snapshots", Cron: "30 23 * * *", Task: "net_worth_snapshots", Enabled: true, CatchUp: true},
+	{Name: "morning_briefing", Cron: "0 8 * * *", Agent: "manager", Enabled: true, CatchUp: true,
+		Prompt: "Generate Morning Briefing: portfolio summary, today's tasks, top tech news."},
+	{Name: "nutrition_check", Cron: "0 13 * * *", Agent: "health", Enabled: true,
+		Prompt: "Check if nutrition has been logged today. Prompt me if not."},
+	{Name: "evening_review", Cron: "0 21 * * *", Agent: "finance", Enabled: true, CatchUp: true,
+		Prompt: "Review all ventures and trading signals from today. Summarise the ROI."},
+	{Name: "security_audit", Cron: "0 3 * * *", Agent: "vanguard", Enabled: true, CatchUp: true,
+		Prompt: "Boot Vanguard Agent. Run full security audit of digital footprint."},
+	{Name: "tech_research", Cron: "0 */4 * * *", Agent: "oracle",
+		Prompt: "Research top 3 trending Go/Python/AI technical breakthroughs."},
+	{Name: "productivity_nudge", Cron: "0 14 * * *", Agent: "manager",
+		Prompt: "Check if I've been productive today and suggest a task to focus on."},
+}
+
+// seedJobs creates each default job the first time its name is seen. Any row
+// with that name counts, whoever owns it: a claimed or deleted job must not
+// come back as an unowned copy on the next boot.
+func seedJobs() {
+	for _, j := range defaultJobs {
+		job := j
+		var n int64
+		db.Instance.Unscoped().Model(&models.ScheduledJob{}).Where("name = ?", job.Name).Count(&n)
+		if n == 0 {
+			db.Instance.Create(&job)
+		}
+	}
+}
+
+// StartScheduler seeds the default jobs and runs the scheduling loop.
+func StartScheduler() {
+	seedJobs()
+
+	// Runs left Running by a crash would otherwise block their job forever.
+	db.Instance.Model(&models.JobRun{}).Where("status = ?", models.RunRunning).
+		Updates(map[string]interface{}{"status": models.RunFailed, "error": "interrupted by gateway restart"})
+
+	var pending []models.ScheduledJob
+	db.Instance.Where("next_run_at IS NULL").Find(&pending)
+	for i := range pending {
+		ScheduleNext(&pending[i], time.Now())
+	}
+
+	log.Println("[SCHEDULER] Online")
+	ticker := time.NewTicker(schedulerTick)
+	for {
+		runDueJobs(time.Now())
+		<-ticker.C
+	}
+}
+
+// ScheduleNext persists the next occurrence of job after t.
+func ScheduleNext(job *models.ScheduledJob, after time.Time) {
+	next, err := NextRun(job, after)
+	if err != nil {
+		log.Printf("[SCHEDULER] %s: %v", job.Name, err)
+		return
+	}
+	job.NextRunAt = &next
+	db.Instance.Model(job).Update("next_run_at", next)
+}
+
+func runDueJobs(now time.Time) {
+	var due []models.ScheduledJob
+	db.Instance.Where("enabled = ? AND next_run_at <= ?", true, now).Find(&due)
+
+	for i := range due {
+		job := due[i]
+		scheduledFor := *job.NextRunAt
+
+		next, err := NextRun(&job, now)
+		if err != nil {
+			log.Printf("[SCHEDULER] %s: %v", job.Name, err)
+			continue
+		}
+
+		// Claim the occurrence; a concurrent tick or replica that got here
+		// first will have moved next_run_at already.
+		res := db.Instance.Model(&models.ScheduledJob{}).
+			Where("id = ? AND next_run_at = ?", job.ID, scheduledFor).
+			Update("next_run_at", next)
+		if res.Error != nil || res.RowsAffected == 0 {
+			continue
+		}
+
+		// An occurrence more than one tick late was missed while offline.
+		if now.Sub(scheduledFor) > 2*schedulerTick && !job.CatchUp {
+			recordSkipped(&job, scheduledFor, "missed while gateway was offline")
+			continue
+		}
+
+		go RunJob(job, scheduledFor)
+	}
+}
+
+// jobContext scopes a job's work to its owner. Unowned jobs run as system.
+func jobContext(job *models.ScheduledJob) context.Context {
+	ctx := context.Background()
+	if job.OwnerID != nil {
+		ctx = db.WithOwner(ctx, *job.OwnerID)
+	}
+	return ctx
+}
+
+func recordSkipped(job *models.ScheduledJob, scheduledFor time.Time, reason string) {
+	now := time.Now()
+	db.For(jobContext(job)).Create(&models.JobRun{
+		JobID:        job.ID,
+		ScheduledFor: scheduledFor,
+		StartedAt:    now,
+		FinishedAt:   &now,
+		Status:       models.RunSkipped,
+		Output:       reason,
+	})
+}
+
+// RunJob executes job once and records the run. It is also used for manual
+// triggers from the API.
+func RunJob(job models.ScheduledJob, scheduledFor time.Time) *models.JobRun {
+	var running int64
+	db.Instance.Model(&models.JobRun{}).
+		Where("job_id = ? AND status = ?", job.ID, models.RunRunning).
+		Count(&running)
+	if running > 0 {
+		recordSkipped(&job, scheduledFor, "previous run still in progress")
+		return nil
+	}
+
+	run := models.JobRun{
+		JobID:        job.ID,
+		ScheduledFor: scheduledFor,
+		StartedAt:    time.Now(),
+		Status:       models.RunRunning,
+	}
+	ctx := jobContext(&job)
+	db.For(ctx).Create(&run)
+	log.Printf("[SCHEDULER] Running %s", job.Name)
+
+	output, err := executeJob(ctx, &job)
+
+	finished := time.Now()
+	run.FinishedAt = &finished
+	run.Output = output
+	if err != nil {
+		run.Status = models.RunFailed
+		run.Error = err.Error()
+		log.Printf("[SCHEDULER ERROR] %s: %v", job.Name, err)
+		EmitEvent(ctx, "SCHEDULER", "Job failed: "+job.Name, "ERROR")
+	} else {
+		run.Status = models.RunSucceeded
+		EmitEvent(ctx, "SCHEDULER", "Job complete: "+job.Name, "SUCCESS")
+	}
+	db.Instance.Save(&run)
+	db.Instance.Model(&models.ScheduledJob{}).Where("id = ?", job.ID).Update("last_run_at", finished)
+
+	return &run
+}
+
+func executeJob(ctx context.Context, job *models.ScheduledJob) (string, error) {
+	if job.Task != "" {
+		task, ok := scheduledTasks[job.Task]
+		if !ok {
+			return "", fmt.Errorf("unknown task %q", job.Task)
+		}
+		return task(ctx)
+	}
+
+	// Unowned jobs run as SYSTEM_CORE, which bypasses UI-only logic in the Brain.
+	userID := "SYSTEM_CORE"
+	if job.OwnerID != nil {
+		userID = job.OwnerID.String()
+	}
+	res, err := RequestAgentIntent(job.Agent, userID, "autonomous_stream", job.Prompt)
+	if err != nil {
+		return "", err
+	}
+
+	output := res.Message
+	if plan := res.Plan(); len(plan) > 0 {
+		results, err := ExecutePlan(ctx, plan)
+		for _, r := range results {
+			output += fmt.Sprintf("\n[%d] %s: %s %s%s", r.Step, r.Action, r.Status, r.Message, r.Error)
+		}
+		if err != nil {
+			return output, err
+		}
+	}
+	return output, nil
+}
+
+func init() {
+	RegisterScheduledTask("kraken_sync", syncKraken)
+	RegisterScheduledTask("paper_trading", RunPaperTrading)
+	RegisterScheduledTask("price_alerts", checkAlerts)
+	RegisterScheduledTask("recurring_transactions", PostRecurringTransactions)
+	RegisterScheduledTask("fx_rates", loadFXRates)
+	RegisterScheduledTask("net_worth_snapshots", snapshotNetWorth)
+}
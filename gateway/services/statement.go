@@ -0,0 +1,608 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gateway/db"
+	"gateway/models"
+	"gateway/utils"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Bank statements are imported in two steps: PreviewStatement parses the
+// stored upload, categorizes each row and marks those already recorded;
+// CommitStatement then writes the rest as FinanceRecords. Commit re-runs
+// the preview, so rows recorded in between are still caught.
+
+var ErrImportCommitted = errors.New("statement already committed")
+
+// CSVMapping says which columns hold what. Columns are named by header or
+// by zero-based index ("0"). Use Amount for a signed column, or Debit and
+// Credit for split ones.
+type CSVMapping struct {
+	Date        string `json:"date"`
+	Description string `json:"description"`
+	Amount      string `json:"amount"`
+	Debit       string `json:"debit"`
+	Credit      string `json:"credit"`
+	Category    string `json:"category"`
+	DateFormat  string `json:"date_format"` // Go layout; detected per file when empty
+	Delimiter   string `json:"delimiter"`
+	NoHeader    bool   `json:"no_header"`
+	Negate      bool   `json:"negate"` // the bank shows spending as positive
+}
+
+// StatementRow is one parsed transaction. Amount is signed: negative is an
+// expense.
+type StatementRow struct {
+	Index       int             `json:"index"`
+	Date        time.Time       `json:"date"`
+	Description string          `json:"description"`
+	Amount      decimal.Decimal `json:"amount"`
+	Type        string          `json:"type"`
+	Category    string          `json:"category"`
+	Rule        string          `json:"rule,omitempty"` // pattern that set Category
+	Duplicate   bool            `json:"duplicate"`
+}
+
+type StatementPreview struct {
+	Import     models.StatementImport `json:"import"`
+	Rows       []StatementRow         `json:"rows"`
+	New        int                    `json:"new"`
+	Duplicates int                    `json:"duplicates"`
+	Income     decimal.Decimal        `json:"income"`
+	Expenses   decimal.Decimal        `json:"expenses"`
+	Anomalies  []Anomaly              `json:"anomalies,omitempty"` // found on commit
+}
+
+// StatementFormat picks the parser from the file extension.
+func StatementFormat(name string) (string, error) {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".csv"):
+		return "csv", nil
+	case strings.HasSuffix(name, ".ofx"), strings.HasSuffix(name, ".qfx"):
+		return "ofx", nil
+	}
+	return "", errors.New("unsupported statement: upload .csv, .ofx or .qfx")
+}
+
+var statementDateLayouts = []string{
+	"2006-01-02", "01/02/2006", "1/2/2006", "02/01/2006", "2006/01/02",
+	"01/02/06", "1/2/06", "02.01.2006", "Jan 2, 2006", "2 Jan 2006", "02 Jan 2006",
+	"2006-01-02 15:04:05", "2006-01-02T15:04:05Z07:00",
+}
+
+func parseStatementDate(s, layout string) (time.Time, error) {
+	return time.ParseInLocation(layout, strings.TrimSpace(s), time.Local)
+}
+
+// detectDateLayout picks the one layout every date in a file parses with.
+// A file whose dates fit several layouts that disagree, such as month/day
+// and day/month when no day is past the 12th, is rejected rather than
+// guessed at.
+func detectDateLayout(dates []string) (string, error) {
+	var fits []string
+	for _, l := range statementDateLayouts {
+		ok := true
+		for _, d := range dates {
+			if _, err := parseStatementDate(d, l); err != nil {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			fits = append(fits, l)
+		}
+	}
+	if len(fits) == 0 {
+		for _, d := range dates {
+			parsed := false
+			for _, l := range statementDateLayouts {
+				if _, err := parseStatementDate(d, l); err == nil {
+					parsed = true
+					break
+				}
+			}
+			if !parsed {
+				return "", fmt.Errorf("unrecognized date %q: set date_format", strings.TrimSpace(d))
+			}
+		}
+		return "", errors.New("dates are in more than one format: set date_format")
+	}
+
+	for _, l := range fits[1:] {
+		for _, d := range dates {
+			a, _ := parseStatementDate(d, fits[0])
+			b, _ := parseStatementDate(d, l)
+			if !a.Equal(b) {
+				return "", fmt.Errorf("dates such as %q could be read as %s or %s: set date_format",
+					strings.TrimSpace(d), fits[0], l)
+			}
+		}
+	}
+	return fits[0], nil
+}
+
+// parseStatementAmount accepts "$1,234.56", "(12.00)" and "-12.00".
+func parseStatementAmount(s string) (decimal.Decimal, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return decimal.Zero, nil
+	}
+	negative := strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")")
+	s = strings.Trim(s, "()")
+	s = strings.NewReplacer("$", "", "€", "", "£", "", " ", "").Replace(s)
+	v, err := utils.ParseDecimal(s)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("bad amount %q", s)
+	}
+	if negative {
+		v = v.Neg()
+	}
+	return v, nil
+}
+
+// csvHeaderGuesses fill in a mapping's empty columns from common headers.
+var csvHeaderGuesses = map[string][]string{
+	"date":        {"date", "transaction date", "posted date", "posting date", "booking date"},
+	"description": {"description", "payee", "name", "memo", "details", "merchant"},
+	"amount":      {"amount", "transaction amount"},
+	"debit":       {"debit", "withdrawal", "withdrawals", "money out"},
+	"credit":      {"credit", "deposit", "deposits", "money in"},
+	"category":    {"category"},
+}
+
+// ParseCSVStatement reads transactions from a CSV bank export.
+func ParseCSVStatement(r io.Reader, m CSVMapping) ([]StatementRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	if m.Delimiter != "" {
+		cr.Comma = []rune(m.Delimiter)[0]
+	}
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read csv: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("statement is empty")
+	}
+
+	headers := map[string]int{}
+	if !m.NoHeader {
+		for i, h := range records[0] {
+			headers[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+		}
+		records = records[1:]
+	}
+	col := func(name, field string) (int, error) {
+		if name == "" {
+			for _, guess := range csvHeaderGuesses[field] {
+				if i, ok := headers[guess]; ok {
+					return i, nil
+				}
+			}
+			return -1, nil
+		}
+		if i, err := strconv.Atoi(name); err == nil {
+			return i, nil
+		}
+		if i, ok := headers[strings.ToLower(name)]; ok {
+			return i, nil
+		}
+		return -1, fmt.Errorf("no %q column for %s", name, field)
+	}
+
+	idx := map[string]int{}
+	for field, name := range map[string]string{
+		"date": m.Date, "description": m.Description, "amount": m.Amount,
+		"debit": m.Debit, "credit": m.Credit, "category": m.Category,
+	} {
+		i, err := col(name, field)
+		if err != nil {
+			return nil, err
+		}
+		idx[field] = i
+	}
+	if idx["date"] < 0 || idx["description"] < 0 {
+		return nil, errors.New("map the date and description columns")
+	}
+	if idx["amount"] < 0 && idx["debit"] < 0 && idx["credit"] < 0 {
+		return nil, errors.New("map an amount column, or debit and credit")
+	}
+
+	field := func(rec []string, name string) string {
+		if i := idx[name]; i >= 0 && i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+
+	blank := func(rec []string) bool { return strings.TrimSpace(strings.Join(rec, "")) == "" }
+
+	layout := m.DateFormat
+	if layout == "" {
+		var dates []string
+		for _, rec := range records {
+			if !blank(rec) {
+				dates = append(dates, field(rec, "date"))
+			}
+		}
+		if layout, err = detectDateLayout(dates); err != nil {
+			return nil, err
+		}
+	}
+
+	var rows []StatementRow
+	for n, rec := range records {
+		if blank(rec) {
+			continue
+		}
+		line := n + 2
+		if m.NoHeader {
+			line = n + 1
+		}
+		date, err := parseStatementDate(field(rec, "date"), layout)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		var amount decimal.Decimal
+		if idx["amount"] >= 0 {
+			if amount, err = parseStatementAmount(field(rec, "amount")); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			if m.Negate {
+				amount = amount.Neg()
+			}
+		} else {
+			debit, err := parseStatementAmount(field(rec, "debit"))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			credit, err := parseStatementAmount(field(rec, "credit"))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			amount = credit.Sub(debit.Abs())
+		}
+		if amount.IsZero() {
+			continue
+		}
+		rows = append(rows, StatementRow{
+			Date:        date,
+			Description: field(rec, "description"),
+			Amount:      amount,
+			Category:    field(rec, "category"),
+		})
+	}
+	return rows, nil
+}
+
+var (
+	ofxTransaction = regexp.MustCompile(`(?is)<STMTTRN>(.*?)</STMTTRN>`)
+	ofxField       = regexp.MustCompile(`(?i)<(DTPOSTED|TRNAMT|NAME|MEMO|PAYEE)>([^<\r\n]*)`)
+	ofxCurrency    = regexp.MustCompile(`(?i)<CURDEF>\s*([A-Za-z]{3})`)
+)
+
+// ParseOFXStatement reads STMTTRN entries from an OFX or QFX file, in
+// either the SGML (1.x) or XML (2.x) dialect.
+func ParseOFXStatement(r io.Reader) ([]StatementRow, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Contains(bytes.ToUpper(body), []byte("<OFX>")) {
+		return nil, errors.New("not an OFX file")
+	}
+
+	var rows []StatementRow
+	for n, m := range ofxTransaction.FindAllSubmatch(body, -1) {
+		fields := map[string]string{}
+		for _, f := range ofxField.FindAllSubmatch(m[1], -1) {
+			fields[strings.ToUpper(string(f[1]))] = strings.TrimSpace(string(f[2]))
+		}
+
+		// DTPOSTED is YYYYMMDD[HHMMSS[.XXX]][[offset:TZ]]; the day is enough.
+		posted := fields["DTPOSTED"]
+		if len(posted) < 8 {
+			return nil, fmt.Errorf("transaction %d: bad DTPOSTED %q", n+1, posted)
+		}
+		date, err := time.ParseInLocation("20060102", posted[:8], time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", n+1, err)
+		}
+		amount, err := parseStatementAmount(fields["TRNAMT"])
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", n+1, err)
+		}
+		description := fields["NAME"]
+		if description == "" {
+			description = fields["PAYEE"]
+		}
+		if memo := fields["MEMO"]; memo != "" && !strings.Contains(description, memo) {
+			description = strings.TrimSpace(description + " " + memo)
+		}
+		if amount.IsZero() {
+			continue
+		}
+		rows = append(rows, StatementRow{Date: date, Description: description, Amount: amount})
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("no transactions found")
+	}
+	return rows, nil
+}
+
+// ValidateCategoryRule checks the rule can match something.
+func ValidateCategoryRule(r *models.CategoryRule) error {
+	r.Pattern = strings.TrimSpace(r.Pattern)
+	r.Category = strings.TrimSpace(r.Category)
+	if r.Pattern == "" || r.Category == "" {
+		return errors.New("pattern and category are required")
+	}
+	if r.Type != "" && r.Type != "expense" && r.Type != "income" {
+		return errors.New(`type must be "expense", "income" or empty`)
+	}
+	if r.IsRegex {
+		if _, err := regexp.Compile("(?i)" + r.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	return nil
+}
+
+type categorizer struct {
+	rules []models.CategoryRule
+	res   []*regexp.Regexp
+}
+
+func loadCategorizer(tx *gorm.DB) (*categorizer, error) {
+	c := &categorizer{}
+	if err := tx.Order("priority desc, id asc").Find(&c.rules).Error; err != nil {
+		return nil, err
+	}
+	c.res = make([]*regexp.Regexp, len(c.rules))
+	for i, r := range c.rules {
+		if r.IsRegex {
+			c.res[i], _ = regexp.Compile("(?i)" + r.Pattern)
+		}
+	}
+	return c, nil
+}
+
+// categorize applies the first matching rule, keeping the bank's own
+// category when none match.
+func (c *categorizer) categorize(row *StatementRow) {
+	desc := strings.ToLower(row.Description)
+	for i, r := range c.rules {
+		if r.Type != "" && r.Type != row.Type {
+			continue
+		}
+		matched := false
+		if c.res[i] != nil {
+			matched = c.res[i].MatchString(row.Description)
+		} else {
+			matched = strings.Contains(desc, strings.ToLower(r.Pattern))
+		}
+		if matched {
+			row.Category, row.Rule = r.Category, r.Pattern
+			return
+		}
+	}
+	if row.Category == "" {
+		row.Category = "Uncategorized"
+	}
+}
+
+// dedupeKey identifies a transaction by day, amount in cents and
+// description, ignoring case and spacing.
+func dedupeKey(date time.Time, typ string, amount decimal.Decimal, description string) string {
+	return fmt.Sprintf("%s|%s|%s|%s", date.In(time.Local).Format("2006-01-02"), typ,
+		amount.Abs().StringFixedBank(2), strings.Join(strings.Fields(strings.ToLower(description)), " "))
+}
+
+// markDuplicates flags rows already recorded. Matching is by count, so two
+// identical coffees in the file against one on record imports one.
+func markDuplicates(tx *gorm.DB, rows []StatementRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	from, to := rows[0].Date, rows[0].Date
+	for _, r := range rows {
+		if r.Date.Before(from) {
+			from = r.Date
+		}
+		if r.Date.After(to) {
+			to = r.Date
+		}
+	}
+	var existing []models.FinanceRecord
+	err := tx.Where("created_at >= ? AND created_at < ?", from.AddDate(0, 0, -1), to.AddDate(0, 0, 2)).
+		Find(&existing).Error
+	if err != nil {
+		return err
+	}
+	have := map[string]int{}
+	for _, e := range existing {
+		have[dedupeKey(e.CreatedAt, strings.ToLower(e.Type), e.Amount, e.Description)]++
+	}
+	for i := range rows {
+		key := dedupeKey(rows[i].Date, rows[i].Type, rows[i].Amount, rows[i].Description)
+		if have[key] > 0 {
+			have[key]--
+			rows[i].Duplicate = true
+		}
+	}
+	return nil
+}
+
+func parseStatement(imp *models.StatementImport, body io.Reader) ([]StatementRow, error) {
+	if imp.Format == "ofx" {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		// The statement's own currency applies unless the upload set one.
+		if m := ofxCurrency.FindSubmatch(data); m != nil && imp.Currency == "" {
+			imp.Currency = string(m[1])
+		}
+		return ParseOFXStatement(bytes.NewReader(data))
+	}
+	var m CSVMapping
+	if imp.Mapping != "" {
+		if err := json.Unmarshal([]byte(imp.Mapping), &m); err != nil {
+			return nil, fmt.Errorf("mapping: %w", err)
+		}
+	}
+	return ParseCSVStatement(body, m)
+}
+
+// previewStatement parses, categorizes and dedupes the import's file.
+func previewStatement(tx *gorm.DB, imp *models.StatementImport, body io.Reader) (*StatementPreview, error) {
+	rows, err := parseStatement(imp, body)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Date.Before(rows[j].Date) })
+
+	cat, err := loadCategorizer(tx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].Index = i
+		rows[i].Type = "income"
+		if rows[i].Amount.IsNegative() {
+			rows[i].Type = "expense"
+		}
+		cat.categorize(&rows[i])
+	}
+	if err := markDuplicates(tx, rows); err != nil {
+		return nil, err
+	}
+
+	p := &StatementPreview{Rows: rows}
+	for _, r := range rows {
+		if r.Duplicate {
+			p.Duplicates++
+			continue
+		}
+		p.New++
+		if r.Type == "expense" {
+			p.Expenses = p.Expenses.Sub(r.Amount)
+		} else {
+			p.Income = p.Income.Add(r.Amount)
+		}
+	}
+	imp.Rows, imp.Duplicates = len(rows), p.Duplicates
+	return p, nil
+}
+
+// PreviewStatement records an import of an uploaded file and returns what
+// committing it would add.
+func PreviewStatement(ctx context.Context, imp *models.StatementImport, body io.Reader) (*StatementPreview, error) {
+	imp.Status = models.ImportPreview
+	p, err := previewStatement(db.For(ctx), imp, body)
+	if err != nil {
+		return nil, err
+	}
+	if imp.Currency, err = recordCurrency(imp.Currency); err != nil {
+		return nil, err
+	}
+	if err := db.For(ctx).Save(imp).Error; err != nil {
+		return nil, err
+	}
+	p.Import = *imp
+	return p, nil
+}
+
+// StatementCommit adjusts a preview before it is written: rows to leave
+// out and categories to override, both by row index.
+type StatementCommit struct {
+	Exclude    []int          `json:"exclude"`
+	Categories map[int]string `json:"categories"`
+}
+
+// CommitStatement writes the import's new rows as FinanceRecords. The
+// import row is locked for the transaction, so two commits of the same
+// import cannot both write.
+func CommitStatement(ctx context.Context, imp *models.StatementImport, body io.Reader, opts StatementCommit) (*StatementPreview, error) {
+	if imp.Status == models.ImportCommitted {
+		return nil, ErrImportCommitted
+	}
+	excluded := map[int]bool{}
+	for _, i := range opts.Exclude {
+		excluded[i] = true
+	}
+
+	// Anomaly events wait for the commit, so a rolled-back import raises
+	// none.
+	txCtx, hooks := withCommitHooks(ctx)
+	var p *StatementPreview
+	err := db.For(txCtx).Transaction(func(tx *gorm.DB) error {
+		var locked models.StatementImport
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, "id = ?", imp.ID).Error; err != nil {
+			return err
+		}
+		if locked.Status == models.ImportCommitted {
+			return ErrImportCommitted
+		}
+
+		var err error
+		if p, err = previewStatement(tx, imp, body); err != nil {
+			return err
+		}
+		imp.Imported = 0
+		var created []models.FinanceRecord
+		for i := range p.Rows {
+			row := &p.Rows[i]
+			if row.Duplicate || excluded[row.Index] {
+				continue
+			}
+			if c, ok := opts.Categories[row.Index]; ok && strings.TrimSpace(c) != "" {
+				row.Category, row.Rule = strings.TrimSpace(c), ""
+			}
+			record := models.FinanceRecord{
+				Base:        models.Base{CreatedAt: row.Date},
+				Amount:      utils.RoundMoney(row.Amount.Abs(), imp.Currency),
+				Currency:    imp.Currency,
+				Category:    row.Category,
+				Description: row.Description,
+				Type:        row.Type,
+				ImportID:    &imp.ID,
+			}
+			if err := tx.Create(&record).Error; err != nil {
+				return err
+			}
+			created = append(created, record)
+			imp.Imported++
+		}
+		if p.Anomalies, err = checkSpendingAnomalies(tx, created); err != nil {
+			return fmt.Errorf("check anomalies: %w", err)
+		}
+		now := time.Now()
+		imp.Status, imp.CommittedAt = models.ImportCommitted, &now
+		return tx.Save(imp).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	hooks.run(ctx)
+	p.Import = *imp
+	return p, nil
+}
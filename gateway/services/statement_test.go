@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"gateway/db"
+	"gateway/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// rowString shows a parsed statement row as "2006-01-02 amount description".
+func rowString(r StatementRow) string {
+	return r.Date.Format("2006-01-02") + " " + r.Amount.String() + " " + r.Description
+}
+
+func TestParseCSVStatement(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		mapping CSVMapping
+		want    []string
+		err     string
+	}{
+		{"signed amount with guessed headers",
+			"Date,Description,Amount\n2026-03-01,Coffee,-4.50\n2026-03-02,Salary,\"$2,000.00\"\n",
+			CSVMapping{},
+			[]string{"2026-03-01 -4.5 Coffee", "2026-03-02 2000 Salary"}, ""},
+		{"split debit and credit",
+			"Posted Date,Payee,Withdrawals,Deposits\n03/05/2026,Rent,1500.00,\n03/20/2026,Refund,,25.00\n",
+			CSVMapping{},
+			[]string{"2026-03-05 -1500 Rent", "2026-03-20 25 Refund"}, ""},
+		{"negated, parenthesised and zero amounts",
+			"date;memo;value\n2026-04-01;Card;12.00\n2026-04-02;Reversal;(3.00)\n2026-04-03;Pending;0\n",
+			CSVMapping{Date: "date", Description: "memo", Amount: "value", Delimiter: ";", Negate: true},
+			[]string{"2026-04-01 -12 Card", "2026-04-02 3 Reversal"}, ""},
+		{"columns by index without a header, blank lines skipped",
+			"Groceries,2026-05-01,-30\n\n,,\nFuel,2026-05-03,-45.10\n",
+			CSVMapping{Date: "1", Description: "0", Amount: "2", NoHeader: true},
+			[]string{"2026-05-01 -30 Groceries", "2026-05-03 -45.1 Fuel"}, ""},
+		{"explicit day-first layout",
+			"Date,Description,Amount\n03/04/2026,Tea,-2\n",
+			CSVMapping{DateFormat: "02/01/2006"},
+			[]string{"2026-04-03 -2 Tea"}, ""},
+		{"month and day ambiguous",
+			"Date,Description,Amount\n03/04/2026,Tea,-2\n05/06/2026,Cake,-3\n",
+			CSVMapping{}, nil, "could be read as"},
+		{"unknown mapped column",
+			"Date,Description,Amount\n2026-03-01,Coffee,-4.50\n",
+			CSVMapping{Amount: "total"}, nil, `no "total" column`},
+		{"no amount column",
+			"Date,Description\n2026-03-01,Coffee\n",
+			CSVMapping{}, nil, "map an amount column"},
+		{"bad amount names its line",
+			"Date,Description,Amount\n2026-03-01,Coffee,-4.50\n2026-03-02,Tea,abc\n",
+			CSVMapping{}, nil, "line 3"},
+		{"empty file", "", CSVMapping{}, nil, "empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := ParseCSVStatement(strings.NewReader(tt.csv), tt.mapping)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != len(tt.want) {
+				t.Fatalf("%d rows, want %d: %+v", len(rows), len(tt.want), rows)
+			}
+			for i, r := range rows {
+				if got := rowString(r); got != tt.want[i] {
+					t.Errorf("row %d = %q, want %q", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseOFXStatement(t *testing.T) {
+	sgml := `OFXHEADER:100
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><CURDEF>EUR
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20260310120000[-5:EST]<TRNAMT>-42.10<NAME>GROCER<MEMO>Store 12
+</STMTTRN>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20260311<TRNAMT>0.00<NAME>Hold
+</STMTTRN>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20260312<TRNAMT>100<PAYEE>Employer<MEMO>Employer
+</STMTTRN>
+</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`
+	xml := `<?xml version="1.0"?><OFX><BANKTRANLIST>
+<STMTTRN><DTPOSTED>20260401</DTPOSTED><TRNAMT>-9.99</TRNAMT><NAME>Streaming</NAME></STMTTRN>
+</BANKTRANLIST></OFX>`
+
+	tests := []struct {
+		name string
+		body string
+		want []string
+		err  string
+	}{
+		{"sgml with memo, payee and a zero amount", sgml,
+			[]string{"2026-03-10 -42.1 GROCER Store 12", "2026-03-12 100 Employer"}, ""},
+		{"xml", xml, []string{"2026-04-01 -9.99 Streaming"}, ""},
+		{"not ofx", "Date,Amount\n", nil, "not an OFX file"},
+		{"no transactions", "<OFX></OFX>", nil, "no transactions"},
+		{"bad date", "<OFX><STMTTRN><DTPOSTED>2026<TRNAMT>1</STMTTRN></OFX>", nil, "bad DTPOSTED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := ParseOFXStatement(strings.NewReader(tt.body))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != len(tt.want) {
+				t.Fatalf("%d rows, want %d: %+v", len(rows), len(tt.want), rows)
+			}
+			for i, r := range rows {
+				if got := rowString(r); got != tt.want[i] {
+					t.Errorf("row %d = %q, want %q", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDedupeKey(t *testing.T) {
+	morning := time.Date(2026, 3, 1, 9, 0, 0, 0, time.Local)
+	base := dedupeKey(morning, "expense", decimal.RequireFromString("-4.50"), "Corner  Coffee")
+
+	tests := []struct {
+		name string
+		key  string
+		same bool
+	}{
+		{"case, spacing and sign ignored",
+			dedupeKey(morning.Add(8*time.Hour), "expense", decimal.RequireFromString("4.5"), " corner coffee "), true},
+		{"sub-cent rounding ignored",
+			dedupeKey(morning, "expense", decimal.RequireFromString("4.4999"), "Corner Coffee"), true},
+		{"another day", dedupeKey(morning.AddDate(0, 0, 1), "expense", decimal.RequireFromString("4.50"), "Corner Coffee"), false},
+		{"another amount", dedupeKey(morning, "expense", decimal.RequireFromString("4.51"), "Corner Coffee"), false},
+		{"another type", dedupeKey(morning, "income", decimal.RequireFromString("4.50"), "Corner Coffee"), false},
+		{"another description", dedupeKey(morning, "expense", decimal.RequireFromString("4.50"), "Corner Cafe"), false},
+	}
+	for _, tt := range tests {
+		if got := tt.key == base; got != tt.same {
+			t.Errorf("%s: %q vs %q, same = %v, want %v", tt.name, tt.key, base, got, tt.same)
+		}
+	}
+}
+
+// recordedFinance answers finance_records queries with records, each
+// "amount description", on the keyed day of March 2026.
+func recordedFinance(records map[int][]string) func(string, []driver.Value) ([]string, [][]driver.Value) {
+	return func(query string, _ []driver.Value) ([]string, [][]driver.Value) {
+		if !strings.Contains(query, `FROM "finance_records"`) {
+			return nil, nil
+		}
+		cols := []string{"id", "created_at", "amount", "description", "type"}
+		var vals [][]driver.Value
+		for d, recs := range records {
+			for _, r := range recs {
+				amount, desc, _ := strings.Cut(r, " ")
+				vals = append(vals, []driver.Value{uuid.NewString(),
+					time.Date(2026, 3, d, 15, 0, 0, 0, time.Local), amount, desc, "Expense"})
+			}
+		}
+		return cols, vals
+	}
+}
+
+func TestMarkDuplicates(t *testing.T) {
+	march := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.Local) }
+	expense := func(d int, amount, desc string) StatementRow {
+		return StatementRow{Date: march(d), Amount: decimal.RequireFromString(amount), Description: desc, Type: "expense"}
+	}
+
+	tests := []struct {
+		name     string
+		recorded map[int][]string
+		rows     []StatementRow
+		want     []bool
+	}{
+		{"nothing recorded", nil,
+			[]StatementRow{expense(1, "-4.50", "Coffee")}, []bool{false}},
+		{"two in the file against one on record", map[int][]string{1: {"4.50 Coffee"}},
+			[]StatementRow{expense(1, "-4.50", "Coffee"), expense(1, "-4.50", "coffee")}, []bool{true, false}},
+		{"two against two", map[int][]string{1: {"4.50 Coffee", "4.50 Coffee"}},
+			[]StatementRow{expense(1, "-4.50", "Coffee"), expense(1, "-4.50", "Coffee")}, []bool{true, true}},
+		{"other day or amount", map[int][]string{2: {"4.50 Coffee", "5.00 Lunch"}},
+			[]StatementRow{expense(1, "-4.50", "Coffee"), expense(2, "-5.50", "Lunch")}, []bool{false, false}},
+		{"income never matches an expense", map[int][]string{3: {"100 Refund"}},
+			[]StatementRow{{Date: march(3), Amount: decimal.NewFromInt(100), Description: "Refund", Type: "income"}},
+			[]bool{false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useRecordingDB(t, recordedFinance(tt.recorded))
+			ctx := db.WithOwner(context.Background(), uuid.New())
+			if err := markDuplicates(db.For(ctx), tt.rows); err != nil {
+				t.Fatal(err)
+			}
+			for i, r := range tt.rows {
+				if r.Duplicate != tt.want[i] {
+					t.Errorf("row %d duplicate = %v, want %v", i, r.Duplicate, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// The preview counts duplicates once each and totals only the new rows.
+func TestPreviewStatementCounts(t *testing.T) {
+	useRecordingDB(t, recordedFinance(map[int][]string{1: {"4.50 Coffee"}}))
+	ctx := db.WithOwner(context.Background(), uuid.New())
+	imp := &models.StatementImport{Format: "csv"}
+	body := "Date,Description,Amount\n2026-03-01,Coffee,-4.50\n2026-03-01,Coffee,-4.50\n2026-03-02,Salary,900\n"
+
+	p, err := previewStatement(db.For(ctx), imp, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.New != 2 || p.Duplicates != 1 || imp.Rows != 3 || imp.Duplicates != 1 {
+		t.Fatalf("new %d, duplicates %d, import rows %d/%d, want 2, 1, 3/1", p.New, p.Duplicates, imp.Rows, imp.Duplicates)
+	}
+	if !p.Expenses.Equal(decimal.RequireFromString("4.5")) || !p.Income.Equal(decimal.NewFromInt(900)) {
+		t.Fatalf("expenses %s, income %s, want 4.5 and 900", p.Expenses, p.Income)
+	}
+}
@@ -0,0 +1,164 @@
+package services
+
+// Streaming contract: POST /brain/v1/process_intent/stream answers with
+// text/event-stream. Events are relayed to the dashboard unchanged except
+// "final", whose data is a BrainResponse and ends the stream:
+//
+//   event: token     data: {"text": "..."}
+//   event: message   data: {"text": "..."}        partial message so far
+//   event: tool      data: {"action": "...", "data": {...}}
+//   event: final     data: <BrainResponse>
+//   event: error     data: {"message": "..."}
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gateway/models"
+	"log"
+	"net/http"
+	"strings"
+)
+
+type BrainEvent struct {
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// Streams are bounded by the caller's context rather than a client timeout.
+var brainStreamClient = &http.Client{}
+
+// StreamIntent relays Brain events to onEvent until the final response
+// arrives. If onEvent fails (the dashboard went away) or ctx is cancelled,
+// the upstream request is aborted. Brains without a streaming endpoint are
+// served by the blocking endpoint and a single "message" event.
+func StreamIntent(
+	ctx context.Context,
+	userID, sessionID, query, filePath string,
+	history []models.ChatHistory,
+	onEvent func(BrainEvent) error,
+) (*BrainResponse, error) {
+	payload, err := json.Marshal(intentBody(userID, sessionID, query, filePath, history))
+	if err != nil {
+		return nil, fmt.Errorf("marshal payload: %w", err)
+	}
+
+	log.Printf("[BRAIN] Stream session=%s file=%s", sessionID, filePath)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		brainURL()+"/brain/v1/process_intent/stream", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := brainStreamClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("brain stream: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+		return streamFallback(ctx, userID, sessionID, query, filePath, history, onEvent)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("brain stream: status %d", resp.StatusCode)
+	}
+
+	var (
+		partial strings.Builder
+		event   = "message"
+		data    bytes.Buffer
+	)
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			continue
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			continue
+		case line != "":
+			// Comments (": keep-alive") and unknown fields.
+			continue
+		}
+
+		// Blank line: dispatch the accumulated event.
+		ev := BrainEvent{Event: event, Data: json.RawMessage(append([]byte(nil), data.Bytes()...))}
+		event, data = "message", bytes.Buffer{}
+		if len(ev.Data) == 0 {
+			continue
+		}
+
+		switch ev.Event {
+		case "final":
+			var result BrainResponse
+			if err := json.Unmarshal(ev.Data, &result); err != nil {
+				return nil, fmt.Errorf("decode brain final: %w", err)
+			}
+			return &result, nil
+
+		case "error":
+			var e struct {
+				Message string `json:"message"`
+			}
+			json.Unmarshal(ev.Data, &e)
+			return nil, fmt.Errorf("brain stream: %s", e.Message)
+
+		case "token":
+			var t struct {
+				Text string `json:"text"`
+			}
+			if json.Unmarshal(ev.Data, &t) == nil {
+				partial.WriteString(t.Text)
+			}
+		}
+
+		if err := onEvent(ev); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("read brain stream: %w", err)
+	}
+
+	// Upstream closed without a final event; keep whatever was streamed.
+	if partial.Len() == 0 {
+		return nil, errors.New("brain stream ended without a response")
+	}
+	return &BrainResponse{Message: partial.String()}, nil
+}
+
+func streamFallback(
+	ctx context.Context,
+	userID, sessionID, query, filePath string,
+	history []models.ChatHistory,
+	onEvent func(BrainEvent) error,
+) (*BrainResponse, error) {
+	res, err := postIntent(ctx, intentBody(userID, sessionID, query, filePath, history))
+	if err != nil {
+		return nil, err
+	}
+	text, _ := json.Marshal(map[string]string{"text": res.Message})
+	if err := onEvent(BrainEvent{Event: "message", Data: text}); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,444 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"gateway/db"
+	"gateway/models"
+	"gateway/utils"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Capital gains are computed from the imported trades. Each acquisition
+// opens a lot; each disposal is matched against open lots in the order the
+// method picks. A crypto-to-crypto or non-USD fiat trade disposes of one
+// asset and acquires the other; its legs come from the ledger and are
+// valued at the day's USD close (or FX rate for fiat). Trades that cannot
+// be valued are listed in Unpriced, and quantity sold beyond the tracked
+// lots is reported as unmatched with no basis, never as gain.
+
+const (
+	LotFIFO = "FIFO"
+	LotLIFO = "LIFO"
+	LotHIFO = "HIFO"
+)
+
+const (
+	TermShort = "short"
+	TermLong  = "long"
+)
+
+type TaxLot struct {
+	Asset    string          `json:"asset"`
+	Acquired time.Time       `json:"acquired"`
+	Quantity decimal.Decimal `json:"quantity"`
+	Cost     decimal.Decimal `json:"cost"` // USD, fees included
+	TxID     string          `json:"txid"`
+}
+
+func (l *TaxLot) unitCost() decimal.Decimal {
+	if l.Quantity.IsZero() {
+		return decimal.Zero
+	}
+	return l.Cost.Div(l.Quantity)
+}
+
+// Disposal is one sell matched against one lot; a sell spanning several
+// lots yields several disposals. Unmatched is set for quantity sold beyond
+// the tracked lots (e.g. coins that were deposited): its basis is unknown,
+// so CostBasis and Gain are left at zero and it is kept out of the totals.
+type Disposal struct {
+	Asset     string          `json:"asset"`
+	Quantity  decimal.Decimal `json:"quantity"`
+	Acquired  time.Time       `json:"acquired"`
+	Sold      time.Time       `json:"sold"`
+	Proceeds  decimal.Decimal `json:"proceeds"`
+	CostBasis decimal.Decimal `json:"cost_basis"`
+	Gain      decimal.Decimal `json:"gain"`
+	Term      string          `json:"term"`
+	TxID      string          `json:"txid"`
+	Unmatched bool            `json:"unmatched"`
+}
+
+type GainTotals struct {
+	Proceeds  decimal.Decimal `json:"proceeds"`
+	CostBasis decimal.Decimal `json:"cost_basis"`
+	Gain      decimal.Decimal `json:"gain"`
+}
+
+// LotReconciliation compares open lots with the synced balance. Untracked
+// is held quantity no lot accounts for.
+type LotReconciliation struct {
+	Asset     string          `json:"asset"`
+	LotQty    decimal.Decimal `json:"lot_quantity"`
+	Held      decimal.Decimal `json:"held"`
+	Untracked decimal.Decimal `json:"untracked"`
+}
+
+// UnmatchedSale totals an asset's quantity sold in the year with no lot to
+// match, whose basis must be supplied by hand.
+type UnmatchedSale struct {
+	Asset    string          `json:"asset"`
+	Quantity decimal.Decimal `json:"quantity"`
+	Proceeds decimal.Decimal `json:"proceeds"`
+}
+
+type TaxReport struct {
+	Year           int                 `json:"year"`
+	Method         string              `json:"method"`
+	Disposals      []Disposal          `json:"disposals"`
+	ShortTerm      GainTotals          `json:"short_term"`
+	LongTerm       GainTotals          `json:"long_term"`
+	TotalGain      decimal.Decimal     `json:"total_gain"` // matched disposals only
+	Unmatched      []UnmatchedSale     `json:"unmatched"`
+	Unpriced       []string            `json:"unpriced"` // txids of trades with no USD value
+	OpenLots       []TaxLot            `json:"open_lots"`
+	Reconciliation []LotReconciliation `json:"reconciliation"`
+}
+
+func ValidLotMethod(m string) bool {
+	return m == LotFIFO || m == LotLIFO || m == LotHIFO
+}
+
+// orderLots sorts open lots so the next one to consume is first.
+func orderLots(lots []*TaxLot, method string) {
+	sort.SliceStable(lots, func(i, j int) bool {
+		switch method {
+		case LotLIFO:
+			return lots[i].Acquired.After(lots[j].Acquired)
+		case LotHIFO:
+			return lots[i].unitCost().GreaterThan(lots[j].unitCost())
+		default:
+			return lots[i].Acquired.Before(lots[j].Acquired)
+		}
+	})
+}
+
+func holdingTerm(acquired, sold time.Time) string {
+	if sold.After(acquired.AddDate(1, 0, 0)) {
+		return TermLong
+	}
+	return TermShort
+}
+
+// lotMove is one side of a trade in USD: an acquisition opens a lot, a
+// disposal is matched against lots.
+type lotMove struct {
+	Asset    string
+	Time     time.Time
+	Quantity decimal.Decimal
+	Value    decimal.Decimal // cost with fees for acquisitions, proceeds net of fees for disposals
+	Acquire  bool
+	TxID     string
+}
+
+// usdMoves turns a USD-quoted trade into its crypto side.
+func usdMoves(t *models.ExchangeTrade) ([]lotMove, bool) {
+	asset, ok := ActiveExchange().USDBase(t.Pair)
+	if !ok {
+		return nil, false
+	}
+	if !t.Volume.IsPositive() {
+		return nil, true
+	}
+	switch t.Type {
+	case "buy":
+		return []lotMove{{Asset: asset, Time: t.Time, Quantity: t.Volume, Value: t.Cost.Add(t.Fee), Acquire: true, TxID: t.TxID}}, true
+	case "sell":
+		return []lotMove{{Asset: asset, Time: t.Time, Quantity: t.Volume, Value: t.Cost.Sub(t.Fee), TxID: t.TxID}}, true
+	}
+	return nil, true
+}
+
+// pairMoves turns a trade between two non-USD assets into a disposal and
+// an acquisition, from its ledger legs. The trade is valued by whichever
+// leg has a USD price that day; ok is false when neither has.
+func pairMoves(t *models.ExchangeTrade, legs []models.LedgerEntry, fx *FXTable, prices historicalPrices) ([]lotMove, bool) {
+	var gave, got *models.LedgerEntry
+	for i := range legs {
+		if legs[i].Amount.IsNegative() {
+			gave = &legs[i]
+		} else if legs[i].Amount.IsPositive() {
+			got = &legs[i]
+		}
+	}
+	if gave == nil || got == nil {
+		return nil, false
+	}
+
+	usdPrice := func(asset string) (decimal.Decimal, bool) {
+		if rate, err := fx.Rate(asset, "USD", t.Time); err == nil {
+			return rate, true
+		}
+		return prices.at(asset, t.Time)
+	}
+	var value decimal.Decimal
+	if p, ok := usdPrice(gave.Asset); ok {
+		value = gave.Amount.Neg().Mul(p)
+	} else if p, ok := usdPrice(got.Asset); ok {
+		value = got.Amount.Mul(p)
+	} else {
+		return nil, false
+	}
+
+	var moves []lotMove
+	if gave.Asset != "USD" {
+		moves = append(moves, lotMove{Asset: gave.Asset, Time: t.Time, Quantity: gave.Amount.Neg().Add(gave.Fee), Value: value, TxID: t.TxID})
+	}
+	if got.Asset != "USD" {
+		moves = append(moves, lotMove{Asset: got.Asset, Time: t.Time, Quantity: got.Amount.Sub(got.Fee), Value: value, Acquire: true, TxID: t.TxID})
+	}
+	return moves, true
+}
+
+// tradeMoves converts trades into lot moves, oldest first. Non-USD trades
+// are read through their ledger legs; the txids of those that could not
+// be valued are returned separately.
+func tradeMoves(tx *gorm.DB, trades []models.ExchangeTrade) ([]lotMove, []string, error) {
+	var moves []lotMove
+	var other []models.ExchangeTrade
+	for i := range trades {
+		m, ok := usdMoves(&trades[i])
+		if !ok {
+			other = append(other, trades[i])
+			continue
+		}
+		moves = append(moves, m...)
+	}
+	unpriced := []string{}
+	if len(other) == 0 {
+		return moves, unpriced, nil
+	}
+
+	txids := make([]string, len(other))
+	for i, t := range other {
+		txids[i] = t.TxID
+	}
+	var entries []models.LedgerEntry
+	if err := tx.Where("type = ? AND ref_id IN ?", "trade", txids).Find(&entries).Error; err != nil {
+		return nil, nil, err
+	}
+	legs := map[string][]models.LedgerEntry{}
+	from := map[string]time.Time{}
+	for _, e := range entries {
+		legs[e.RefID] = append(legs[e.RefID], e)
+		if t, ok := from[e.Asset]; e.Asset != "USD" && (!ok || e.Time.Before(t)) {
+			from[e.Asset] = e.Time
+		}
+	}
+	fx, err := LoadFXTable()
+	if err != nil {
+		return nil, nil, err
+	}
+	prices := usdCandles(from)
+
+	for i := range other {
+		m, ok := pairMoves(&other[i], legs[other[i].TxID], fx, prices)
+		if !ok {
+			unpriced = append(unpriced, other[i].TxID)
+			continue
+		}
+		moves = append(moves, m...)
+	}
+	sort.SliceStable(moves, func(i, j int) bool { return moves[i].Time.Before(moves[j].Time) })
+	return moves, unpriced, nil
+}
+
+// matchLots replays moves in time order and returns every disposal plus
+// the lots still open afterwards. A lot's cost and a sale's proceeds are
+// split in proportion to quantity, so a lot consumed in full gives up
+// exactly its cost. Disposal amounts are rounded to the cent.
+func matchLots(moves []lotMove, method string) ([]Disposal, map[string][]*TaxLot) {
+	open := map[string][]*TaxLot{}
+	var disposals []Disposal
+
+	for _, m := range moves {
+		if !m.Quantity.IsPositive() {
+			continue
+		}
+		asset := m.Asset
+
+		if m.Acquire {
+			open[asset] = append(open[asset], &TaxLot{
+				Asset: asset, Acquired: m.Time, Quantity: m.Quantity, Cost: m.Value, TxID: m.TxID,
+			})
+			continue
+		}
+
+		proceedsOf := func(qty decimal.Decimal) decimal.Decimal {
+			return m.Value.Mul(qty).Div(m.Quantity)
+		}
+		remaining := m.Quantity
+		lots := open[asset]
+		orderLots(lots, method)
+
+		for len(lots) > 0 && remaining.IsPositive() {
+			lot := lots[0]
+			qty := decimal.Min(lot.Quantity, remaining)
+			basis := lot.Cost.Mul(qty).Div(lot.Quantity)
+			proceeds := utils.RoundMoney(proceedsOf(qty), "USD")
+			rounded := utils.RoundMoney(basis, "USD")
+			disposals = append(disposals, Disposal{
+				Asset: asset, Quantity: qty, Acquired: lot.Acquired, Sold: m.Time,
+				Proceeds: proceeds, CostBasis: rounded, Gain: proceeds.Sub(rounded),
+				Term: holdingTerm(lot.Acquired, m.Time), TxID: m.TxID,
+			})
+			lot.Cost = lot.Cost.Sub(basis)
+			lot.Quantity = lot.Quantity.Sub(qty)
+			remaining = remaining.Sub(qty)
+			if !lot.Quantity.IsPositive() {
+				lots = lots[1:]
+			}
+		}
+		open[asset] = lots
+
+		if remaining.IsPositive() {
+			disposals = append(disposals, Disposal{
+				Asset: asset, Quantity: remaining, Sold: m.Time,
+				Proceeds: utils.RoundMoney(proceedsOf(remaining), "USD"), Term: TermShort,
+				TxID: m.TxID, Unmatched: true,
+			})
+		}
+	}
+	return disposals, open
+}
+
+// BuildTaxReport computes realized gains for disposals in year using the
+// given lot method, and reconciles the remaining lots with CryptoHoldings.
+func BuildTaxReport(ctx context.Context, year int, method string) (*TaxReport, error) {
+	method = strings.ToUpper(method)
+	if method == "" {
+		method = LotFIFO
+	}
+	if !ValidLotMethod(method) {
+		return nil, fmt.Errorf("unknown lot method %q: use FIFO, LIFO or HIFO", method)
+	}
+
+	var trades []models.ExchangeTrade
+	if err := db.For(ctx).Order("time asc").Find(&trades).Error; err != nil {
+		return nil, err
+	}
+	var holdings []models.CryptoHoldings
+	if err := db.For(ctx).Find(&holdings).Error; err != nil {
+		return nil, err
+	}
+
+	moves, unpriced, err := tradeMoves(db.For(ctx), trades)
+	if err != nil {
+		return nil, err
+	}
+	all, open := matchLots(moves, method)
+	report := &TaxReport{Year: year, Method: method, Disposals: []Disposal{}, Unmatched: []UnmatchedSale{},
+		Unpriced: unpriced, OpenLots: []TaxLot{}}
+
+	unmatched := map[string]*UnmatchedSale{}
+	for _, d := range all {
+		if d.Sold.Year() != year {
+			continue
+		}
+		report.Disposals = append(report.Disposals, d)
+		if d.Unmatched {
+			u := unmatched[d.Asset]
+			if u == nil {
+				u = &UnmatchedSale{Asset: d.Asset}
+				unmatched[d.Asset] = u
+			}
+			u.Quantity = u.Quantity.Add(d.Quantity)
+			u.Proceeds = u.Proceeds.Add(d.Proceeds)
+			continue
+		}
+		totals := &report.ShortTerm
+		if d.Term == TermLong {
+			totals = &report.LongTerm
+		}
+		totals.Proceeds = totals.Proceeds.Add(d.Proceeds)
+		totals.CostBasis = totals.CostBasis.Add(d.CostBasis)
+		totals.Gain = totals.Gain.Add(d.Gain)
+		report.TotalGain = report.TotalGain.Add(d.Gain)
+	}
+
+	for _, u := range unmatched {
+		report.Unmatched = append(report.Unmatched, *u)
+	}
+	sort.Slice(report.Unmatched, func(i, j int) bool { return report.Unmatched[i].Asset < report.Unmatched[j].Asset })
+
+	lotQty := map[string]decimal.Decimal{}
+	for asset, lots := range open {
+		orderLots(lots, LotFIFO)
+		for _, l := range lots {
+			report.OpenLots = append(report.OpenLots, *l)
+			lotQty[asset] = lotQty[asset].Add(l.Quantity)
+		}
+	}
+	held := map[string]decimal.Decimal{}
+	for _, h := range holdings {
+		if h.Asset != "USD" {
+			held[h.Asset] = held[h.Asset].Add(h.Balance)
+		}
+	}
+	for asset := range lotQty {
+		if _, ok := held[asset]; !ok {
+			held[asset] = decimal.Zero
+		}
+	}
+	for asset, qty := range held {
+		report.Reconciliation = append(report.Reconciliation, LotReconciliation{
+			Asset: asset, LotQty: lotQty[asset], Held: qty, Untracked: qty.Sub(lotQty[asset]),
+		})
+	}
+	sort.Slice(report.Reconciliation, func(i, j int) bool {
+		return report.Reconciliation[i].Asset < report.Reconciliation[j].Asset
+	})
+	return report, nil
+}
+
+// WriteForm8949CSV writes the report's disposals in Form 8949 column order,
+// short-term (Part I) before long-term (Part II).
+func WriteForm8949CSV(w io.Writer, r *TaxReport) error {
+	rows := append([]Disposal(nil), r.Disposals...)
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Term != rows[j].Term {
+			return rows[i].Term == TermShort
+		}
+		return rows[i].Sold.Before(rows[j].Sold)
+	})
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"Part", "(a) Description of property", "(b) Date acquired", "(c) Date sold or disposed of",
+		"(d) Proceeds", "(e) Cost or other basis", "(f) Code", "(g) Adjustment", "(h) Gain or (loss)",
+	})
+	for _, d := range rows {
+		part := "I (short-term)"
+		if d.Term == TermLong {
+			part = "II (long-term)"
+		}
+		description := fmt.Sprintf("%s %s", d.Quantity.Round(8).String(), d.Asset)
+		acquired, basis, gain := d.Acquired.Format("01/02/2006"), d.CostBasis.StringFixed(2), d.Gain.StringFixed(2)
+		if d.Unmatched {
+			// No lot backs this quantity; the basis has to be filled in by hand.
+			description += " (BASIS MISSING)"
+			acquired, basis, gain = "UNKNOWN", "", ""
+		}
+		cw.Write([]string{
+			part,
+			description,
+			acquired,
+			d.Sold.Format("01/02/2006"),
+			d.Proceeds.StringFixed(2),
+			basis,
+			"",
+			"",
+			gain,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
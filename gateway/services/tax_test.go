@@ -0,0 +1,128 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func dec(s string) decimal.Decimal { return decimal.RequireFromString(s) }
+
+// wantDisposal is a disposal's quantity, basis, proceeds and term, or
+// unmatched quantity when basis is "".
+type wantDisposal struct {
+	qty, basis, proceeds, term string
+}
+
+func TestMatchLots(t *testing.T) {
+	buys := []lotMove{
+		{Asset: "BTC", Time: day("2024-01-10"), Quantity: dec("1"), Value: dec("10000"), Acquire: true, TxID: "B1"},
+		{Asset: "BTC", Time: day("2025-03-01"), Quantity: dec("1"), Value: dec("30000"), Acquire: true, TxID: "B2"},
+		{Asset: "BTC", Time: day("2025-06-01"), Quantity: dec("1"), Value: dec("20000"), Acquire: true, TxID: "B3"},
+	}
+	sell := func(qty, proceeds string) lotMove {
+		return lotMove{Asset: "BTC", Time: day("2025-09-01"), Quantity: dec(qty), Value: dec(proceeds), TxID: "S1"}
+	}
+
+	tests := []struct {
+		name      string
+		method    string
+		moves     []lotMove
+		disposals []wantDisposal
+		open      []string // quantity:cost of each lot left, in consumption order
+	}{
+		{"FIFO", LotFIFO, append(buys[:3:3], sell("1.5", "60000")),
+			[]wantDisposal{{"1", "10000", "40000", TermLong}, {"0.5", "15000", "20000", TermShort}},
+			[]string{"0.5:15000", "1:20000"}},
+		{"LIFO", LotLIFO, append(buys[:3:3], sell("1.5", "60000")),
+			[]wantDisposal{{"1", "20000", "40000", TermShort}, {"0.5", "15000", "20000", TermShort}},
+			[]string{"0.5:15000", "1:10000"}},
+		{"HIFO", LotHIFO, append(buys[:3:3], sell("1.5", "60000")),
+			[]wantDisposal{{"1", "30000", "40000", TermShort}, {"0.5", "10000", "20000", TermShort}},
+			[]string{"0.5:10000", "1:10000"}},
+		{"sold beyond the lots", LotFIFO, append(buys[:1:1], sell("3", "90000")),
+			[]wantDisposal{{"1", "10000", "30000", TermLong}, {"2", "", "60000", TermShort}},
+			nil},
+		{"nothing to match", LotFIFO, []lotMove{sell("1", "40000")},
+			[]wantDisposal{{"1", "", "40000", TermShort}},
+			nil},
+		{"sale before the buy", LotFIFO, []lotMove{
+			{Asset: "BTC", Time: day("2025-01-01"), Quantity: dec("1"), Value: dec("500"), TxID: "S0"},
+			buys[0]},
+			[]wantDisposal{{"1", "", "500", TermShort}},
+			[]string{"1:10000"}},
+		{"lot used up in pieces gives up its whole cost", LotFIFO, []lotMove{
+			{Asset: "ETH", Time: day("2025-01-01"), Quantity: dec("3"), Value: dec("100"), Acquire: true},
+			{Asset: "ETH", Time: day("2025-02-01"), Quantity: dec("1"), Value: dec("40")},
+			{Asset: "ETH", Time: day("2025-03-01"), Quantity: dec("2"), Value: dec("80")}},
+			[]wantDisposal{{"1", "33.33", "40", TermShort}, {"2", "66.67", "80", TermShort}},
+			nil},
+		{"zero quantity ignored", LotFIFO, []lotMove{
+			{Asset: "BTC", Time: day("2025-01-01"), Quantity: decimal.Zero, Value: dec("5"), Acquire: true},
+			sell("0", "0")},
+			nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			disposals, open := matchLots(tt.moves, tt.method)
+			if len(disposals) != len(tt.disposals) {
+				t.Fatalf("%d disposals, want %d: %+v", len(disposals), len(tt.disposals), disposals)
+			}
+			for i, w := range tt.disposals {
+				d := disposals[i]
+				if !d.Quantity.Equal(dec(w.qty)) || !d.Proceeds.Equal(dec(w.proceeds)) || d.Term != w.term {
+					t.Errorf("disposal %d = %s for %s (%s), want %s for %s (%s)", i, d.Quantity, d.Proceeds, d.Term, w.qty, w.proceeds, w.term)
+				}
+				if w.basis == "" {
+					if !d.Unmatched || !d.CostBasis.IsZero() || !d.Gain.IsZero() {
+						t.Errorf("disposal %d = %+v, want unmatched with no basis or gain", i, d)
+					}
+					continue
+				}
+				if d.Unmatched || !d.CostBasis.Equal(dec(w.basis)) || !d.Gain.Equal(d.Proceeds.Sub(d.CostBasis)) {
+					t.Errorf("disposal %d basis %s gain %s, want basis %s", i, d.CostBasis, d.Gain, w.basis)
+				}
+			}
+
+			var lots []string
+			for _, l := range open[tt.moves[len(tt.moves)-1].Asset] {
+				if l.Quantity.IsPositive() {
+					lots = append(lots, l.Quantity.String()+":"+l.Cost.String())
+				}
+			}
+			if len(lots) != len(tt.open) {
+				t.Fatalf("open lots = %v, want %v", lots, tt.open)
+			}
+			for i := range lots {
+				if lots[i] != tt.open[i] {
+					t.Errorf("open lots = %v, want %v", lots, tt.open)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestHoldingTerm(t *testing.T) {
+	tests := []struct {
+		acquired, sold, want string
+	}{
+		{"2024-03-01", "2024-09-01", TermShort},
+		{"2024-03-01", "2025-03-01", TermShort}, // exactly a year is still short
+		{"2024-03-01", "2025-03-02", TermLong},
+	}
+	for _, tt := range tests {
+		if got := holdingTerm(day(tt.acquired), day(tt.sold)); got != tt.want {
+			t.Errorf("holdingTerm(%s, %s) = %s, want %s", tt.acquired, tt.sold, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gateway/utils"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Tool arguments are plain structs. Field metadata is read from tags:
+//   json:"amount"            key in the Brain's data map
+//   tool:"required"          key must be present and non-empty
+//   desc:"..."               description published in the JSON schema
+//   enum:"BUY,SELL,HOLD"     allowed values (matched case-insensitively)
+// Arg structs may also implement argValidator for cross-field checks.
+
+var ErrUnknownTool = errors.New("unknown tool")
+
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+type ToolValidationError struct {
+	Tool   string       `json:"tool"`
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ToolValidationError) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		parts[i] = f.Field + ": " + f.Reason
+	}
+	return fmt.Sprintf("invalid arguments for %s (%s)", e.Tool, strings.Join(parts, "; "))
+}
+
+type ToolSchema struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+type argValidator interface {
+	Validate() []FieldError
+}
+
+type toolDef struct {
+	schema ToolSchema
+	run    func(tx *gorm.DB, data map[string]interface{}) (string, string, error)
+}
+
+var (
+	toolRegistry = map[string]*toolDef{}
+	toolAliases  = map[string]string{}
+)
+
+// RegisterTool binds a tool name to a typed handler. T must be a struct.
+func RegisterTool[T any](name, description string, run func(tx *gorm.DB, args T) (string, string, error)) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		panic("RegisterTool: args for " + name + " must be a struct")
+	}
+	if _, exists := toolRegistry[name]; exists {
+		panic("RegisterTool: duplicate tool " + name)
+	}
+
+	toolRegistry[name] = &toolDef{
+		schema: ToolSchema{
+			Name:        name,
+			Description: description,
+			Parameters:  schemaFor(t),
+		},
+		run: func(tx *gorm.DB, data map[string]interface{}) (string, string, error) {
+			args, err := bindArgs[T](name, data)
+			if err != nil {
+				return "", "", err
+			}
+			return run(tx, args)
+		},
+	}
+}
+
+// bindArgs decodes the Brain's data map into T and validates it, reporting
+// every bad field at once in a *ToolValidationError.
+func bindArgs[T any](name string, data map[string]interface{}) (T, error) {
+	var args T
+	fields := decodeArgs(data, reflect.ValueOf(&args).Elem())
+	if len(fields) == 0 {
+		if v, ok := any(args).(argValidator); ok {
+			fields = v.Validate()
+		}
+	}
+	if len(fields) > 0 {
+		return args, &ToolValidationError{Tool: name, Fields: fields}
+	}
+	return args, nil
+}
+
+// RegisterToolAlias lets a legacy action name resolve to a registered tool.
+// Aliases are not listed in ToolSchemas.
+func RegisterToolAlias(alias, name string) {
+	toolAliases[alias] = name
+}
+
+func lookupTool(name string) (*toolDef, bool) {
+	if target, ok := toolAliases[name]; ok {
+		name = target
+	}
+	def, ok := toolRegistry[name]
+	return def, ok
+}
+
+// ToolSchemas returns every registered tool, sorted by name.
+func ToolSchemas() []ToolSchema {
+	out := make([]ToolSchema, 0, len(toolRegistry))
+	for _, def := range toolRegistry {
+		out = append(out, def.schema)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func argKey(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+func argEnum(f reflect.StructField) []string {
+	raw := f.Tag.Get("enum")
+	if raw == "" {
+		return nil
+	}
+	return strings.Split(raw, ",")
+}
+
+func schemaFor(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	required := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := argKey(f)
+		if key == "" {
+			continue
+		}
+
+		prop := map[string]interface{}{"type": jsonType(f.Type)}
+		if d := f.Tag.Get("desc"); d != "" {
+			prop["description"] = d
+		}
+		if enum := argEnum(f); enum != nil {
+			prop["enum"] = enum
+		}
+		props[key] = prop
+
+		if f.Tag.Get("tool") == "required" {
+			required = append(required, key)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": props,
+		"required":   required,
+	}
+}
+
+// decimalType is accepted for money arguments and published as a number.
+var decimalType = reflect.TypeOf(decimal.Decimal{})
+
+func jsonType(t reflect.Type) string {
+	if t == decimalType {
+		return "number"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return "object"
+	}
+}
+
+func decodeArgs(data map[string]interface{}, out reflect.Value) []FieldError {
+	var errs []FieldError
+	t := out.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := argKey(f)
+		if key == "" {
+			continue
+		}
+
+		raw, ok := data[key]
+		if !ok || raw == nil {
+			if f.Tag.Get("tool") == "required" {
+				errs = append(errs, FieldError{Field: key, Reason: "is required"})
+			}
+			continue
+		}
+
+		if err := assignArg(out.Field(i), raw); err != nil {
+			errs = append(errs, FieldError{Field: key, Reason: err.Error()})
+			continue
+		}
+
+		if f.Type.Kind() == reflect.String {
+			fv := out.Field(i)
+			if f.Tag.Get("tool") == "required" && strings.TrimSpace(fv.String()) == "" {
+				errs = append(errs, FieldError{Field: key, Reason: "must not be empty"})
+				continue
+			}
+			if enum := argEnum(f); enum != nil && fv.String() != "" {
+				canonical, ok := matchEnum(enum, fv.String())
+				if !ok {
+					errs = append(errs, FieldError{Field: key, Reason: "must be one of " + strings.Join(enum, ", ")})
+					continue
+				}
+				fv.SetString(canonical)
+			}
+		}
+	}
+
+	return errs
+}
+
+func matchEnum(enum []string, v string) (string, bool) {
+	for _, e := range enum {
+		if strings.EqualFold(e, strings.TrimSpace(v)) {
+			return e, true
+		}
+	}
+	return "", false
+}
+
+func assignArg(fv reflect.Value, raw interface{}) error {
+	if fv.Type() == decimalType {
+		d, err := utils.ParseDecimal(raw)
+		if err != nil {
+			return fmt.Errorf("expected number: %w", err)
+		}
+		fv.Set(reflect.ValueOf(d))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		switch v := raw.(type) {
+		case string:
+			fv.SetString(v)
+		case float64, bool, json.Number:
+			fv.SetString(fmt.Sprintf("%v", v))
+		default:
+			return fmt.Errorf("expected string, got %T", raw)
+		}
+
+	case reflect.Float32, reflect.Float64:
+		n, err := argNumber(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(n)
+
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := argNumber(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(n))
+
+	case reflect.Bool:
+		switch v := raw.(type) {
+		case bool:
+			fv.SetBool(v)
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("expected boolean, got %q", v)
+			}
+			fv.SetBool(b)
+		default:
+			return fmt.Errorf("expected boolean, got %T", raw)
+		}
+
+	default:
+		return fmt.Errorf("unsupported argument type %s", fv.Type())
+	}
+	return nil
+}
+
+// argNumber accepts JSON numbers and numeric strings, since the Brain's
+// models are not consistent about quoting.
+func argNumber(raw interface{}) (float64, error) {
+	switch v := raw.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case json.Number:
+		return v.Float64()
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("expected number, got %q", v)
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("expected number, got %T", raw)
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// badFields returns the fields a bindArgs error names, sorted.
+func badFields(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var verr *ToolValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("err = %v, want *ToolValidationError", err)
+	}
+	fields := make([]string, len(verr.Fields))
+	for i, f := range verr.Fields {
+		fields[i] = f.Field
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func TestBindExpenseArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		data map[string]interface{}
+		want recordExpenseArgs
+		bad  []string
+	}{
+		{"valid", map[string]interface{}{"amount": 12.5, "category": "Food", "description": "lunch"},
+			recordExpenseArgs{Amount: decimal.RequireFromString("12.5"), Category: "Food", Description: "lunch"}, nil},
+		{"quoted amount", map[string]interface{}{"amount": "12.50", "category": "Food", "currency": "eur"},
+			recordExpenseArgs{Amount: decimal.RequireFromString("12.5"), Category: "Food", Currency: "eur"}, nil},
+		{"nil data", nil, recordExpenseArgs{}, []string{"amount", "category"}},
+		{"missing amount", map[string]interface{}{"category": "Food"}, recordExpenseArgs{}, []string{"amount"}},
+		{"null amount", map[string]interface{}{"amount": nil, "category": "Food"}, recordExpenseArgs{}, []string{"amount"}},
+		{"amount not a number", map[string]interface{}{"amount": "twelve", "category": "Food"}, recordExpenseArgs{}, []string{"amount"}},
+		{"amount is a bool", map[string]interface{}{"amount": true, "category": "Food"}, recordExpenseArgs{}, []string{"amount"}},
+		{"blank category", map[string]interface{}{"amount": 5, "category": "  "}, recordExpenseArgs{}, []string{"category"}},
+		{"category is a list", map[string]interface{}{"amount": 5, "category": []interface{}{"Food"}}, recordExpenseArgs{}, []string{"category"}},
+		{"negative amount", map[string]interface{}{"amount": -5, "category": "Food"}, recordExpenseArgs{}, []string{"amount"}},
+		{"bad currency", map[string]interface{}{"amount": 5, "category": "Food", "currency": "euro"}, recordExpenseArgs{}, []string{"currency"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := bindArgs[recordExpenseArgs]("execute_record_expense", tt.data)
+			if bad := badFields(t, err); !reflect.DeepEqual(bad, tt.bad) {
+				t.Fatalf("bad fields = %v, want %v", bad, tt.bad)
+			}
+			if err != nil {
+				return
+			}
+			if !got.Amount.Equal(tt.want.Amount) {
+				t.Errorf("amount = %s, want %s", got.Amount, tt.want.Amount)
+			}
+			got.Amount, tt.want.Amount = decimal.Zero, decimal.Zero
+			if got != tt.want {
+				t.Errorf("args = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindMealArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		data map[string]interface{}
+		want recordMealArgs
+		bad  []string
+	}{
+		{"valid", map[string]interface{}{"food_item": "Oats", "calories": 350.0, "protein": "12"},
+			recordMealArgs{FoodItem: "Oats", Calories: 350, Protein: 12}, nil},
+		{"missing everything", map[string]interface{}{}, recordMealArgs{}, []string{"calories", "food_item"}},
+		{"calories not a number", map[string]interface{}{"food_item": "Oats", "calories": "lots"}, recordMealArgs{}, []string{"calories"}},
+		{"calories is an object", map[string]interface{}{"food_item": "Oats", "calories": map[string]interface{}{"kcal": 1}}, recordMealArgs{}, []string{"calories"}},
+		{"negative macros", map[string]interface{}{"food_item": "Oats", "calories": 100, "fats": -1, "carbs": -2}, recordMealArgs{}, []string{"carbs", "fats"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := bindArgs[recordMealArgs]("execute_record_meal", tt.data)
+			if bad := badFields(t, err); !reflect.DeepEqual(bad, tt.bad) {
+				t.Fatalf("bad fields = %v, want %v", bad, tt.bad)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("args = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindSignalArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   map[string]interface{}
+		action string
+		bad    []string
+	}{
+		{"canonical action", map[string]interface{}{"asset": "XXBTZUSD", "signal_action": "buy"}, "BUY", nil},
+		{"unknown action", map[string]interface{}{"asset": "XXBTZUSD", "signal_action": "MAYBE"}, "", []string{"signal_action"}},
+		{"action is a number", map[string]interface{}{"asset": "XXBTZUSD", "signal_action": 1.0}, "", []string{"signal_action"}},
+		{"confidence not a number", map[string]interface{}{"asset": "XXBTZUSD", "signal_action": "SELL", "confidence": "high"}, "", []string{"confidence"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := bindArgs[tradingSignalArgs]("execute_generate_trading_signal", tt.data)
+			if bad := badFields(t, err); !reflect.DeepEqual(bad, tt.bad) {
+				t.Fatalf("bad fields = %v, want %v", bad, tt.bad)
+			}
+			if err == nil && got.Action != tt.action {
+				t.Errorf("action = %q, want %q", got.Action, tt.action)
+			}
+		})
+	}
+}
+
+// Registered tools reject bad arguments before their handler, and so
+// before touching the transaction.
+func TestToolRejectsBadArgsBeforeRunning(t *testing.T) {
+	def, ok := lookupTool("execute_record_workout")
+	if !ok {
+		t.Fatal("execute_record_workout not registered")
+	}
+	_, _, err := def.run(nil, map[string]interface{}{"exercise": "", "sets": []interface{}{3}})
+	if bad := badFields(t, err); !reflect.DeepEqual(bad, []string{"exercise", "sets"}) {
+		t.Fatalf("bad fields = %v, want [exercise sets]", bad)
+	}
+}
+
+func TestToolSchemasPublishRequiredFields(t *testing.T) {
+	for _, s := range ToolSchemas() {
+		if s.Name != "execute_record_expense" {
+			continue
+		}
+		required := s.Parameters["required"].([]string)
+		sort.Strings(required)
+		if !reflect.DeepEqual(required, []string{"amount", "category"}) {
+			t.Fatalf("required = %v, want [amount category]", required)
+		}
+		props := s.Parameters["properties"].(map[string]interface{})
+		if typ := props["amount"].(map[string]interface{})["type"]; typ != "number" {
+			t.Fatalf("amount type = %v, want number", typ)
+		}
+		return
+	}
+	t.Fatal("execute_record_expense not published")
+}
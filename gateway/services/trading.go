@@ -0,0 +1,334 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gateway/db"
+	"gateway/models"
+	"gateway/utils"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const tradeActionType = "Trade_Order"
+
+// tradeLimitLock serializes the daily limit check with the orders it
+// admits.
+const tradeLimitLock = 7340102
+
+var (
+	ErrSignalNotFound    = errors.New("signal not found")
+	ErrSignalNotTradable = errors.New("signal cannot be traded")
+	ErrTradeLimit        = errors.New("trade limit exceeded")
+)
+
+// TradeLimits caps the USD notional of live orders. They are read from
+// TRADE_MAX_ORDER_USD and TRADE_DAILY_LIMIT_USD.
+type TradeLimits struct {
+	MaxOrder decimal.Decimal `json:"max_order_usd"`
+	Daily    decimal.Decimal `json:"daily_limit_usd"`
+}
+
+func CurrentTradeLimits() TradeLimits {
+	return TradeLimits{
+		MaxOrder: envDecimal("TRADE_MAX_ORDER_USD", decimal.NewFromInt(100)),
+		Daily:    envDecimal("TRADE_DAILY_LIMIT_USD", decimal.NewFromInt(500)),
+	}
+}
+
+func envFloat(key string, fallback float64) float64 {
+	if v, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return v
+	}
+	return fallback
+}
+
+// envDecimal reads a money amount, so limits compare exactly.
+func envDecimal(key string, fallback decimal.Decimal) decimal.Decimal {
+	if v, err := utils.ParseDecimal(os.Getenv(key)); err == nil {
+		return v
+	}
+	return fallback
+}
+
+// AdminOnlyAction reports whether approving or retrying an action of this
+// type needs an admin. Trade orders spend real money.
+func AdminOnlyAction(actionType string) bool {
+	return actionType == tradeActionType
+}
+
+// checkTradeLimits rejects an order of `notional` USD that would break the
+// per-order cap or push today's placed orders over the daily cap. Orders
+// still being placed count against the cap.
+func checkTradeLimits(tx *gorm.DB, notional decimal.Decimal) error {
+	limits := CurrentTradeLimits()
+	maxOrder, daily := limits.MaxOrder, limits.Daily
+	if notional.GreaterThan(maxOrder) {
+		return fmt.Errorf("%w: order of %s exceeds the %s per-order limit", ErrTradeLimit,
+			FormatMoney(notional, "USD"), FormatMoney(maxOrder, "USD"))
+	}
+
+	now := time.Now()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var placed decimal.Decimal
+	tx.Model(&models.TradingSignal{}).
+		Where("(order_id <> '' OR status = ?) AND ordered_at >= ?", models.SignalPlacing, dayStart).
+		Select("COALESCE(sum(notional), 0)").
+		Scan(&placed)
+
+	if placed.Add(notional).GreaterThan(daily) {
+		return fmt.Errorf("%w: %s already traded today, %s more exceeds the %s daily limit",
+			ErrTradeLimit, FormatMoney(placed, "USD"), FormatMoney(notional, "USD"), FormatMoney(daily, "USD"))
+	}
+	return nil
+}
+
+// SignalOrder is what a reviewer asks to trade on a signal.
+type SignalOrder struct {
+	Volume     decimal.Decimal `json:"volume"`
+	OrderType  string          `json:"order_type"`
+	LimitPrice decimal.Decimal `json:"limit_price"`
+	Validate   bool            `json:"validate"`
+}
+
+func signalOrderRequest(s *models.TradingSignal) OrderRequest {
+	return OrderRequest{
+		Pair:      s.Asset,
+		Side:      strings.ToLower(s.Action),
+		OrderType: s.OrderType,
+		Volume:    s.Volume,
+		Price:     s.LimitPrice,
+	}
+}
+
+// prepareSignalOrder checks that a signal can be traded and fills in its
+// order fields from o.
+func prepareSignalOrder(s *models.TradingSignal, o SignalOrder) error {
+	if s.Action != "BUY" && s.Action != "SELL" {
+		return fmt.Errorf("%w: %s signals are not orders", ErrSignalNotTradable, s.Action)
+	}
+	if s.OrderID != "" {
+		return fmt.Errorf("%w: order %s already placed", ErrSignalNotTradable, s.OrderID)
+	}
+	if !o.Volume.IsPositive() {
+		return fmt.Errorf("%w: volume must be greater than zero", ErrSignalNotTradable)
+	}
+
+	orderType := strings.ToLower(o.OrderType)
+	if orderType == "" {
+		orderType = "market"
+	}
+	price := s.Price
+	switch orderType {
+	case "market":
+	case "limit":
+		if !o.LimitPrice.IsPositive() {
+			return fmt.Errorf("%w: limit orders need a limit_price", ErrSignalNotTradable)
+		}
+		price = o.LimitPrice
+	default:
+		return fmt.Errorf("%w: unknown order_type %q", ErrSignalNotTradable, o.OrderType)
+	}
+	if !price.IsPositive() {
+		return fmt.Errorf("%w: signal has no price, use a limit order", ErrSignalNotTradable)
+	}
+
+	s.OrderType = orderType
+	s.Volume = utils.RoundCrypto(o.Volume)
+	s.LimitPrice = utils.RoundCrypto(o.LimitPrice)
+	s.Notional = utils.RoundMoney(s.Volume.Mul(price), "USD")
+	return nil
+}
+
+func loadSignal(tx *gorm.DB, id string) (*models.TradingSignal, error) {
+	var signal models.TradingSignal
+	if err := tx.First(&signal, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSignalNotFound
+		}
+		return nil, err
+	}
+	return &signal, nil
+}
+
+// ProposeSignalOrder queues an order on a BUY/SELL signal for approval in
+// the Action Center. With o.Validate it instead asks the exchange to check the
+// order, places nothing and returns the exchange's description of it.
+func ProposeSignalOrder(ctx context.Context, id string, o SignalOrder) (*models.TradingSignal, string, error) {
+	var signal *models.TradingSignal
+	var description string
+
+	err := db.For(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if signal, err = loadSignal(tx, id); err != nil {
+			return err
+		}
+		if err := prepareSignalOrder(signal, o); err != nil {
+			return err
+		}
+		if err := checkTradeLimits(tx, signal.Notional); err != nil {
+			return err
+		}
+
+		if o.Validate {
+			req := signalOrderRequest(signal)
+			req.Validate = true
+			res, err := ActiveExchange().PlaceOrder(req)
+			if err != nil {
+				return fmt.Errorf("validate order: %w", err)
+			}
+			description = res.Description
+			return nil
+		}
+
+		var open int64
+		tx.Model(&models.PendingAction{}).
+			Where("type = ? AND ref_id = ? AND status NOT IN ?", tradeActionType, id,
+				[]string{models.ActionRejected, models.ActionExecuted}).
+			Count(&open)
+		if open > 0 {
+			return fmt.Errorf("%w: an order for this signal is already awaiting review", ErrSignalNotTradable)
+		}
+
+		signal.Status = models.SignalAwaiting
+		if err := tx.Save(signal).Error; err != nil {
+			return err
+		}
+
+		description = fmt.Sprintf("%s %s %s (%s) ~%s", signal.Action, signal.Volume, signal.Asset, signal.OrderType, FormatMoney(signal.Notional, "USD"))
+		if signal.OrderType == "limit" {
+			description += fmt.Sprintf(" @ %s", signal.LimitPrice)
+		}
+		content := fmt.Sprintf("%s\n\nConfidence: %.0f\nReasoning: %s", description, signal.Confidence, signal.Reasoning)
+		return mirrorToActionCenter(tx, tradeActionType, fmt.Sprint(signal.ID), "Trade: "+description, content)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return signal, description, nil
+}
+
+// RefreshSignalOrder pulls the latest fills and fees for a placed order.
+func RefreshSignalOrder(ctx context.Context, id string) (*models.TradingSignal, error) {
+	signal, err := loadSignal(db.For(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+	if signal.OrderID == "" {
+		return nil, fmt.Errorf("%w: no order placed yet", ErrSignalNotTradable)
+	}
+	if err := recordFill(db.For(ctx), signal); err != nil {
+		return nil, err
+	}
+	return signal, nil
+}
+
+func recordFill(tx *gorm.DB, s *models.TradingSignal) error {
+	fill, err := ActiveExchange().QueryOrder(s.OrderID)
+	if err != nil {
+		return fmt.Errorf("query order %s: %w", s.OrderID, err)
+	}
+
+	s.OrderStatus = fill.Status
+	s.FilledVolume = fill.FilledVolume
+	s.AvgFillPrice = fill.AvgPrice
+	s.Fee = fill.Fee
+	if fill.Status == "closed" {
+		s.Status = models.SignalFilled
+	}
+	return tx.Save(s).Error
+}
+
+// reserveSignalOrder marks the signal as being placed, under the trade
+// limit lock, so its notional counts against the daily cap before the
+// order leaves. It commits on its own: the order must not go out while the
+// reservation could still roll back.
+func reserveSignalOrder(ctx context.Context, id string) (*models.TradingSignal, error) {
+	var signal *models.TradingSignal
+	err := db.For(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", tradeLimitLock).Error; err != nil {
+			return err
+		}
+		var err error
+		if signal, err = loadSignal(tx.Clauses(clause.Locking{Strength: "UPDATE"}), id); err != nil {
+			return err
+		}
+		if signal.OrderID != "" {
+			return fmt.Errorf("%w: order %s already placed", ErrSignalNotTradable, signal.OrderID)
+		}
+		if signal.Status == models.SignalPlacing {
+			// A previous attempt may have reached the exchange; check there
+			// before trading again.
+			return fmt.Errorf("%w: an earlier attempt may have placed this order, check the exchange", ErrSignalNotTradable)
+		}
+		if err := checkTradeLimits(tx, signal.Notional); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		signal.Status = models.SignalPlacing
+		signal.OrderedAt = &now
+		return tx.Save(signal).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return signal, nil
+}
+
+func init() {
+	// Trade_Order places the signal's order on the exchange. Limits are checked
+	// again here since other orders may have gone through since proposal.
+	// The exchange call happens outside any transaction: the reservation
+	// commits before it, and the txid commits straight after, so a later
+	// failure cannot roll back the record of a live order.
+	RegisterDeployer(tradeActionType, DeployerFunc(func(tx *gorm.DB, a *models.PendingAction) error {
+		ctx := tx.Statement.Context
+		signal, err := reserveSignalOrder(ctx, a.RefID)
+		if err != nil {
+			return fmt.Errorf("trade signal %s: %w", a.RefID, err)
+		}
+
+		res, err := ActiveExchange().PlaceOrder(signalOrderRequest(signal))
+		if err == nil && len(res.TxIDs) == 0 {
+			err = errors.New("exchange accepted the order but returned no txid")
+		}
+		if err != nil {
+			// Nothing was placed; release the reservation so the order can be
+			// retried.
+			if rerr := db.For(ctx).Model(signal).Updates(map[string]interface{}{
+				"status": models.SignalAwaiting, "ordered_at": nil,
+			}).Error; rerr != nil {
+				log.Printf("[TRADER] Release signal %d: %v", signal.ID, rerr)
+			}
+			return fmt.Errorf("place order: %w", err)
+		}
+
+		signal.OrderID = res.TxIDs[0]
+		signal.OrderStatus = "open"
+		signal.Status = models.SignalSubmitted
+		if err := db.For(ctx).Save(signal).Error; err != nil {
+			// The order is live and the signal stays Placing, which blocks a
+			// retry; the txid must be recorded by hand.
+			log.Printf("[TRADER] Order %s placed but not recorded on signal %d: %v", signal.OrderID, signal.ID, err)
+			return err
+		}
+		log.Printf("[TRADER] Placed %s (%s)", signal.OrderID, res.Description)
+
+		// Market orders usually fill at once; limit orders are picked up by
+		// a later refresh.
+		if err := recordFill(db.For(ctx), signal); err != nil {
+			log.Printf("[TRADER] %v", err)
+		}
+		EmitEvent(ctx, "TRADER", fmt.Sprintf("Order %s placed: %s", signal.OrderID, res.Description), "SUCCESS")
+		return nil
+	}))
+}
@@ -2,8 +2,6 @@ package utils
 
 import (
 	"fmt"
-	"strconv"
-	"log"
 )
 
 
@@ -15,19 +13,12 @@ func SafeString(data map[string]interface{}, key string) string {
 	return fmt.Sprintf("%v", val)
 }
 
-func ParseNumeric(val interface{}) float64 {
-	switch v := val.(type) {
-	case float64:
-		return v
-	case string:
-		f, err := strconv.ParseFloat(v, 64)
-		if err != nil {
-			log.Printf("Error parsing numeric string: %v", err)
-			return 0
-		}
-		return f
-	default:
-		log.Printf("Unexpected type for numeric value: %T", val)
-		return 0
+// ParseNumeric reads a float from decoded JSON or form input, reporting
+// bad input rather than returning 0. Money should go through ParseDecimal.
+func ParseNumeric(val interface{}) (float64, error) {
+	d, err := ParseDecimal(val)
+	if err != nil {
+		return 0, err
 	}
+	return d.InexactFloat64(), nil
 }
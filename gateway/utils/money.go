@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Money is stored as NUMERIC and handled as decimal.Decimal. Rounding
+// rules:
+//   - fiat amounts round to the currency's minor unit (cents, whole yen)
+//     with banker's rounding, so rounding many amounts does not drift one
+//     way;
+//   - crypto quantities and prices keep CryptoPlaces, Kraken's finest;
+//   - sums are taken exactly and rounded once, at the end.
+
+const CryptoPlaces = 10
+
+// zeroDecimalCurrencies and threeDecimalCurrencies differ from the usual
+// two-digit minor unit (ISO 4217).
+var (
+	zeroDecimalCurrencies  = map[string]bool{"JPY": true, "KRW": true, "VND": true, "CLP": true, "ISK": true}
+	threeDecimalCurrencies = map[string]bool{"BHD": true, "KWD": true, "OMR": true, "JOD": true, "TND": true}
+)
+
+// CurrencyPlaces is the number of decimal places in currency's minor unit.
+func CurrencyPlaces(currency string) int32 {
+	switch c := strings.ToUpper(currency); {
+	case zeroDecimalCurrencies[c]:
+		return 0
+	case threeDecimalCurrencies[c]:
+		return 3
+	}
+	return 2
+}
+
+// RoundMoney rounds d to currency's minor unit, half to even.
+func RoundMoney(d decimal.Decimal, currency string) decimal.Decimal {
+	return d.RoundBank(CurrencyPlaces(currency))
+}
+
+// RoundCrypto rounds a crypto quantity or price to CryptoPlaces.
+func RoundCrypto(d decimal.Decimal) decimal.Decimal {
+	return d.RoundBank(CryptoPlaces)
+}
+
+// ParseDecimal reads a number from decoded JSON or form input. Strings may
+// carry thousands separators ("1,234.50"). Anything else is an error,
+// never a silent zero.
+func ParseDecimal(val interface{}) (decimal.Decimal, error) {
+	switch v := val.(type) {
+	case decimal.Decimal:
+		return v, nil
+	case float64:
+		return decimal.NewFromFloat(v), nil
+	case float32:
+		return decimal.NewFromFloat32(v), nil
+	case int:
+		return decimal.NewFromInt(int64(v)), nil
+	case int64:
+		return decimal.NewFromInt(v), nil
+	case json.Number:
+		return decimal.NewFromString(v.String())
+	case string:
+		s := strings.ReplaceAll(strings.TrimSpace(v), ",", "")
+		if s == "" {
+			return decimal.Zero, fmt.Errorf("empty number")
+		}
+		d, err := decimal.NewFromString(s)
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("bad number %q", v)
+		}
+		return d, nil
+	case nil:
+		return decimal.Zero, fmt.Errorf("missing number")
+	}
+	return decimal.Zero, fmt.Errorf("unexpected type for numeric value: %T", val)
+}